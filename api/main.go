@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,9 +19,16 @@ import (
 )
 
 func main() {
-	// Configure structured logging
+	// Configure structured logging, LOG_LEVEL is one of debug, info (default), warn or error
+	logLevel := slog.LevelInfo
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+			slog.Error("Invalid LOG_LEVEL", "error", err)
+			return
+		}
+	}
 	logHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+		Level: logLevel,
 	})
 	slog.SetDefault(slog.New(logHandler))
 
@@ -38,27 +47,86 @@ func main() {
 
 	apiRouter := mainRouter.PathPrefix("/api/v1").Subrouter()
 
-	// Get a connection from the pool
-	conn, err := db.GetPool().Acquire(context.Background())
-	if err != nil {
-		slog.Error("Failed to acquire database connection", "error", err)
-		return
+	workflowConfig := workflow.DefaultConfig()
+	if from := os.Getenv("EMAIL_FROM_ADDRESS"); from != "" {
+		workflowConfig.DefaultEmailFrom = from
+	}
+	if geocodingURL := os.Getenv("GEOCODING_API_BASE_URL"); geocodingURL != "" {
+		workflowConfig.WeatherAPI.GeocodingBaseURL = geocodingURL
+	}
+	if weatherURL := os.Getenv("WEATHER_API_BASE_URL"); weatherURL != "" {
+		workflowConfig.WeatherAPI.WeatherBaseURL = weatherURL
+	}
+	workflowConfig.WeatherAPI.OpenWeatherMapAPIKey = os.Getenv("OPENWEATHERMAP_API_KEY")
+	if rate := os.Getenv("WEATHER_API_RATE_LIMIT"); rate != "" {
+		limit, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			slog.Error("Invalid WEATHER_API_RATE_LIMIT", "error", err)
+			return
+		}
+		workflowConfig.WeatherAPI.RateLimit = limit
+	}
+	if burst := os.Getenv("WEATHER_API_RATE_BURST"); burst != "" {
+		b, err := strconv.Atoi(burst)
+		if err != nil {
+			slog.Error("Invalid WEATHER_API_RATE_BURST", "error", err)
+			return
+		}
+		workflowConfig.WeatherAPI.RateBurst = b
+	}
+	workflowConfig.DefaultCity = os.Getenv("DEFAULT_CITY")
+	// API keys are comma separated, owner keys are workflow-id=key pairs
+	if keys := os.Getenv("API_KEYS"); keys != "" {
+		workflowConfig.APIKeys.Admin = strings.Split(keys, ",")
+	}
+	if owners := os.Getenv("WORKFLOW_API_KEYS"); owners != "" {
+		workflowConfig.APIKeys.Owners = make(map[string]string)
+		for _, pair := range strings.Split(owners, ",") {
+			id, key, ok := strings.Cut(pair, "=")
+			if !ok || id == "" || key == "" {
+				slog.Error("Invalid WORKFLOW_API_KEYS, expected workflow-id=key pairs", "pair", id)
+				return
+			}
+			workflowConfig.APIKeys.Owners[id] = key
+		}
+	}
+	if size := os.Getenv("MAX_BODY_BYTES"); size != "" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			slog.Error("Invalid MAX_BODY_BYTES", "error", err)
+			return
+		}
+		workflowConfig.MaxBodyBytes = n
+	}
+
+	if n := os.Getenv("BATCH_CONCURRENCY"); n != "" {
+		concurrency, err := strconv.Atoi(n)
+		if err != nil {
+			slog.Error("Invalid BATCH_CONCURRENCY", "error", err)
+			return
+		}
+		workflowConfig.BatchConcurrency = concurrency
 	}
-	defer conn.Release()
 
-	workflowService, err := workflow.NewService(conn.Conn())
+	// the service runs its queries on the pool, so concurrent requests don't share a connection
+	workflowService, err := workflow.NewService(db.GetPool(), workflowConfig)
 	if err != nil {
 		slog.Error("Failed to create workflow service", "error", err)
 		return
 	}
 
 	workflowService.LoadRoutes(apiRouter, false)
+	// health check probed by load balancers and orchestrators, outside of the versioned API
+	mainRouter.HandleFunc("/healthz", workflowService.HandleHealthz).Methods("GET")
+	// Prometheus metrics of the workflow executions
+	mainRouter.Handle("/metrics", workflow.MetricsHandler()).Methods("GET")
 
 	// Configure CORS
 	corsHandler := handlers.CORS(
 		handlers.AllowedOrigins([]string{"http://localhost:3003"}), // Frontend URL
-		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "Idempotency-Key", "X-Request-ID", "X-API-Key"}),
+		handlers.ExposedHeaders([]string{"X-Created-At", "X-Updated-At", "Last-Modified", "Idempotent-Replayed", "X-Request-ID", "X-Execution-ID", "X-Workflow-ID"}),
 		handlers.AllowCredentials(),
 	)(mainRouter)
 
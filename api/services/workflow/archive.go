@@ -0,0 +1,138 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// this file archive.go contains the archive node, writing the execution so far to a file or an object store.
+
+const (
+	// contents the archive node can write, selected with the archive "content" metadata
+	ArchiveContentResult  = "result"
+	ArchiveContentContext = "context"
+)
+
+// ArchiveWriter writes an archive document to a destination.
+type ArchiveWriter interface {
+	WriteArchive(ctx context.Context, destination string, data []byte) error
+}
+
+// ExecutionArchive is the JSON document written by the archive node.
+type ExecutionArchive struct {
+	WorkflowID string         `json:"workflowId"`
+	NodeID     string         `json:"nodeId"`
+	ArchivedAt string         `json:"archivedAt"`
+	Steps      []StepResult   `json:"steps,omitempty"`   // steps recorded before the archive node, for the result content
+	Context    map[string]any `json:"context,omitempty"` // contextData, for the context content
+}
+
+// archiveContent returns the content the archive node writes, defaulting to the result.
+func archiveContent(node Node) string {
+	if cfg := node.Data.Metadata.Archive; cfg != nil && cfg.Content != "" {
+		return cfg.Content
+	}
+	return ArchiveContentResult
+}
+
+// processArchiveNode writes the steps recorded so far (or contextData) as an ExecutionArchive to the node
+// destination and returns the destination and the number of bytes written.
+func processArchiveNode(ctx context.Context, node Node, contextData *Context, writer ArchiveWriter) (string, int, error) {
+	cfg := node.Data.Metadata.Archive
+	if cfg == nil || cfg.Destination == "" {
+		return "", 0, fmt.Errorf("archive node %s has no destination configured", node.ID)
+	}
+
+	stats, ok := ctx.Value(executionStatsContextKey{}).(*executionStats)
+	if !ok {
+		// called outside of processNodes, e.g from a test
+		stats = &executionStats{}
+	}
+	archive := ExecutionArchive{
+		WorkflowID: stats.workflowID,
+		NodeID:     node.ID,
+		ArchivedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	switch content := archiveContent(node); content {
+	case ArchiveContentResult:
+		if stats.steps != nil {
+			archive.Steps = stats.steps.snapshot()
+		}
+	case ArchiveContentContext:
+		archive.Context = contextData.Snapshot()
+	default:
+		return cfg.Destination, 0, fmt.Errorf("archive node %s has an unsupported content: %s", node.ID, content)
+	}
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return cfg.Destination, 0, fmt.Errorf("failed to marshal archive: %w", err)
+	}
+	if err := writer.WriteArchive(ctx, cfg.Destination, data); err != nil {
+		return cfg.Destination, 0, err
+	}
+	return cfg.Destination, len(data), nil
+}
+
+// defaultArchiveWriter writes archives to local files, or PUTs them to http(s) URLs such as a presigned S3 URL.
+type defaultArchiveWriter struct{}
+
+func (defaultArchiveWriter) WriteArchive(ctx context.Context, destination string, data []byte) error {
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme == "" {
+		return writeArchiveFile(destination, data)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return writeArchiveFile(u.Path, data)
+	case "http", "https":
+		return putArchive(ctx, destination, data)
+	default:
+		return fmt.Errorf("%w: unsupported destination scheme %s", ErrArchiveFailed, u.Scheme)
+	}
+}
+
+// writeArchiveFile writes the archive to the file, creating its directory when needed.
+func writeArchiveFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("%w: %v", ErrArchiveFailed, err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("%w: %v", ErrArchiveFailed, err)
+	}
+	return nil
+}
+
+// putArchive uploads the archive with a PUT request to the URL.
+func putArchive(ctx context.Context, destination string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, destination, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrArchiveFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClientFromContext(ctx).Do(req)
+	if err != nil {
+		// the error names the URL, which may carry a signature
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactURL(urlErr.URL)
+		}
+		return fmt.Errorf("%w: %v", ErrArchiveFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: destination returned %d", ErrArchiveFailed, resp.StatusCode)
+	}
+	return nil
+}
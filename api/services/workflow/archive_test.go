@@ -0,0 +1,114 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memoryArchiveWriter keeps the written archives by destination.
+type memoryArchiveWriter map[string][]byte
+
+func (m memoryArchiveWriter) WriteArchive(ctx context.Context, destination string, data []byte) error {
+	m[destination] = data
+	return nil
+}
+
+func TestProcessNodesArchive(t *testing.T) {
+	newWorkflow := func(archive *ArchiveConfig) *WorkflowDefinition {
+		return &WorkflowDefinition{
+			ID: "wf-1",
+			Nodes: []Node{
+				{ID: StartNodeID},
+				{ID: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "score = 2 * 21"}}},
+				{ID: ArchiveNodeID, Data: NodeData{Metadata: NodeMetadata{Archive: archive}}},
+				{ID: EndNodeID},
+			},
+			Edges: []Edge{
+				{Source: StartNodeID, Target: TransformNodeID},
+				{Source: TransformNodeID, Target: ArchiveNodeID},
+				{Source: ArchiveNodeID, Target: EndNodeID},
+			},
+		}
+	}
+
+	t.Run("archives the steps so far", func(t *testing.T) {
+		writer := memoryArchiveWriter{}
+		wf := newWorkflow(&ArchiveConfig{Destination: "archives/run.json"})
+
+		got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{ArchiveWriter: writer})
+		require.NoError(t, err)
+		require.Equal(t, StatusCompleted, got.Status)
+
+		var archive ExecutionArchive
+		require.NoError(t, json.Unmarshal(writer["archives/run.json"], &archive))
+		require.Equal(t, "wf-1", archive.WorkflowID)
+		require.Equal(t, ArchiveNodeID, archive.NodeID)
+		require.Len(t, archive.Steps, 2)
+		require.Equal(t, TransformNodeID, archive.Steps[1].NodeID)
+		require.Nil(t, archive.Context)
+
+		output, err := got.Steps[2].ArchiveOutput()
+		require.NoError(t, err)
+		require.Equal(t, "archives/run.json", output.Destination)
+		require.Equal(t, len(writer["archives/run.json"]), output.BytesWritten)
+	})
+
+	t.Run("archives the context", func(t *testing.T) {
+		writer := memoryArchiveWriter{}
+		wf := newWorkflow(&ArchiveConfig{Destination: "context.json", Content: ArchiveContentContext})
+
+		_, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{ArchiveWriter: writer})
+		require.NoError(t, err)
+
+		var archive ExecutionArchive
+		require.NoError(t, json.Unmarshal(writer["context.json"], &archive))
+		require.Empty(t, archive.Steps)
+		require.Equal(t, map[string]any{"score": 42.0}, archive.Context)
+	})
+
+	t.Run("skipped in a dry run", func(t *testing.T) {
+		writer := memoryArchiveWriter{}
+		wf := newWorkflow(&ArchiveConfig{Destination: "run.json"})
+
+		got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{ArchiveWriter: writer, DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, StatusSkipped, got.Steps[2].Status)
+		require.Empty(t, writer)
+	})
+
+	t.Run("error: no destination", func(t *testing.T) {
+		got, err := processNodes(context.Background(), newWorkflow(nil), &ExecutePayload{}, ExecutionOptions{ArchiveWriter: memoryArchiveWriter{}})
+		require.NoError(t, err)
+		require.Equal(t, StatusFailed, got.Status)
+		require.Equal(t, ArchiveNodeID, got.Error.NodeID)
+	})
+}
+
+func TestDefaultArchiveWriter(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("local file", func(t *testing.T) {
+		path := filepath.Join(dir, "nested", "run.json")
+		require.NoError(t, defaultArchiveWriter{}.WriteArchive(context.Background(), path, []byte(`{}`)))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, `{}`, string(data))
+	})
+
+	t.Run("file url", func(t *testing.T) {
+		path := filepath.Join(dir, "url.json")
+		require.NoError(t, defaultArchiveWriter{}.WriteArchive(context.Background(), "file://"+path, []byte(`{}`)))
+		require.FileExists(t, path)
+	})
+
+	t.Run("error: unsupported scheme", func(t *testing.T) {
+		err := defaultArchiveWriter{}.WriteArchive(context.Background(), "s3://bucket/run.json", []byte(`{}`))
+		require.ErrorIs(t, err, ErrArchiveFailed)
+	})
+}
@@ -0,0 +1,106 @@
+package workflow
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// this file auth.go contains the optional API key authentication of the workflow routes.
+
+const apiKeyHeader = "X-API-Key"
+
+// APIKeys configures the API key authentication. Authentication is disabled when no key is configured.
+type APIKeys struct {
+	// Admin keys are granted access to every workflow.
+	Admin []string
+	// Owners maps a workflow id to the key of its owner, granted access to that workflow only.
+	Owners map[string]string
+}
+
+// enabled reports whether any key is configured.
+func (k APIKeys) enabled() bool {
+	return len(k.Admin) > 0 || len(k.Owners) > 0
+}
+
+// known reports whether the key is an admin key or owns a workflow.
+func (k APIKeys) known(key string) bool {
+	if k.isAdmin(key) {
+		return true
+	}
+	for _, owner := range k.Owners {
+		if keysEqual(owner, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdmin reports whether the key is an admin key.
+func (k APIKeys) isAdmin(key string) bool {
+	for _, admin := range k.Admin {
+		if keysEqual(admin, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccess reports whether the key grants access to the workflow.
+func (k APIKeys) canAccess(key, workflowID string) bool {
+	if k.isAdmin(key) {
+		return true
+	}
+	owner, ok := k.Owners[workflowID]
+	return ok && keysEqual(owner, key)
+}
+
+// keysEqual compares the keys in constant time so the response time doesn't leak how much of a key matched.
+func keysEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// authMiddleware requires a known API key in the X-API-Key header when keys are configured, responding with
+// 401 when it's missing or unknown. Routes of a single workflow (/workflows/{id}/...) also require the key to
+// be an admin key or the workflow owner's key, otherwise it responds with 403 (see adminOnly for the routes
+// restricted to the admin keys).
+func (s *Service) authMiddleware(next http.Handler) http.Handler {
+	keys := s.config.APIKeys
+	if !keys.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(apiKeyHeader)
+		switch {
+		case key == "":
+			writeJSONError(w, ErrMissingAPIKey, http.StatusUnauthorized)
+			return
+		case !keys.known(key):
+			writeJSONError(w, ErrInvalidAPIKey, http.StatusUnauthorized)
+			return
+		}
+
+		if id, ok := mux.Vars(r)["id"]; ok && !keys.canAccess(key, id) {
+			loggerFromContext(r.Context()).Warn("API key doesn't grant access to the workflow", "id", id)
+			writeJSONError(w, ErrWorkflowForbidden, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminOnly restricts a route to the admin keys when keys are configured, responding with 403 to an owner key.
+// It's used by the routes not scoped to a single workflow that list, store or execute arbitrary workflows, the
+// key itself is checked by authMiddleware.
+func (s *Service) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys := s.config.APIKeys
+		if keys.enabled() && !keys.isAdmin(r.Header.Get(apiKeyHeader)) {
+			loggerFromContext(r.Context()).Warn("API key isn't an admin key", "path", r.URL.Path)
+			writeJSONError(w, ErrAdminKeyRequired, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
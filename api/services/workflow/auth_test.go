@@ -0,0 +1,65 @@
+package workflow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	keys := APIKeys{
+		Admin:  []string{"admin-key"},
+		Owners: map[string]string{"wf-1": "owner-key"},
+	}
+	definition := `{"id":"wf-1","nodes":[{"id":"start","type":"start"},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"end"}]}`
+
+	tests := []struct {
+		label      string
+		keys       APIKeys
+		method     string
+		target     string
+		apiKey     string
+		wantStatus int
+		wantCode   string
+	}{
+		{label: "disabled without keys", method: http.MethodPost, target: "/workflows/validate", wantStatus: http.StatusOK},
+		{label: "error: missing key", keys: keys, method: http.MethodPost, target: "/workflows/validate", wantStatus: http.StatusUnauthorized, wantCode: ErrMissingAPIKey.Code},
+		{label: "error: unknown key", keys: keys, method: http.MethodPost, target: "/workflows/validate", apiKey: "guess", wantStatus: http.StatusUnauthorized, wantCode: ErrInvalidAPIKey.Code},
+		{label: "owner key on a route without workflow", keys: keys, method: http.MethodPost, target: "/workflows/validate", apiKey: "owner-key", wantStatus: http.StatusOK},
+		{label: "error: owner key listing the workflows", keys: keys, method: http.MethodGet, target: "/workflows", apiKey: "owner-key", wantStatus: http.StatusForbidden, wantCode: ErrAdminKeyRequired.Code},
+		{label: "error: owner key creating a workflow", keys: keys, method: http.MethodPost, target: "/workflows", apiKey: "owner-key", wantStatus: http.StatusForbidden, wantCode: ErrAdminKeyRequired.Code},
+		{label: "error: owner key executing an inline workflow", keys: keys, method: http.MethodPost, target: "/workflows/execute", apiKey: "owner-key", wantStatus: http.StatusForbidden, wantCode: ErrAdminKeyRequired.Code},
+		// the inline execute route rejects the body without a payload once the key is accepted
+		{label: "admin key executing an inline workflow", keys: keys, method: http.MethodPost, target: "/workflows/execute", apiKey: "admin-key", wantStatus: http.StatusBadRequest},
+		// the cancel route answers 404 for an execution that isn't running once the key is accepted
+		{label: "owner key on its workflow", keys: keys, method: http.MethodPost, target: "/workflows/wf-1/executions/exec-1/cancel", apiKey: "owner-key", wantStatus: http.StatusNotFound},
+		{label: "admin key on any workflow", keys: keys, method: http.MethodPost, target: "/workflows/wf-2/executions/exec-1/cancel", apiKey: "admin-key", wantStatus: http.StatusNotFound},
+		{label: "error: owner key on another workflow", keys: keys, method: http.MethodPost, target: "/workflows/wf-2/executions/exec-1/cancel", apiKey: "owner-key", wantStatus: http.StatusForbidden, wantCode: ErrWorkflowForbidden.Code},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			config := DefaultConfig()
+			config.APIKeys = tt.keys
+			router := mux.NewRouter()
+			s := &Service{config: config, executions: newExecutionRegistry()}
+			s.LoadRoutes(router, false)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(definition))
+			if tt.apiKey != "" {
+				req.Header.Set(apiKeyHeader, tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantCode != "" {
+				require.Contains(t, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// this file batch.go contains the batch execution of a stored workflow, once per payload
+// (POST /workflows/{id}/execute/batch), e.g to alert a list of users.
+
+const (
+	// maxBatchSize is the largest number of payloads a batch can execute.
+	maxBatchSize = 100
+	// defaultBatchConcurrency is the number of batch items executed at once when the config doesn't set one.
+	defaultBatchConcurrency = 4
+)
+
+// BatchItemResult is the outcome of the execution of one payload of a batch, in the order of the payloads.
+// It has the execution result, or the error when the payload was rejected or the execution couldn't run.
+type BatchItemResult struct {
+	Index  int              `json:"index"`
+	Status string           `json:"status"`
+	Result *ExecutionResult `json:"result,omitempty"`
+	Error  string           `json:"error,omitempty"`
+	Code   string           `json:"code,omitempty"`
+	Fields []FieldError     `json:"fields,omitempty"` // invalid fields of a rejected payload
+}
+
+// BatchExecuteResponse is the result of the batch execute route.
+type BatchExecuteResponse struct {
+	Items     []BatchItemResult `json:"items"`
+	Completed int               `json:"completed"`
+	Failed    int               `json:"failed"`
+}
+
+// executeBatchItem validates the payload and executes the workflow for it within the timeout.
+func executeBatchItem(ctx context.Context, wf *WorkflowDefinition, index int, payload ExecutePayload, opts ExecutionOptions, timeout time.Duration) BatchItemResult {
+	item := BatchItemResult{Index: index, Status: StatusFailed}
+
+	payload = *payload.withDefaultCity(opts.DefaultCity)
+	if err := payload.ValidateFor(wf); err != nil {
+		item.Error = err.Error()
+		item.Code = errorCode(err)
+		var validationErrs ValidationErrors
+		if errors.As(err, &validationErrs) {
+			item.Code = ErrInvalidPayload.Code
+			item.Fields = validationErrs
+		}
+		return item
+	}
+
+	// each item has its own deadline, so a slow item doesn't use up the time of the others
+	itemCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := ExecuteWorkflowWithOptions(itemCtx, wf, &payload, opts)
+	item.Result = result
+	if err != nil {
+		item.Error = err.Error()
+		item.Code = errorCode(err)
+		return item
+	}
+	item.Status = result.Status
+	return item
+}
+
+// executeBatch executes the workflow for every payload, concurrency at a time. A failed item doesn't stop
+// the others, its error is reported in its own result.
+func executeBatch(ctx context.Context, wf *WorkflowDefinition, payloads []ExecutePayload, opts ExecutionOptions, timeout time.Duration, concurrency int) BatchExecuteResponse {
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	items := make([]BatchItemResult, len(payloads))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, payload := range payloads {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			items[i] = executeBatchItem(ctx, wf, i, payload, opts, timeout)
+		}()
+	}
+	wg.Wait()
+
+	response := BatchExecuteResponse{Items: items}
+	for _, item := range items {
+		if item.Status == StatusCompleted {
+			response.Completed++
+		} else {
+			response.Failed++
+		}
+	}
+	return response
+}
+
+// HandleExecuteWorkflowBatch executes the workflow once per payload of the JSON array body. It accepts the same
+// query params and headers as the execute route, the timeout applies to each payload. The response is 200
+// whatever the outcome of the items, each item reports its own result or error.
+func (s *Service) HandleExecuteWorkflowBatch(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	ctx := r.Context()
+	logger := loggerFromContext(ctx)
+	logger.Debug("Handling batch workflow execution for id", "id", id)
+
+	opts, timeout, err := s.executionRequestOptions(r)
+	if err != nil {
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var payloads []ExecutePayload
+	if err := json.NewDecoder(r.Body).Decode(&payloads); err != nil {
+		logger.Debug("Invalid batch payload", "error", err)
+		writeBodyError(w, err)
+		return
+	}
+	if len(payloads) == 0 || len(payloads) > maxBatchSize {
+		err := fmt.Errorf("%w: must have between 1 and %d payloads", ErrInvalidPayload, maxBatchSize)
+		writeJSONError(w, ValidationErrors{newFieldError("payloads", err)}, http.StatusBadRequest)
+		return
+	}
+
+	definitions, err := s.GetWorkflowDefinitionsByIDs(ctx, []string{id})
+	if err != nil {
+		var status int
+		var respErr error
+
+		var notFound *WorkflowsNotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			status = http.StatusNotFound
+			respErr = ErrWorkflowNotFound
+		default:
+			status = http.StatusInternalServerError
+			respErr = ErrInternalServerError
+		}
+
+		writeJSONError(w, respErr, status)
+		return
+	}
+	definitionBytes := definitions[id]
+
+	var wf WorkflowDefinition
+	if err := json.Unmarshal(definitionBytes, &wf); err != nil {
+		logger.Error("Invalid workflow format", "id", id, "error", err)
+		writeJSONError(w, ErrInvalidWorkflowFormat, http.StatusInternalServerError)
+		return
+	}
+
+	s.withStoredWorkflowHooks(id, &wf, &opts)
+	response := executeBatch(ctx, &wf, payloads, opts, timeout, s.config.BatchConcurrency)
+	logger.Info("Batch execution finished", "id", id, "items", len(payloads), "completed", response.Completed, "failed", response.Failed)
+
+	jsonBytes, err := json.Marshal(response)
+	if err != nil {
+		logger.Error("Failed to marshal batch results", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeExecutionBody(w, r, jsonBytes)
+}
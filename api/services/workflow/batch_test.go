@@ -0,0 +1,117 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteBatch(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		switch payload.FormData.City {
+		case "Atlantis":
+			return errors.New("city not found")
+		case "Slowtown":
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		time.Sleep(5 * time.Millisecond)
+		contextData.SetFloat(weatherContextKey(WeatherMetricTemperature), 25)
+		return nil
+	}
+	defer func() { processWeatherNodeFn = processWeatherNode }()
+
+	wf := &WorkflowDefinition{
+		ID:    "wf-1",
+		Nodes: []Node{{ID: StartNodeID}, {ID: FormNodeID}, {ID: WeatherAPINodeID}, {ID: EndNodeID}},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: FormNodeID},
+			{Source: FormNodeID, Target: WeatherAPINodeID},
+			{Source: WeatherAPINodeID, Target: EndNodeID},
+		},
+	}
+	payload := func(city string) ExecutePayload {
+		return ExecutePayload{
+			FormData:  FormData{Name: "Jane", Email: "jane@example.com", City: city},
+			Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+		}
+	}
+	payloads := []ExecutePayload{payload("Sydney"), payload(""), payload("Atlantis"), payload("Slowtown")}
+	for range 6 {
+		payloads = append(payloads, payload("Perth"))
+	}
+
+	got := executeBatch(context.Background(), wf, payloads, ExecutionOptions{}, 50*time.Millisecond, 2)
+	require.Len(t, got.Items, len(payloads))
+	require.Equal(t, 7, got.Completed)
+	require.Equal(t, 3, got.Failed)
+	require.LessOrEqual(t, maxInFlight, 2)
+
+	for i, item := range got.Items {
+		require.Equal(t, i, item.Index)
+	}
+
+	require.Equal(t, StatusCompleted, got.Items[0].Status)
+	require.Equal(t, StatusCompleted, got.Items[0].Result.Status)
+
+	// the rejected payload reports its invalid fields
+	require.Equal(t, StatusFailed, got.Items[1].Status)
+	require.Nil(t, got.Items[1].Result)
+	require.Equal(t, ErrInvalidPayload.Code, got.Items[1].Code)
+	require.Equal(t, "formData.city", got.Items[1].Fields[0].Field)
+
+	// a failed node fails its own item only
+	require.Equal(t, StatusFailed, got.Items[2].Status)
+	require.Equal(t, "city not found", got.Items[2].Result.Error.Message)
+
+	// the timeout applies to each item
+	require.Equal(t, StatusFailed, got.Items[3].Status)
+	require.Equal(t, ErrExecutionTimeout.Code, got.Items[3].Code)
+}
+
+func TestHandleExecuteWorkflowBatchInvalidBody(t *testing.T) {
+	tests := []struct {
+		label      string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{label: "error: not an array", body: `{"formData":{}}`, wantStatus: http.StatusBadRequest},
+		{label: "error: no payloads", body: `[]`, wantStatus: http.StatusBadRequest, wantCode: ErrInvalidPayload.Code},
+		{label: "error: too many payloads", body: "[" + strings.Repeat("{},", maxBatchSize) + "{}]", wantStatus: http.StatusBadRequest, wantCode: ErrInvalidPayload.Code},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			router := mux.NewRouter()
+			s := &Service{config: DefaultConfig()}
+			s.LoadRoutes(router, false)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/workflows/wf-1/execute/batch", strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.Contains(t, rec.Body.String(), tt.wantCode)
+		})
+	}
+}
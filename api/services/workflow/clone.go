@@ -0,0 +1,91 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// this file clone.go contains the copy of a stored workflow into a new one (POST /workflows/{id}/clone).
+
+// cloneNameSuffix is appended to the source workflow name when the clone request doesn't name the clone.
+const cloneNameSuffix = " (copy)"
+
+// CloneWorkflowRequest is the optional body of the clone route.
+type CloneWorkflowRequest struct {
+	Name string `json:"name,omitempty"` // name of the clone, defaults to the source name followed by " (copy)"
+}
+
+// cloneDefinition returns a copy of the stored definition with the given id and name. Every other field is
+// kept as stored, including the ones the API doesn't model (e.g the editor's edge styles).
+func cloneDefinition(stored []byte, id, name string) ([]byte, string, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(stored, &doc); err != nil || doc == nil {
+		return nil, "", ErrInvalidWorkflowFormat
+	}
+
+	if name == "" {
+		sourceName, _ := doc["name"].(string)
+		if sourceName == "" {
+			sourceName = defaultWorkflowName
+		}
+		name = sourceName + cloneNameSuffix
+	}
+	doc["id"] = id
+	doc["name"] = name
+
+	definition, err := json.Marshal(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	return definition, name, nil
+}
+
+// HandleCloneWorkflow stores a copy of the workflow under a new id and returns the id of the copy.
+func (s *Service) HandleCloneWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	ctx := r.Context()
+	logger := loggerFromContext(ctx)
+	logger.Debug("Cloning workflow for id", "id", id)
+
+	var req CloneWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("Invalid clone payload", "error", err)
+		writeBodyError(w, err)
+		return
+	}
+
+	cloneID, err := s.CloneWorkflow(ctx, id, req.Name)
+	if err != nil {
+		var status int
+		var respErr error
+
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			status = http.StatusNotFound
+			respErr = ErrWorkflowNotFound
+		default:
+			logger.Error("Error cloning workflow", "id", id, "error", err)
+			status = http.StatusInternalServerError
+			respErr = ErrInternalServerError
+		}
+
+		writeJSONError(w, respErr, status)
+		return
+	}
+
+	jsonBytes, err := json.Marshal(CreateWorkflowResponse{ID: cloneID})
+	if err != nil {
+		logger.Error("Failed to marshal clone response", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonBytes)
+}
@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneDefinition(t *testing.T) {
+	stored := `{"id":"wf-1","name":"Heat alert","nodes":[{"id":"start","type":"start"}],"edges":[{"id":"e1","source":"start","target":"end","style":{"stroke":"#10b981"}}]}`
+
+	tests := []struct {
+		label    string
+		stored   string
+		name     string
+		wantName string
+		wantErr  error
+	}{
+		{label: "named after the source", stored: stored, wantName: "Heat alert (copy)"},
+		{label: "name override", stored: stored, name: "Cold alert", wantName: "Cold alert"},
+		{label: "unnamed source", stored: `{"id":"wf-1","nodes":[],"edges":[]}`, wantName: "Untitled Workflow (copy)"},
+		{label: "error: invalid stored definition", stored: `[]`, wantErr: ErrInvalidWorkflowFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			definition, name, err := cloneDefinition([]byte(tt.stored), "wf-2", tt.name)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantName, name)
+
+			var doc map[string]any
+			require.NoError(t, json.Unmarshal(definition, &doc))
+			require.Equal(t, "wf-2", doc["id"])
+			require.Equal(t, tt.wantName, doc["name"])
+		})
+	}
+
+	t.Run("keeps the fields the API doesn't model", func(t *testing.T) {
+		definition, _, err := cloneDefinition([]byte(stored), "wf-2", "")
+		require.NoError(t, err)
+		require.Contains(t, string(definition), `"style":{"stroke":"#10b981"}`)
+	})
+}
+
+func TestHandleCloneWorkflowInvalidBody(t *testing.T) {
+	router := mux.NewRouter()
+	s := &Service{config: DefaultConfig()}
+	s.LoadRoutes(router, false)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/workflows/wf-1/clone", strings.NewReader(`{"name":`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), ErrInvalidJSON.Code)
+}
@@ -0,0 +1,41 @@
+package workflow
+
+import (
+	"context"
+)
+
+// this file email.go contains the email sending of the email node, through a configurable EmailSender.
+
+// EmailMessage is the email built by an email node.
+type EmailMessage struct {
+	ID      string // unique id of the message, e.g for the Message-ID header
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// EmailSender sends the emails of the email nodes, e.g through an SMTP server or a provider API.
+// Send must return once the context is done, the execution being canceled or timed out.
+type EmailSender interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
+
+// EmailSenderFunc adapts a function to the EmailSender interface.
+type EmailSenderFunc func(ctx context.Context, msg EmailMessage) error
+
+// Send calls f(ctx, msg).
+func (f EmailSenderFunc) Send(ctx context.Context, msg EmailMessage) error {
+	return f(ctx, msg)
+}
+
+// noopEmailSender is the default sender, it only logs the message as no live emails are sent.
+type noopEmailSender struct{}
+
+func (noopEmailSender) Send(ctx context.Context, msg EmailMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	loggerFromContext(ctx).Debug("Email not sent, no email sender configured", "id", msg.ID, "to", msg.To)
+	return nil
+}
@@ -0,0 +1,73 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessNodesEmailSender(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{
+				Subject: "Alert for {{city}}",
+				Body:    "Hi {{name}}",
+			}}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: EmailNodeID},
+			{Source: EmailNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{Name: "Jane", Email: "jane@example.com", City: "Sydney"}}
+
+	tests := []struct {
+		label      string
+		sendErr    error
+		wantStatus string
+	}{
+		{label: "sent", wantStatus: "sent"},
+		{label: "error: send failed", sendErr: errors.New("smtp unavailable"), wantStatus: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			var sent []EmailMessage
+			sender := EmailSenderFunc(func(ctx context.Context, msg EmailMessage) error {
+				sent = append(sent, msg)
+				return tt.sendErr
+			})
+
+			got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{EmailSender: sender})
+			require.NoError(t, err)
+			require.Len(t, sent, 1)
+			require.Equal(t, "jane@example.com", sent[0].To)
+			require.Equal(t, defaultEmailFrom, sent[0].From)
+			require.Equal(t, "Alert for Sydney", sent[0].Subject)
+			require.Equal(t, "Hi Jane", sent[0].Body)
+			require.NotEmpty(t, sent[0].ID)
+
+			step := got.Steps[1]
+			require.Equal(t, tt.wantStatus, step.Output["deliveryStatus"])
+			if tt.sendErr != nil {
+				require.Equal(t, "failed", step.Status)
+				require.ErrorIs(t, got.Error, ErrEmailSendFailed)
+				require.Equal(t, ErrEmailSendFailed.Code, got.Error.Code)
+				return
+			}
+			require.Equal(t, sent[0].ID, step.Output["messageId"])
+		})
+	}
+}
+
+func TestNoopEmailSender(t *testing.T) {
+	require.NoError(t, noopEmailSender{}.Send(context.Background(), EmailMessage{ID: "1", To: "jane@example.com"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, noopEmailSender{}.Send(ctx, EmailMessage{ID: "1", To: "jane@example.com"}), context.Canceled)
+}
@@ -1,28 +1,260 @@
 package workflow
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
 
 // this file errors.go will contains custom workflow related errors
 
+// CodedError bundles a human readable message with a stable machine-readable code.
+type CodedError struct {
+	Code    string
+	Message string
+}
+
+func (e *CodedError) Error() string {
+	return e.Message
+}
+
+func newCodedError(code, message string) *CodedError {
+	return &CodedError{Code: code, Message: message}
+}
+
+// codeInternal is used for errors that don't carry their own code.
+const codeInternal = "INTERNAL_ERROR"
+
 var (
 	// generic errors
-	ErrInternalServerError  = errors.New("internal server error")
-	ErrResponseDecodeFailed = errors.New("failed to decode response")
-	ErrMarshalFailed        = errors.New("failed to marshal results")
+	ErrInternalServerError       = newCodedError(codeInternal, "internal server error")
+	ErrResponseDecodeFailed      = newCodedError("RESPONSE_DECODE_FAILED", "failed to decode response")
+	ErrMarshalFailed             = newCodedError("MARSHAL_FAILED", "failed to marshal results")
+	ErrMethodNotAllowed          = newCodedError("METHOD_NOT_ALLOWED", "method not allowed")
+	ErrMissingAPIKey             = newCodedError("MISSING_API_KEY", "missing X-API-Key header")
+	ErrInvalidAPIKey             = newCodedError("INVALID_API_KEY", "invalid API key")
+	ErrWorkflowForbidden         = newCodedError("WORKFLOW_FORBIDDEN", "the API key doesn't grant access to this workflow")
+	ErrAdminKeyRequired          = newCodedError("ADMIN_KEY_REQUIRED", "the route requires an admin API key")
+	ErrGeocodingRequestFailed    = newCodedError("GEOCODING_REQUEST_FAILED", "geocoding API returned an unexpected status")
+	ErrWeatherRequestFailed      = newCodedError("WEATHER_REQUEST_FAILED", "weather API returned an unexpected status")
+	ErrWeatherResponseIncomplete = newCodedError("WEATHER_RESPONSE_INCOMPLETE", "weather API response is missing the weather data")
+	ErrWeatherRateLimited        = newCodedError("WEATHER_RATE_LIMITED", "weather API rate limit reached")
+	ErrStaleWeatherData          = newCodedError("STALE_WEATHER_DATA", "weather reading is too old")
+	ErrAmbiguousCity             = newCodedError("AMBIGUOUS_CITY", "city matches several places")
+	ErrNoGeocodingMatch          = newCodedError("NO_GEOCODING_MATCH", "no place of the city matches the country or region hint")
+	ErrSlackWebhookFailed        = newCodedError("SLACK_WEBHOOK_FAILED", "slack webhook returned an unexpected status")
+	ErrArchiveFailed             = newCodedError("ARCHIVE_FAILED", "failed to write the archive")
+	ErrEmailSendFailed           = newCodedError("EMAIL_SEND_FAILED", "failed to send the email")
 
 	// Workflow-level errors
-	ErrWorkflowNotFound      = errors.New("workflow not found")
-	ErrInvalidWorkflowFormat = errors.New("invalid workflow format")
-	ErrMissingStartNode      = errors.New("missing 'start' node")
-	ErrMissingEndNode        = errors.New("missing 'end' node")
+	ErrWorkflowNotFound           = newCodedError("WORKFLOW_NOT_FOUND", "workflow not found")
+	ErrInvalidWorkflowFormat      = newCodedError("INVALID_WORKFLOW_FORMAT", "invalid workflow format")
+	ErrMissingStartNode           = newCodedError("MISSING_START_NODE", "missing 'start' node")
+	ErrMissingEndNode             = newCodedError("MISSING_END_NODE", "missing 'end' node")
+	ErrEntryNodeNotFound          = newCodedError("ENTRY_NODE_NOT_FOUND", "entry node does not exist")
+	ErrDanglingEdge               = newCodedError("DANGLING_EDGE", "edge references a node that does not exist")
+	ErrStartNodeIncomingEdge      = newCodedError("START_NODE_INCOMING_EDGE", "start node must not have incoming edges")
+	ErrEndNodeOutgoingEdge        = newCodedError("END_NODE_OUTGOING_EDGE", "end node must not have outgoing edges")
+	ErrCycleDetected              = newCodedError("CYCLE_DETECTED", "workflow graph contains a cycle")
+	ErrDuplicateNodeID            = newCodedError("DUPLICATE_NODE_ID", "duplicate node id")
+	ErrWorkflowAlreadyExists      = newCodedError("WORKFLOW_ALREADY_EXISTS", "workflow already exists")
+	ErrExecutionTimeout           = newCodedError("EXECUTION_TIMEOUT", "workflow execution timed out")
+	ErrNodeTimeout                = newCodedError("NODE_TIMEOUT", "node timed out")
+	ErrNodePanicked               = newCodedError("NODE_PANICKED", "node processor panicked")
+	ErrUnreachableNodes           = newCodedError("UNREACHABLE_NODES", "workflow has nodes that can't be reached from the start node")
+	ErrUnknownNodeType            = newCodedError("UNKNOWN_NODE_TYPE", "no processor is registered for the node type")
+	ErrTooManySteps               = newCodedError("TOO_MANY_STEPS", "workflow execution exceeded the maximum number of steps")
+	ErrExecutionCanceled          = newCodedError("EXECUTION_CANCELED", "workflow execution was canceled")
+	ErrExecutionNotFound          = newCodedError("EXECUTION_NOT_FOUND", "execution not found or already finished")
+	ErrExecutionAlreadyRunning    = newCodedError("EXECUTION_ALREADY_RUNNING", "an execution with this id is already running")
+	ErrIdempotencyKeyReused       = newCodedError("IDEMPOTENCY_KEY_REUSED", "the idempotency key was already used with a different payload")
+	ErrIdempotencyKeyInFlight     = newCodedError("IDEMPOTENCY_KEY_IN_FLIGHT", "an execution with this idempotency key is still running")
+	ErrIdempotencyKeyStreamed     = newCodedError("IDEMPOTENCY_KEY_NOT_SUPPORTED", "the idempotency key isn't supported for streamed executions")
+	ErrConflictingConditionRoutes = newCodedError("CONFLICTING_CONDITION_ROUTES", "condition node has several edges for the same outcome")
+	ErrMissingConditionRoute      = newCodedError("MISSING_CONDITION_ROUTE", "condition node has no edge for an outcome")
+	ErrMissingContextDependency   = newCodedError("MISSING_CONTEXT_DEPENDENCY", "condition reads a context key that no upstream node produces")
+	ErrContextValueNotFound       = newCodedError("CONTEXT_VALUE_NOT_FOUND", "not in context data")
+	ErrContextValueNotNumeric     = newCodedError("CONTEXT_VALUE_NOT_NUMERIC", "is not numeric")
 
 	// Request validation errors
-	ErrInvalidJSON           = errors.New("invalid JSON")
-	ErrMissingFormFieldName  = errors.New("name is required")
-	ErrMissingFormFieldEmail = errors.New("email is required")
-	ErrMissingFormFieldCity  = errors.New("city is required")
+	ErrInvalidJSON                = newCodedError("INVALID_JSON", "invalid JSON")
+	ErrRequestTooLarge            = newCodedError("REQUEST_TOO_LARGE", "request body too large")
+	ErrMissingRequiredField       = newCodedError("MISSING_REQUIRED_FIELD", "field is required")
+	ErrInvalidPayload             = newCodedError("INVALID_PAYLOAD", "invalid request payload")
+	ErrMissingFormFieldName       = newCodedError("MISSING_FORM_FIELD_NAME", "name is required")
+	ErrMissingFormFieldEmail      = newCodedError("MISSING_FORM_FIELD_EMAIL", "email is required")
+	ErrMissingFormFieldCity       = newCodedError("MISSING_FORM_FIELD_CITY", "city is required")
+	ErrIncompleteCoordinates      = newCodedError("INCOMPLETE_COORDINATES", "lat and lon must be given together")
+	ErrInvalidLatitude            = newCodedError("INVALID_LATITUDE", "latitude must be between -90 and 90")
+	ErrInvalidLongitude           = newCodedError("INVALID_LONGITUDE", "longitude must be between -180 and 180")
+	ErrMissingFormFieldPhone      = newCodedError("MISSING_FORM_FIELD_PHONE", "phone is required")
+	ErrInvalidPhoneNumber         = newCodedError("INVALID_PHONE_NUMBER", "invalid phone number")
+	ErrInvalidTimeout             = newCodedError("INVALID_TIMEOUT", "invalid execution timeout")
+	ErrInvalidQueryParam          = newCodedError("INVALID_QUERY_PARAM", "invalid query parameter")
+	ErrInvalidFailurePolicy       = newCodedError("INVALID_FAILURE_POLICY", "invalid failure policy, must be stopOnError or continue")
+	ErrInvalidThresholdRange      = newCodedError("INVALID_THRESHOLD_RANGE", "upperThreshold must not be lower than threshold")
+	ErrInvalidTolerance           = newCodedError("INVALID_TOLERANCE", "tolerance must not be negative")
+	ErrUnsupportedWeatherProvider = newCodedError("UNSUPPORTED_WEATHER_PROVIDER", "unsupported weather provider")
+	ErrInvalidExpression          = newCodedError("INVALID_EXPRESSION", "invalid expression")
+	ErrInvalidJSONPath            = newCodedError("INVALID_JSON_PATH", "invalid JSONPath")
+	ErrJSONPathNotFound           = newCodedError("JSON_PATH_NOT_FOUND", "JSONPath does not resolve in the response")
+	ErrInvalidEmailAddress        = newCodedError("INVALID_EMAIL_ADDRESS", "invalid email address")
+	ErrEmailDomainUndeliverable   = newCodedError("EMAIL_DOMAIN_UNDELIVERABLE", "email domain has no MX records")
+	ErrUnsupportedOperator        = newCodedError("UNSUPPORTED_OPERATOR", "unsupported operator")
 )
 
+// errorCode returns the code of the first CodedError in the err chain.
+func errorCode(err error) string {
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return codeInternal
+}
+
+// errorToJSON returns the JSON error body with the error message and its machine-readable code.
 func errorToJSON(err error) string {
-	return `{"error":"` + err.Error() + `"}`
+	body, marshalErr := json.Marshal(struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}{
+		Error: err.Error(),
+		Code:  errorCode(err),
+	})
+	if marshalErr != nil {
+		return `{"error":"` + ErrInternalServerError.Message + `","code":"` + codeInternal + `"}`
+	}
+	return string(body)
+}
+
+// FieldError describes a single invalid field in a request payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Code    string `json:"code"`
+
+	err error
+}
+
+func newFieldError(field string, err error) FieldError {
+	return FieldError{Field: field, Message: err.Error(), Code: errorCode(err), err: err}
+}
+
+// ValidationErrors collects every invalid field found while validating a request payload.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, fe := range v {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return ErrInvalidPayload.Error() + ": " + strings.Join(msgs, ", ")
+}
+
+// Unwrap returns the underlying field errors so errors.Is can match them (e.g ErrMissingFormFieldName).
+func (v ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(v))
+	for _, fe := range v {
+		if fe.err != nil {
+			errs = append(errs, fe.err)
+		}
+	}
+	return errs
+}
+
+// validationErrorsToJSON returns the structured JSON body listing all invalid fields.
+func validationErrorsToJSON(v ValidationErrors) string {
+	body, err := json.Marshal(struct {
+		Error  string       `json:"error"`
+		Code   string       `json:"code"`
+		Fields []FieldError `json:"fields"`
+	}{
+		Error:  ErrInvalidPayload.Error(),
+		Code:   ErrInvalidPayload.Code,
+		Fields: v,
+	})
+	if err != nil {
+		return errorToJSON(ErrInvalidPayload)
+	}
+	return string(body)
+}
+
+// writeBodyError writes the error response for a request body that couldn't be read or decoded:
+// 413 when the body is larger than the limit set by maxBodyMiddleware, otherwise 400 with the coded error
+// or invalid JSON.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	var codedErr *CodedError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeJSONError(w, fmt.Errorf("%w: limit is %d bytes", ErrRequestTooLarge, maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+	case errors.As(err, &codedErr):
+		writeJSONError(w, err, http.StatusBadRequest)
+	default:
+		writeJSONError(w, invalidJSONError(err), http.StatusBadRequest)
+	}
+}
+
+// invalidJSONError wraps ErrInvalidJSON with the problem found decoding a JSON body, e.g
+// "condition.threshold must be a number, got string". Errors that aren't decoding errors return ErrInvalidJSON.
+func invalidJSONError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return fmt.Errorf("%w: %s must be %s, got %s", ErrInvalidJSON, field, jsonTypeName(typeErr.Type), typeErr.Value)
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("%w: %s at offset %d", ErrInvalidJSON, syntaxErr.Error(), syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: unexpected end of input", ErrInvalidJSON)
+	default:
+		return ErrInvalidJSON
+	}
+}
+
+// jsonTypeName describes the JSON value expected for a Go type, e.g "a number" for a float64.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return t.String()
+	}
+}
+
+// writeJSONError writes the error as a JSON response body with the status. ValidationErrors are written with
+// every invalid field (see validationErrorsToJSON), other errors with errorToJSON.
+// Unlike http.Error, the response is sent with the application/json content type.
+func writeJSONError(w http.ResponseWriter, err error, status int) {
+	body := errorToJSON(err)
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		body = validationErrorsToJSON(validationErrs)
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	fmt.Fprintln(w, body)
 }
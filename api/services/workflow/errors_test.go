@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorToJSON(t *testing.T) {
+	tests := []struct {
+		label string
+		err   error
+		want  string
+	}{
+		{
+			label: "sentinel error",
+			err:   ErrWorkflowNotFound,
+			want:  `{"error":"workflow not found","code":"WORKFLOW_NOT_FOUND"}`,
+		},
+		{
+			label: "wrapped sentinel error keeps its code",
+			err:   fmt.Errorf("%w: edge e1 has unknown target x", ErrDanglingEdge),
+			want:  `{"error":"edge references a node that does not exist: edge e1 has unknown target x","code":"DANGLING_EDGE"}`,
+		},
+		{
+			label: "uncoded error",
+			err:   errors.New(`bad "quoted" value`),
+			want:  `{"error":"bad \"quoted\" value","code":"INTERNAL_ERROR"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			require.JSONEq(t, tt.want, errorToJSON(tt.err))
+		})
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	tests := []struct {
+		label  string
+		err    error
+		status int
+		want   string
+	}{
+		{
+			label:  "coded error",
+			err:    ErrWorkflowNotFound,
+			status: http.StatusNotFound,
+			want:   `{"error":"workflow not found","code":"WORKFLOW_NOT_FOUND"}`,
+		},
+		{
+			label:  "validation errors list every field",
+			err:    ValidationErrors{newFieldError("formData.name", ErrMissingFormFieldName)},
+			status: http.StatusBadRequest,
+			want:   `{"error":"invalid request payload","code":"INVALID_PAYLOAD","fields":[{"field":"formData.name","message":"name is required","code":"MISSING_FORM_FIELD_NAME"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeJSONError(rec, tt.err, tt.status)
+
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			require.JSONEq(t, tt.want, rec.Body.String())
+		})
+	}
+}
+
+func TestInvalidJSONError(t *testing.T) {
+	tests := []struct {
+		label string
+		body  string
+		want  string
+	}{
+		{
+			label: "wrong type names the field",
+			body:  `{"condition":{"operator":"greater_than","threshold":"25"}}`,
+			want:  "invalid JSON: condition.threshold must be a number, got string",
+		},
+		{
+			label: "wrong type of a nested object",
+			body:  `{"formData":["Alice"]}`,
+			want:  "invalid JSON: formData must be an object, got array",
+		},
+		{
+			label: "syntax error",
+			body:  `{"formData":{"name":'Alice'}}`,
+			want:  "invalid JSON: invalid character '\\'' looking for beginning of value at offset 21",
+		},
+		{
+			label: "truncated body",
+			body:  `{"formData":`,
+			want:  "invalid JSON: unexpected end of input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			var payload ExecutePayload
+			err := invalidJSONError(json.NewDecoder(strings.NewReader(tt.body)).Decode(&payload))
+
+			require.ErrorIs(t, err, ErrInvalidJSON)
+			require.EqualError(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package workflow
+
+import (
+	"fmt"
+	"sync"
+)
+
+// this file execution_context.go contains the Context holding the values produced during an execution.
+
+// Context holds the values the nodes produce during an execution (e.g "weather.temperature") so downstream
+// nodes can read them. It's safe for concurrent use and its typed accessors return errors instead of
+// panicking on a value of the wrong type.
+type Context struct {
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+// NewContext returns an empty Context.
+func NewContext() *Context {
+	return &Context{values: make(map[string]any)}
+}
+
+// newContextFrom returns a Context holding a copy of the values.
+func newContextFrom(values map[string]any) *Context {
+	c := NewContext()
+	for k, v := range values {
+		c.values[k] = v
+	}
+	return c
+}
+
+// Set stores the value under the key.
+func (c *Context) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+}
+
+// Get returns the value stored under the key and whether it's present.
+func (c *Context) Get(key string) (any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// SetFloat stores a numeric value under the key.
+func (c *Context) SetFloat(key string, value float64) {
+	c.Set(key, value)
+}
+
+// GetFloat returns the numeric value stored under the key. It returns ErrContextValueNotFound when the key
+// isn't present and ErrContextValueNotNumeric when the value isn't a float64.
+func (c *Context) GetFloat(key string) (float64, error) {
+	v, ok := c.Get(key)
+	if !ok {
+		return 0, fmt.Errorf("%s %w", key, ErrContextValueNotFound)
+	}
+	value, ok := toFloat64(v)
+	if !ok {
+		return 0, fmt.Errorf("%s %w", key, ErrContextValueNotNumeric)
+	}
+	return value, nil
+}
+
+// toFloat64 converts a numeric value of any int or float kind to a float64, e.g a count a custom processor stored
+// as an int. It returns false for non numeric values.
+func toFloat64(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int8:
+		return float64(val), true
+	case int16:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint8:
+		return float64(val), true
+	case uint16:
+		return float64(val), true
+	case uint32:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	default:
+		return 0, false
+	}
+}
+
+// Snapshot returns a copy of every value, e.g to include them in the execution result.
+func (c *Context) Snapshot() map[string]any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	values := make(map[string]any, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+	return values
+}
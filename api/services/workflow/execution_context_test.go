@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextGetFloat(t *testing.T) {
+	c := newContextFrom(map[string]any{"weather.temperature": 21.5, "alerts": 3, "readings": int64(7), "city": "Sydney"})
+
+	value, err := c.GetFloat("weather.temperature")
+	require.NoError(t, err)
+	require.Equal(t, 21.5, value)
+
+	value, err = c.GetFloat("alerts")
+	require.NoError(t, err)
+	require.Equal(t, 3.0, value)
+
+	value, err = c.GetFloat("readings")
+	require.NoError(t, err)
+	require.Equal(t, 7.0, value)
+
+	_, err = c.GetFloat("weather.windspeed")
+	require.ErrorIs(t, err, ErrContextValueNotFound)
+	require.EqualError(t, err, "weather.windspeed not in context data")
+
+	_, err = c.GetFloat("city")
+	require.ErrorIs(t, err, ErrContextValueNotNumeric)
+}
+
+func TestContextConcurrentAccess(t *testing.T) {
+	c := NewContext()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("value.%d", i)
+			c.SetFloat(key, float64(i))
+			_, _ = c.GetFloat(key)
+			_ = c.Snapshot()
+		}(i)
+	}
+	wg.Wait()
+
+	require.Len(t, c.Snapshot(), 50)
+}
@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// this file executions.go contains the registry of the in-flight executions, used to cancel them by ID.
+
+// executionIDHeader carries the execution ID. A client can choose the ID of its execution by sending the header,
+// so it can cancel a synchronous execution it's still waiting for.
+const executionIDHeader = "X-Execution-ID"
+
+// workflowIDHeader carries the ID an inline execution is tracked under, generated for every inline execution since
+// the definition's own ID may be empty or shared by concurrent drafts. It addresses the execution on the cancel route.
+const workflowIDHeader = "X-Workflow-ID"
+
+// executionStatusCanceled is the status reported by the cancel endpoint.
+const executionStatusCanceled = "canceled"
+
+// executionRegistry keeps the cancel func of every in-flight execution of the service, keyed by execution ID.
+// Executions aren't shared between API instances.
+type executionRegistry struct {
+	mu     sync.Mutex
+	active map[string]*activeExecution
+}
+
+// activeExecution is the registry entry of an execution. The runner removes its own entry by pointer, so an
+// execution ID reused once the entry is gone can't have its entry removed by the previous runner.
+type activeExecution struct {
+	workflowID string
+	cancel     context.CancelCauseFunc
+	canceled   bool
+}
+
+func newExecutionRegistry() *executionRegistry {
+	return &executionRegistry{active: make(map[string]*activeExecution)}
+}
+
+// register tracks the execution until remove is called with the returned entry. It returns false when an
+// execution with the same ID is already in flight.
+func (r *executionRegistry) register(workflowID, executionID string, cancel context.CancelCauseFunc) (*activeExecution, bool) {
+	execution := &activeExecution{workflowID: workflowID, cancel: cancel}
+	if r == nil {
+		return execution, true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.active[executionID]; ok {
+		return nil, false
+	}
+	r.active[executionID] = execution
+	return execution, true
+}
+
+// remove stops tracking the execution once it has finished. The entry is only deleted while it's still the one
+// registered under the ID.
+func (r *executionRegistry) remove(executionID string, execution *activeExecution) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active[executionID] == execution {
+		delete(r.active, executionID)
+	}
+}
+
+// cancel cancels the in-flight execution of the workflow with ErrExecutionCanceled. It returns false when the
+// workflow has no such execution in flight, or it was already canceled. The entry is kept until its runner
+// removes it.
+func (r *executionRegistry) cancel(workflowID, executionID string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	execution, ok := r.active[executionID]
+	if !ok || execution.workflowID != workflowID || execution.canceled {
+		return false
+	}
+	execution.cancel(ErrExecutionCanceled)
+	execution.canceled = true
+	return true
+}
+
+// CancelExecutionResponse is returned by the cancel endpoint.
+type CancelExecutionResponse struct {
+	ExecutionID string `json:"executionId"`
+	Status      string `json:"status"`
+}
+
+// HandleCancelExecution cancels an in-flight execution of the workflow. The execution stops before its next node
+// and reports the ErrExecutionCanceled error. It responds with 404 when the execution isn't in flight.
+func (s *Service) HandleCancelExecution(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, executionID := vars["id"], vars["execId"]
+	logger := loggerFromContext(r.Context())
+
+	if !s.executions.cancel(id, executionID) {
+		writeJSONError(w, ErrExecutionNotFound, http.StatusNotFound)
+		return
+	}
+	logger.Info("Canceled workflow execution", "id", id, "execution id", executionID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(CancelExecutionResponse{ExecutionID: executionID, Status: executionStatusCanceled})
+}
@@ -0,0 +1,109 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionRegistry(t *testing.T) {
+	registry := newExecutionRegistry()
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	first, ok := registry.register("wf-1", "exec-1", cancel)
+	require.True(t, ok)
+	_, ok = registry.register("wf-1", "exec-1", cancel)
+	require.False(t, ok, "the id is already in flight")
+
+	require.False(t, registry.cancel("wf-2", "exec-1"), "the execution belongs to another workflow")
+	require.NoError(t, ctx.Err())
+
+	require.True(t, registry.cancel("wf-1", "exec-1"))
+	require.ErrorIs(t, context.Cause(ctx), ErrExecutionCanceled)
+	require.False(t, registry.cancel("wf-1", "exec-1"), "the execution is already canceled")
+	_, ok = registry.register("wf-1", "exec-1", cancel)
+	require.False(t, ok, "a canceled execution is tracked until its runner removes it")
+	registry.remove("exec-1", first)
+
+	// the id is reused by a new execution, the previous runner removing its entry again leaves it tracked
+	next, ok := registry.register("wf-1", "exec-1", cancel)
+	require.True(t, ok)
+	registry.remove("exec-1", first)
+	require.True(t, registry.cancel("wf-1", "exec-1"))
+	registry.remove("exec-1", next)
+	require.False(t, registry.cancel("wf-1", "exec-1"))
+}
+
+func TestHandleCancelExecution(t *testing.T) {
+	started := make(chan struct{})
+	RegisterNodeProcessor("wait", NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	defer UnregisterNodeProcessor("wait")
+
+	definition := `{"id":"draft","nodes":[{"id":"start","type":"start"},{"id":"wait","type":"wait"},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"wait"},{"id":"e2","source":"wait","target":"end"}]}`
+	payload := `{"formData":{"name":"Jane","email":"jane@example.com","city":"Sydney"},"condition":{"operator":"greater_than","threshold":20}}`
+
+	router := mux.NewRouter()
+	s := &Service{config: DefaultConfig(), executions: newExecutionRegistry()}
+	s.LoadRoutes(router, false)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// the execution is streamed so its headers arrive while it's still running
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/workflows/execute", strings.NewReader(`{"definition":`+definition+`,"payload":`+payload+`}`))
+	require.NoError(t, err)
+	req.Header.Set(executionIDHeader, "exec-1")
+	req.Header.Set("Accept", ndjsonContentType)
+	execution, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer execution.Body.Close()
+	<-started
+
+	require.Equal(t, "exec-1", execution.Header.Get(executionIDHeader))
+	workflowID := execution.Header.Get(workflowIDHeader)
+	require.NotEmpty(t, workflowID)
+	require.NotEqual(t, "draft", workflowID, "inline executions aren't tracked under the definition id")
+
+	cancel := func(path string) *http.Response {
+		resp, err := http.Post(server.URL+path, "application/json", nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("error: unknown execution", func(t *testing.T) {
+		resp := cancel("/workflows/" + workflowID + "/executions/exec-2/cancel")
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("error: definition id", func(t *testing.T) {
+		resp := cancel("/workflows/draft/executions/exec-1/cancel")
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("cancels the running execution", func(t *testing.T) {
+		resp := cancel("/workflows/" + workflowID + "/executions/exec-1/cancel")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body CancelExecutionResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Equal(t, CancelExecutionResponse{ExecutionID: "exec-1", Status: executionStatusCanceled}, body)
+
+		stream, err := io.ReadAll(execution.Body)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(stream)), "\n")
+		var summary ExecutionSummary
+		require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+		require.Equal(t, ErrExecutionCanceled.Code, summary.Code)
+	})
+}
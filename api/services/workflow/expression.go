@@ -0,0 +1,386 @@
+package workflow
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// this file expression.go contains the arithmetic expression evaluator used by the transform node and
+// the condition node expressions.
+//
+// Supported syntax:
+//   - numbers (e.g 0.5) and variables (e.g temperature or weather.windspeed)
+//   - the + - * / operators with the usual precedence, unary minus and parentheses
+//   - calls to the functions in expressionFunctions (e.g abs(temperature - 20) or max(temperature, feels_like))
+//   - in condition expressions only, one comparison between two expressions: < <= > >= ==
+//
+// Variables are resolved from contextData by their exact key first, then as a weather metric
+// (e.g temperature resolves to weather.temperature).
+
+// expressionFunction is a function callable from an expression.
+type expressionFunction struct {
+	// arity is the number of arguments, variadicArity for one or more
+	arity int
+	call  func(args []float64) float64
+}
+
+// variadicArity is the arity of the functions taking one or more arguments.
+const variadicArity = -1
+
+// expressionFunctions are the functions an expression can call, by name.
+var expressionFunctions = map[string]expressionFunction{
+	"abs":   {arity: 1, call: func(args []float64) float64 { return math.Abs(args[0]) }},
+	"round": {arity: 1, call: func(args []float64) float64 { return math.Round(args[0]) }},
+	"floor": {arity: 1, call: func(args []float64) float64 { return math.Floor(args[0]) }},
+	"ceil":  {arity: 1, call: func(args []float64) float64 { return math.Ceil(args[0]) }},
+	"sqrt":  {arity: 1, call: func(args []float64) float64 { return math.Sqrt(args[0]) }},
+	"pow":   {arity: 2, call: func(args []float64) float64 { return math.Pow(args[0], args[1]) }},
+	"min":   {arity: variadicArity, call: func(args []float64) float64 { return slices.Min(args) }},
+	"max":   {arity: variadicArity, call: func(args []float64) float64 { return slices.Max(args) }},
+}
+
+// comparisonOperators maps the comparison symbols of a condition expression to the condition operators.
+var comparisonOperators = map[string]string{
+	"<":  OperatorLessThan,
+	"<=": OperatorLessThanOrEqual,
+	">":  OperatorGreaterThan,
+	">=": OperatorGreaterThanOrEqual,
+	"==": OperatorEquals,
+}
+
+// evaluateExpression parses the expression and evaluates it against contextData.
+func evaluateExpression(expr string, contextData *Context) (float64, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	p := &expressionParser{tokens: tokens, contextData: contextData}
+	value, err := p.parseSum()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.tokens) {
+		return 0, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, p.tokens[p.pos].text)
+	}
+
+	return value, nil
+}
+
+// comparison is a condition expression evaluated against contextData.
+type comparison struct {
+	// left is the expression on the left-hand side of the comparison, e.g "abs(temperature - 20)"
+	left string
+	// value is the value of the left-hand side
+	value float64
+	// condition compares the value with the right-hand side value as its threshold
+	condition Condition
+}
+
+// evaluateComparison parses a condition expression comparing two expressions (e.g "abs(temperature - 20) < 5")
+// and evaluates both sides against contextData.
+func evaluateComparison(expr string, contextData *Context) (comparison, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return comparison{}, err
+	}
+
+	p := &expressionParser{tokens: tokens, contextData: contextData}
+	left, err := p.parseSum()
+	if err != nil {
+		return comparison{}, err
+	}
+	if p.pos >= len(p.tokens) {
+		return comparison{}, fmt.Errorf("%w: missing comparison", ErrInvalidExpression)
+	}
+	opToken := p.tokens[p.pos]
+	operator, ok := comparisonOperators[opToken.text]
+	if opToken.kind != tokenOperator || !ok {
+		return comparison{}, fmt.Errorf("%w: expected a comparison, got %q", ErrInvalidExpression, opToken.text)
+	}
+	p.pos++
+
+	right, err := p.parseSum()
+	if err != nil {
+		return comparison{}, err
+	}
+	if p.pos < len(p.tokens) {
+		return comparison{}, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, p.tokens[p.pos].text)
+	}
+
+	return comparison{
+		left:      strings.TrimSpace(string([]rune(expr)[:opToken.pos])),
+		value:     left,
+		condition: Condition{Operator: operator, Threshold: right},
+	}, nil
+}
+
+// expressionVariables returns the names of the variables the expression reads, in order of appearance.
+func expressionVariables(expr string) ([]string, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for i, tok := range tokens {
+		if tok.kind != tokenIdent {
+			continue
+		}
+		// a name followed by a parenthesis is a function call
+		if i+1 < len(tokens) && tokens[i+1].kind == tokenOperator && tokens[i+1].text == "(" {
+			continue
+		}
+		if !slices.Contains(names, tok.text) {
+			names = append(names, tok.text)
+		}
+	}
+	return names, nil
+}
+
+// expressionVariableKeys returns the contextData keys a variable can be resolved from, in lookup order.
+func expressionVariableKeys(name string) []string {
+	return []string{name, weatherContextKey(name)}
+}
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenIdent
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind tokenKind
+	text string
+	pos  int // offset of the token in the expression, in runes
+}
+
+// tokenizeExpression splits the expression into number, identifier and operator tokens.
+func tokenizeExpression(expr string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/(),", r):
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: string(r), pos: i})
+			i++
+		case strings.ContainsRune("<>=", r):
+			// comparisons, with or without a trailing =
+			start := i
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidExpression, r)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty expression", ErrInvalidExpression)
+	}
+	return tokens, nil
+}
+
+// expressionParser is a recursive descent parser evaluating the tokens as it goes.
+type expressionParser struct {
+	tokens      []expressionToken
+	pos         int
+	contextData *Context
+}
+
+// peekOperator reports whether the next token is one of the operators.
+func (p *expressionParser) peekOperator(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// parseSum handles + and -.
+func (p *expressionParser) parseSum() (float64, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op, ok := p.peekOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseProduct()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+// parseProduct handles * and /.
+func (p *expressionParser) parseProduct() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op, ok := p.peekOperator("*", "/")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		if op == "*" {
+			left *= right
+			continue
+		}
+		if right == 0 {
+			return 0, fmt.Errorf("%w: division by zero", ErrInvalidExpression)
+		}
+		left /= right
+	}
+}
+
+// parseUnary handles a leading minus.
+func (p *expressionParser) parseUnary() (float64, error) {
+	if _, ok := p.peekOperator("-"); ok {
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary handles numbers, variables, function calls and parenthesised expressions.
+func (p *expressionParser) parsePrimary() (float64, error) {
+	if p.pos >= len(p.tokens) {
+		return 0, fmt.Errorf("%w: unexpected end of expression", ErrInvalidExpression)
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid number %q", ErrInvalidExpression, tok.text)
+		}
+		return value, nil
+	case tokenIdent:
+		if _, ok := p.peekOperator("("); ok {
+			return p.parseCall(tok.text)
+		}
+		return p.variable(tok.text)
+	default:
+		if tok.text != "(" {
+			return 0, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, tok.text)
+		}
+		value, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.peekOperator(")"); !ok {
+			return 0, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidExpression)
+		}
+		p.pos++
+		return value, nil
+	}
+}
+
+// parseCall handles the arguments of a call to the named function, the next token being the opening parenthesis.
+func (p *expressionParser) parseCall(name string) (float64, error) {
+	fn, ok := expressionFunctions[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown function %s", ErrInvalidExpression, name)
+	}
+	p.pos++
+
+	var args []float64
+	if _, ok := p.peekOperator(")"); !ok {
+		for {
+			arg, err := p.parseSum()
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, arg)
+			if _, ok := p.peekOperator(","); !ok {
+				break
+			}
+			p.pos++
+		}
+	}
+	if _, ok := p.peekOperator(")"); !ok {
+		return 0, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidExpression)
+	}
+	p.pos++
+
+	switch {
+	case fn.arity == variadicArity && len(args) == 0:
+		return 0, fmt.Errorf("%w: %s expects at least 1 argument", ErrInvalidExpression, name)
+	case fn.arity != variadicArity && len(args) != fn.arity:
+		return 0, fmt.Errorf("%w: %s expects %d argument(s), got %d", ErrInvalidExpression, name, fn.arity, len(args))
+	}
+
+	value := fn.call(args)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%w: %s result is not a finite number", ErrInvalidExpression, name)
+	}
+	return value, nil
+}
+
+// variable resolves a variable from contextData.
+func (p *expressionParser) variable(name string) (float64, error) {
+	for _, key := range expressionVariableKeys(name) {
+		v, ok := p.contextData.Get(key)
+		if !ok {
+			continue
+		}
+		value, ok := toFloat64(v)
+		if !ok {
+			return 0, fmt.Errorf("%w: variable %s is not numeric", ErrInvalidExpression, name)
+		}
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("%w: unknown variable %s", ErrInvalidExpression, name)
+}
@@ -0,0 +1,124 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	contextData := map[string]any{
+		"weather.temperature": 20.0,
+		"weather.windspeed":   10.0,
+		"feels_like":          15.0,
+		"label":               "warm",
+		"alerts":              3,
+		"readings":            uint8(4),
+		"humidity":            float32(0.5),
+	}
+
+	tests := []struct {
+		label       string
+		expr        string
+		want        float64
+		expectErr   bool
+		errExpected error
+	}{
+		{label: "precedence", expr: "temperature - windspeed * 0.5", want: 15},
+		{label: "parentheses", expr: "(temperature - windspeed) * 0.5", want: 5},
+		{label: "unary minus", expr: "-feels_like + 1", want: -14},
+		{label: "full context key", expr: "weather.windspeed / 4", want: 2.5},
+		{label: "function", expr: "abs(feels_like - temperature)", want: 5},
+		{label: "variadic function", expr: "max(temperature, feels_like, windspeed) - min(temperature, feels_like)", want: 5},
+		{label: "nested functions", expr: "round(sqrt(pow(temperature, 2) + 1))", want: 20},
+		{label: "int variable", expr: "alerts * 2 + temperature", want: 26},
+		{label: "uint variable", expr: "readings / alerts", want: 4.0 / 3},
+		{label: "float32 variable", expr: "humidity * 100", want: 50},
+		{label: "error: unknown function", expr: "median(temperature)", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: wrong number of arguments", expr: "abs(temperature, 1)", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: variadic function without arguments", expr: "max()", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: non finite result", expr: "sqrt(-feels_like)", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: comparison", expr: "temperature > 1", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: unknown variable", expr: "pressure * 2", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: non numeric variable", expr: "label + 1", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: division by zero", expr: "temperature / 0", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: missing parenthesis", expr: "(temperature + 1", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: trailing token", expr: "temperature 1", expectErr: true, errExpected: ErrInvalidExpression},
+		{label: "error: empty", expr: "  ", expectErr: true, errExpected: ErrInvalidExpression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := evaluateExpression(tt.expr, newContextFrom(contextData))
+			if tt.expectErr {
+				require.ErrorIs(t, err, tt.errExpected)
+			} else {
+				require.NoError(t, err)
+				require.InDelta(t, tt.want, got, 1e-9)
+			}
+		})
+	}
+}
+
+func TestEvaluateComparison(t *testing.T) {
+	contextData := map[string]any{
+		"weather.temperature": 17.0,
+		"feels_like":          31.0,
+		"alerts":              3,
+	}
+
+	tests := []struct {
+		label         string
+		expr          string
+		wantLeft      string
+		wantValue     float64
+		wantCondition Condition
+		expectErr     bool
+	}{
+		{
+			label:         "function on the left-hand side",
+			expr:          "abs(temperature - 20) < 5",
+			wantLeft:      "abs(temperature - 20)",
+			wantValue:     3,
+			wantCondition: Condition{Operator: OperatorLessThan, Threshold: 5},
+		},
+		{
+			label:         "expression on the right-hand side",
+			expr:          "max(temperature, feels_like) >= temperature * 2",
+			wantLeft:      "max(temperature, feels_like)",
+			wantValue:     31,
+			wantCondition: Condition{Operator: OperatorGreaterThanOrEqual, Threshold: 34},
+		},
+		{
+			label:         "equals",
+			expr:          "round(temperature)==17",
+			wantLeft:      "round(temperature)",
+			wantValue:     17,
+			wantCondition: Condition{Operator: OperatorEquals, Threshold: 17},
+		},
+		{
+			label:         "int-valued context key",
+			expr:          "alerts * 10 > temperature",
+			wantLeft:      "alerts * 10",
+			wantValue:     30,
+			wantCondition: Condition{Operator: OperatorGreaterThan, Threshold: 17},
+		},
+		{label: "error: missing comparison", expr: "temperature + 1", expectErr: true},
+		{label: "error: unsupported comparison", expr: "temperature = 1", expectErr: true},
+		{label: "error: two comparisons", expr: "1 < temperature < 20", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := evaluateComparison(tt.expr, newContextFrom(contextData))
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrInvalidExpression)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantLeft, got.left)
+			require.InDelta(t, tt.wantValue, got.value, 1e-9)
+			require.Equal(t, tt.wantCondition, got.condition)
+		})
+	}
+}
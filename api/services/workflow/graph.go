@@ -0,0 +1,124 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// this file graph.go contains the layout view of a stored workflow (GET /workflows/{id}/graph), e.g for a read-only viewer.
+
+// positionsQueryParam leaves the node positions out of the graph view when false.
+const positionsQueryParam = "positions"
+
+// WorkflowGraph is the projection of a workflow definition needed to render it, without the node metadata.
+type WorkflowGraph struct {
+	ID    string      `json:"id"`
+	Name  string      `json:"name,omitempty"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Label    string    `json:"label,omitempty"`
+	Position *Position `json:"position,omitempty"` // omitted with positions=false
+}
+
+type GraphEdge struct {
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	SourceHandle string `json:"sourceHandle,omitempty"`
+	Label        string `json:"label,omitempty"`
+}
+
+// workflowGraph projects the definition to its graph view, with the node positions when includePositions is set.
+func workflowGraph(wf WorkflowDefinition, includePositions bool) WorkflowGraph {
+	graph := WorkflowGraph{
+		ID:    wf.ID,
+		Name:  wf.Name,
+		Nodes: make([]GraphNode, 0, len(wf.Nodes)),
+		Edges: make([]GraphEdge, 0, len(wf.Edges)),
+	}
+	for _, node := range wf.Nodes {
+		graphNode := GraphNode{ID: node.ID, Type: node.Type, Label: node.Data.Label}
+		if includePositions {
+			position := node.Position
+			graphNode.Position = &position
+		}
+		graph.Nodes = append(graph.Nodes, graphNode)
+	}
+	for _, edge := range wf.Edges {
+		graph.Edges = append(graph.Edges, GraphEdge{
+			ID:           edge.ID,
+			Source:       edge.Source,
+			Target:       edge.Target,
+			SourceHandle: edge.SourceHandle,
+			Label:        edge.Label,
+		})
+	}
+	return graph
+}
+
+// HandleGetWorkflowGraph returns the graph view of the workflow, the node positions are included unless the
+// positions query parameter is false.
+func (s *Service) HandleGetWorkflowGraph(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	ctx := r.Context()
+	logger := loggerFromContext(ctx)
+	logger.Debug("Returning workflow graph for id", "id", id)
+
+	includePositions := true
+	if raw := r.URL.Query().Get(positionsQueryParam); raw != "" {
+		var err error
+		if includePositions, err = strconv.ParseBool(raw); err != nil {
+			writeJSONError(w, fmt.Errorf("%w: %s", ErrInvalidQueryParam, positionsQueryParam), http.StatusBadRequest)
+			return
+		}
+	}
+
+	record, err := s.GetWorkflowByID(ctx, id)
+	if err != nil {
+		var status int
+		var respErr error
+
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			status = http.StatusNotFound
+			respErr = ErrWorkflowNotFound
+		default:
+			logger.Error("Error loading workflow", "id", id, "error", err)
+			status = http.StatusInternalServerError
+			respErr = ErrInternalServerError
+		}
+
+		writeJSONError(w, respErr, status)
+		return
+	}
+
+	var wf WorkflowDefinition
+	if err := json.Unmarshal(record.Definition, &wf); err != nil {
+		logger.Error("Invalid workflow format", "id", id, "error", err)
+		writeJSONError(w, ErrInvalidWorkflowFormat, http.StatusInternalServerError)
+		return
+	}
+
+	jsonBytes, err := json.Marshal(workflowGraph(wf, includePositions))
+	if err != nil {
+		logger.Error("Failed to marshal workflow graph", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
+		return
+	}
+
+	setWorkflowTimestampHeaders(w, record)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonBytes)
+}
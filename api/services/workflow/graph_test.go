@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowGraph(t *testing.T) {
+	wf := WorkflowDefinition{
+		ID:   "wf-1",
+		Name: "Heat alert",
+		Nodes: []Node{
+			{ID: "start", Type: "start", Position: Position{X: 10, Y: 20}, Data: NodeData{Label: "Start"}},
+			{ID: "check", Type: "condition", Position: Position{X: 30, Y: 40}, Data: NodeData{Label: "Too hot?", Metadata: NodeMetadata{InputVariables: []string{"temperature"}}}},
+		},
+		Edges: []Edge{
+			{ID: "e1", Source: "start", Target: "check", Type: "smoothstep", Style: map[string]interface{}{"stroke": "#10b981"}},
+			{ID: "e2", Source: "check", Target: "end", SourceHandle: "true", Label: "hot"},
+		},
+	}
+
+	tests := []struct {
+		label            string
+		includePositions bool
+		want             string
+	}{
+		{
+			label:            "with positions",
+			includePositions: true,
+			want:             `{"id":"wf-1","name":"Heat alert","nodes":[{"id":"start","type":"start","label":"Start","position":{"x":10,"y":20}},{"id":"check","type":"condition","label":"Too hot?","position":{"x":30,"y":40}}],"edges":[{"id":"e1","source":"start","target":"check"},{"id":"e2","source":"check","target":"end","sourceHandle":"true","label":"hot"}]}`,
+		},
+		{
+			label: "without positions",
+			want:  `{"id":"wf-1","name":"Heat alert","nodes":[{"id":"start","type":"start","label":"Start"},{"id":"check","type":"condition","label":"Too hot?"}],"edges":[{"id":"e1","source":"start","target":"check"},{"id":"e2","source":"check","target":"end","sourceHandle":"true","label":"hot"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			graph, err := json.Marshal(workflowGraph(wf, tt.includePositions))
+			require.NoError(t, err)
+			require.JSONEq(t, tt.want, string(graph))
+		})
+	}
+
+	t.Run("empty definition", func(t *testing.T) {
+		graph, err := json.Marshal(workflowGraph(WorkflowDefinition{ID: "wf-2"}, true))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":"wf-2","nodes":[],"edges":[]}`, string(graph))
+	})
+}
+
+func TestHandleGetWorkflowGraphInvalidPositions(t *testing.T) {
+	router := mux.NewRouter()
+	s := &Service{config: DefaultConfig()}
+	s.LoadRoutes(router, false)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/workflows/wf-1/graph?positions=maybe", nil))
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), ErrInvalidQueryParam.Code)
+}
@@ -0,0 +1,57 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// this file health.go contains the health check answering load balancer and orchestrator probes.
+
+// healthCheckTimeout bounds the database ping so a probe gets a quick answer when the database hangs.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthResponse is the health check response body.
+type HealthResponse struct {
+	Status   string `json:"status"`
+	Database string `json:"database"`
+}
+
+const (
+	healthStatusOK          = "ok"
+	healthStatusUnavailable = "unavailable"
+)
+
+// HandleHealthz answers GET /healthz with 200 when the database can be reached and 503 otherwise.
+func (s *Service) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	healthHandler(s.PingDB).ServeHTTP(w, r)
+}
+
+// healthHandler returns the health check handler using ping to check the database connectivity.
+func healthHandler(ping func(ctx context.Context) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		status := http.StatusOK
+		resp := HealthResponse{Status: healthStatusOK, Database: healthStatusOK}
+		if err := ping(ctx); err != nil {
+			loggerFromContext(r.Context()).Error("Database health check failed", "error", err)
+			status = http.StatusServiceUnavailable
+			resp = HealthResponse{Status: healthStatusUnavailable, Database: healthStatusUnavailable}
+		}
+
+		jsonBytes, err := json.Marshal(resp)
+		if err != nil {
+			writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
+			return
+		}
+
+		// probes must never see a cached answer
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(jsonBytes)
+	})
+}
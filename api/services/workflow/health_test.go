@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		label      string
+		pingErr    error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			label:      "database reachable",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"ok","database":"ok"}`,
+		},
+		{
+			label:      "database unreachable",
+			pingErr:    errors.New("connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"status":"unavailable","database":"unavailable"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			ping := func(ctx context.Context) error {
+				_, ok := ctx.Deadline()
+				require.True(t, ok, "the ping is bounded by a timeout")
+				return tt.pingErr
+			}
+
+			rec := httptest.NewRecorder()
+			healthHandler(ping).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			require.JSONEq(t, tt.wantBody, rec.Body.String())
+		})
+	}
+}
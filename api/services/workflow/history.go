@@ -0,0 +1,66 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// this file history.go contains the history node, loading values of the previous execution of the workflow
+// into contextData (e.g to alert when the temperature dropped since the last run).
+
+// ExecutionRecord is a stored execution of a workflow.
+type ExecutionRecord struct {
+	ID         string
+	WorkflowID string
+	Result     ExecutionResult
+	Context    map[string]any // final contextData of the execution
+	CreatedAt  time.Time
+}
+
+// processHistoryNode loads the values of the most recent prior execution into contextData, as mapped by the node
+// history values (context key to the key in the previous execution context). It returns the previous execution,
+// nil when the workflow has no prior execution (or no history is available, e.g for an inline execution), and the
+// mapped keys the previous execution has no value for.
+func processHistoryNode(ctx context.Context, node Node, contextData *Context, lastExecution func(ctx context.Context, workflowID string) (*ExecutionRecord, error)) (*ExecutionRecord, []string, error) {
+	cfg := node.Data.Metadata.History
+	if cfg == nil || len(cfg.Values) == 0 {
+		return nil, nil, fmt.Errorf("history node %s has no values configured", node.ID)
+	}
+
+	stats, ok := ctx.Value(executionStatsContextKey{}).(*executionStats)
+	if lastExecution == nil || !ok || stats.workflowID == "" {
+		return nil, nil, nil
+	}
+
+	previous, err := lastExecution(ctx, stats.workflowID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the previous execution: %w", err)
+	}
+	if previous == nil {
+		return nil, nil, nil
+	}
+
+	var missing []string
+	for key, previousKey := range cfg.Values {
+		value, ok := previous.Context[previousKey]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		contextData.Set(key, value)
+	}
+	sort.Strings(missing)
+	return previous, missing, nil
+}
+
+// hasHistoryNode reports whether the workflow has a history node, i.e whether its executions have to be stored.
+func hasHistoryNode(wf *WorkflowDefinition) bool {
+	for _, node := range wf.Nodes {
+		if nodeType, _ := resolveNodeType(node, builtinProcessors(ExecutionOptions{})); nodeType == HistoryNodeID && !hasCustomProcessor(nodeType) {
+			return true
+		}
+	}
+	return false
+}
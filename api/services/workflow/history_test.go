@@ -0,0 +1,187 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessHistoryNode(t *testing.T) {
+	node := Node{ID: HistoryNodeID, Data: NodeData{Metadata: NodeMetadata{History: &HistoryConfig{Values: map[string]string{
+		"previousTemperature": "weather.temperature",
+		"previousWind":        "weather.windspeed",
+	}}}}}
+	previous := &ExecutionRecord{ID: "exec-1", Context: map[string]any{"weather.temperature": 21.5}}
+	ctx := contextWithExecutionStats(context.Background(), &executionStats{workflowID: "wf-1"})
+
+	tests := []struct {
+		label         string
+		node          Node
+		ctx           context.Context
+		lastExecution func(ctx context.Context, workflowID string) (*ExecutionRecord, error)
+		wantPrevious  *ExecutionRecord
+		wantMissing   []string
+		wantContext   map[string]any
+		wantErr       string
+	}{
+		{
+			label: "loads the previous values",
+			node:  node,
+			ctx:   ctx,
+			lastExecution: func(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+				require.Equal(t, "wf-1", workflowID)
+				return previous, nil
+			},
+			wantPrevious: previous,
+			wantMissing:  []string{"previousWind"},
+			wantContext:  map[string]any{"previousTemperature": 21.5},
+		},
+		{
+			label: "no previous execution",
+			node:  node,
+			ctx:   ctx,
+			lastExecution: func(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+				return nil, nil
+			},
+			wantContext: map[string]any{},
+		},
+		{
+			label:       "no history available",
+			node:        node,
+			ctx:         ctx,
+			wantContext: map[string]any{},
+		},
+		{
+			label: "unsaved workflow",
+			node:  node,
+			ctx:   context.Background(),
+			lastExecution: func(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+				t.Fatal("an execution outside of processNodes has no workflow history")
+				return nil, nil
+			},
+			wantContext: map[string]any{},
+		},
+		{
+			label: "error: lookup failed",
+			node:  node,
+			ctx:   ctx,
+			lastExecution: func(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+				return nil, errors.New("connection refused")
+			},
+			wantErr: "failed to load the previous execution: connection refused",
+		},
+		{
+			label:   "error: no values configured",
+			node:    Node{ID: HistoryNodeID},
+			ctx:     ctx,
+			wantErr: "history node history has no values configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			contextData := NewContext()
+			got, missing, err := processHistoryNode(tt.ctx, tt.node, contextData, tt.lastExecution)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPrevious, got)
+			require.Equal(t, tt.wantMissing, missing)
+			require.Equal(t, tt.wantContext, contextData.Snapshot())
+		})
+	}
+}
+
+func TestProcessNodesHistoryDelta(t *testing.T) {
+	temperature := 0.0
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		contextData.SetFloat(weatherContextKey(WeatherMetricTemperature), temperature)
+		return nil
+	}
+	defer func() { processWeatherNodeFn = processWeatherNode }()
+
+	wf := &WorkflowDefinition{
+		ID: "wf-1",
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: HistoryNodeID, Data: NodeData{Metadata: NodeMetadata{History: &HistoryConfig{Values: map[string]string{
+				"previousTemperature": "weather.temperature",
+			}}}}},
+			{ID: WeatherAPINodeID},
+			{ID: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{ConditionExpr: "previousTemperature - temperature >= 10"}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{ID: "e1", Source: StartNodeID, Target: WeatherAPINodeID},
+			{ID: "e2", Source: WeatherAPINodeID, Target: HistoryNodeID},
+			{ID: "e3", Source: HistoryNodeID, Target: ConditionNodeID},
+			{ID: "e4", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionMetHandle},
+			{ID: "e5", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+		},
+	}
+
+	// the executions are stored in memory the way the execute route stores them in the database
+	var stored []*ExecutionRecord
+	opts := ExecutionOptions{
+		LastExecution: func(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+			if len(stored) == 0 {
+				return nil, nil
+			}
+			return stored[len(stored)-1], nil
+		},
+		OnExecutionComplete: func(ctx context.Context, result *ExecutionResult, contextData map[string]any) {
+			stored = append(stored, &ExecutionRecord{ID: "exec", WorkflowID: "wf-1", Result: *result, Context: contextData})
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{City: "Sydney"}}
+
+	// the first run has no history, so the condition has nothing to compare against
+	temperature = 25
+	got, err := processNodes(context.Background(), wf, payload, opts)
+	require.NoError(t, err)
+	require.Equal(t, false, got.Steps[2].Output["found"])
+	require.Len(t, stored, 1)
+
+	temperature = 14
+	got, err = processNodes(context.Background(), wf, payload, opts)
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Equal(t, map[string]interface{}{"previousTemperature": 25.0}, got.Steps[2].Output["values"])
+	require.Equal(t, true, got.Steps[3].Output["conditionMet"])
+
+	temperature = 10
+	got, err = processNodes(context.Background(), wf, payload, opts)
+	require.NoError(t, err)
+	require.Equal(t, false, got.Steps[3].Output["conditionMet"])
+	require.Len(t, stored, 3)
+}
+
+func TestWithStoredWorkflowHooksStoresExecutions(t *testing.T) {
+	tests := []struct {
+		label     string
+		nodes     []Node
+		dryRun    bool
+		wantStore bool
+	}{
+		{label: "with a history node", nodes: []Node{{ID: StartNodeID}, {ID: HistoryNodeID}, {ID: EndNodeID}}, wantStore: true},
+		{label: "with a typed history node", nodes: []Node{{ID: "previous", Type: HistoryNodeID}}, wantStore: true},
+		{label: "without a history node", nodes: []Node{{ID: StartNodeID}, {ID: EndNodeID}}},
+		{label: "dry run", nodes: []Node{{ID: HistoryNodeID}}, dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			s := &Service{config: DefaultConfig()}
+			opts := ExecutionOptions{DryRun: tt.dryRun}
+			s.withStoredWorkflowHooks("wf-1", &WorkflowDefinition{ID: "wf-1", Nodes: tt.nodes}, &opts)
+
+			require.Equal(t, tt.wantStore, opts.OnExecutionComplete != nil)
+			require.NotNil(t, opts.LastExecution)
+			require.NotNil(t, opts.OnDeadLetter)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package workflow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// this file idempotency.go contains the cache of execution responses keyed by the Idempotency-Key header.
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+
+	// defaultIdempotencyWindow is how long an execution response is replayed for the same key.
+	defaultIdempotencyWindow = 24 * time.Hour
+)
+
+// idempotencyCache keeps the execution responses in memory for the configured window.
+// Responses aren't shared between API instances.
+type idempotencyCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*idempotencyEntry
+	now     func() time.Time
+}
+
+// idempotencyEntry is the execution of a key: in flight until done is closed, then the response body (nil when the
+// execution didn't complete and the key was released, see release).
+type idempotencyEntry struct {
+	payloadHash string
+	body        []byte
+	done        chan struct{}
+	expiresAt   time.Time
+}
+
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		window:  window,
+		entries: make(map[string]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+// idempotencyCacheKey scopes the client key to the workflow so the same key can be reused across workflows.
+func idempotencyCacheKey(workflowID, key string) string {
+	return workflowID + ":" + key
+}
+
+// payloadHash returns the hash of the execute payload stored along the key, so the reuse of a key for another
+// payload is detected.
+func payloadHash(payload ExecutePayload) (string, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reserve marks the key as in flight for the payload when it isn't known (or has expired) and returns reserved
+// true, the caller then has to complete or release it. Otherwise it returns the entry of the prior execution of the
+// key, in flight or done, or ErrIdempotencyKeyReused when that execution was for another payload.
+func (c *idempotencyCache) reserve(key, payloadHash string) (*idempotencyEntry, bool, error) {
+	if c == nil {
+		return nil, true, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if isDone(entry) && !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+
+	if entry, ok := c.entries[key]; ok {
+		if entry.payloadHash != payloadHash {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		return entry, false, nil
+	}
+
+	c.entries[key] = &idempotencyEntry{payloadHash: payloadHash, done: make(chan struct{})}
+	return nil, true, nil
+}
+
+// reserveIdempotencyKey reserves the key for the execution of the payload, see idempotencyCache.reserve. When the
+// key was already used for the payload, it returns the response of that execution instead of reserving it, waiting
+// for the execution while it's in flight (ErrIdempotencyKeyInFlight when the request ends first). The key is
+// reserved again when that execution didn't complete.
+func (s *Service) reserveIdempotencyKey(ctx context.Context, key string, payload ExecutePayload) ([]byte, bool, error) {
+	hash, err := payloadHash(payload)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for {
+		entry, reserved, err := s.idempotency.reserve(key, hash)
+		if err != nil || reserved {
+			return nil, reserved, err
+		}
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return nil, false, ErrIdempotencyKeyInFlight
+		}
+		if entry.body != nil {
+			return entry.body, false, nil
+		}
+	}
+}
+
+// complete stores the response of the reserved key for the window and wakes up the requests waiting for it.
+func (c *idempotencyCache) complete(key string, body []byte) {
+	if c == nil || key == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || isDone(entry) {
+		return
+	}
+	entry.body = body
+	entry.expiresAt = c.now().Add(c.window)
+	close(entry.done)
+}
+
+// release drops the reservation of a key whose execution didn't complete (e.g it failed or timed out) so it can be
+// retried, and wakes up the requests waiting for it. It's a no-op for a completed key.
+func (c *idempotencyCache) release(key string) {
+	if c == nil || key == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || isDone(entry) {
+		return
+	}
+	delete(c.entries, key)
+	close(entry.done)
+}
+
+// isDone reports whether the execution of the entry is over, completed or released.
+func isDone(entry *idempotencyEntry) bool {
+	select {
+	case <-entry.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newIdempotencyCache(time.Hour)
+	cache.now = func() time.Time { return now }
+
+	_, reserved, err := cache.reserve("wf-1:key", "hash-1")
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// a duplicate gets the in-flight entry, a reuse for another payload is rejected
+	entry, reserved, err := cache.reserve("wf-1:key", "hash-1")
+	require.NoError(t, err)
+	require.False(t, reserved)
+	require.False(t, isDone(entry))
+	_, _, err = cache.reserve("wf-1:key", "hash-2")
+	require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	cache.complete("wf-1:key", []byte(`{"status":"completed"}`))
+	require.True(t, isDone(entry))
+	entry, reserved, err = cache.reserve("wf-1:key", "hash-1")
+	require.NoError(t, err)
+	require.False(t, reserved)
+	require.JSONEq(t, `{"status":"completed"}`, string(entry.body))
+
+	// release is a no-op for a completed key
+	cache.release("wf-1:key")
+	_, reserved, _ = cache.reserve("wf-1:key", "hash-1")
+	require.False(t, reserved)
+
+	// the key is scoped to the workflow
+	_, reserved, _ = cache.reserve(idempotencyCacheKey("wf-2", "key"), "hash-1")
+	require.True(t, reserved)
+
+	// a released key can be reserved again
+	cache.release(idempotencyCacheKey("wf-2", "key"))
+	_, reserved, _ = cache.reserve(idempotencyCacheKey("wf-2", "key"), "hash-2")
+	require.True(t, reserved)
+	cache.release(idempotencyCacheKey("wf-2", "key"))
+
+	now = now.Add(time.Hour)
+	_, reserved, _ = cache.reserve("wf-1:key", "hash-2")
+	require.True(t, reserved)
+	require.Len(t, cache.entries, 1)
+}
+
+func TestHandleExecuteWorkflowIdempotency(t *testing.T) {
+	body := `{"formData":{"name":"Jane","email":"jane@example.com","city":"Sydney"}}`
+	var payload ExecutePayload
+	require.NoError(t, json.Unmarshal([]byte(body), &payload))
+	hash, err := payloadHash(payload)
+	require.NoError(t, err)
+
+	tests := []struct {
+		label        string
+		body         string
+		accept       string
+		inFlight     bool
+		completeWith string
+		wantStatus   int
+		wantBody     string
+		wantReplayed bool
+	}{
+		{
+			label:        "success: replays the completed execution",
+			body:         body,
+			completeWith: `{"status":"completed","steps":[]}`,
+			wantStatus:   http.StatusOK,
+			wantBody:     `{"status":"completed","steps":[]}`,
+			wantReplayed: true,
+		},
+		{
+			label:        "success: waits for the in-flight execution",
+			body:         body,
+			inFlight:     true,
+			completeWith: `{"status":"completed","steps":[]}`,
+			wantStatus:   http.StatusOK,
+			wantBody:     `{"status":"completed","steps":[]}`,
+			wantReplayed: true,
+		},
+		{
+			label:      "error: in-flight execution outlasting the request",
+			body:       body,
+			inFlight:   true,
+			wantStatus: http.StatusConflict,
+			wantBody:   errorToJSON(ErrIdempotencyKeyInFlight),
+		},
+		{
+			label:        "error: key reused with another payload",
+			body:         strings.Replace(body, "Sydney", "Perth", 1),
+			completeWith: `{"status":"completed","steps":[]}`,
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantBody:     errorToJSON(ErrIdempotencyKeyReused),
+		},
+		{
+			label:      "error: streamed execution",
+			body:       body,
+			accept:     ndjsonContentType,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   errorToJSON(ErrIdempotencyKeyStreamed),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			s := &Service{config: DefaultConfig(), idempotency: newIdempotencyCache(time.Hour)}
+			key := idempotencyCacheKey("wf-1", "retry-1")
+			_, _, err := s.idempotency.reserve(key, hash)
+			require.NoError(t, err)
+			if tt.completeWith != "" {
+				if tt.inFlight {
+					go func() {
+						time.Sleep(10 * time.Millisecond)
+						s.idempotency.complete(key, []byte(tt.completeWith))
+					}()
+				} else {
+					s.idempotency.complete(key, []byte(tt.completeWith))
+				}
+			}
+
+			router := mux.NewRouter()
+			s.LoadRoutes(router, false)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			r := httptest.NewRequest(http.MethodPost, "/workflows/wf-1/execute", strings.NewReader(tt.body)).WithContext(ctx)
+			r.Header.Set(idempotencyKeyHeader, "retry-1")
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.JSONEq(t, tt.wantBody, rec.Body.String())
+			if tt.wantReplayed {
+				require.Equal(t, "true", rec.Header().Get(idempotencyReplayedHeader))
+			}
+		})
+	}
+}
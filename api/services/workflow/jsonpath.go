@@ -0,0 +1,148 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// this file jsonpath.go contains the JSONPath subset used to extract values from http-request node responses.
+//
+// The supported grammar is:
+//
+//	path     = "$" { step }
+//	step     = "." key | "[" quoted "]" | "[" index "]"
+//	key      = one or more characters other than "." and "["
+//	quoted   = "'" characters other than "'" "'" | '"' characters other than '"' '"'
+//	index    = an integer, negative indexes count from the end of the array
+//
+// Wildcards (.* and [*]), recursive descent (..), slices ([0:2]), filters ([?(...)]), unions ([0,1]) and escapes
+// in quoted keys aren't supported, such paths are rejected with ErrInvalidJSONPath.
+
+// jsonPathStep is one step of a parsed JSONPath: an object key, or an array index when key is empty.
+type jsonPathStep struct {
+	key   string
+	index int
+}
+
+func (s jsonPathStep) String() string {
+	if s.key != "" {
+		return "." + s.key
+	}
+	return fmt.Sprintf("[%d]", s.index)
+}
+
+// parseJSONPath parses a JSONPath made of the root $ followed by dot keys (.data.price), bracketed keys
+// (['price'] or ["price"]) and array indexes ([0], negative indexes count from the end), see the grammar above.
+// An unsupported expression is reported with ErrInvalidJSONPath naming the feature.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("%w: %q must start with $", ErrInvalidJSONPath, path)
+	}
+
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			switch {
+			case strings.HasPrefix(rest, ".."):
+				return nil, fmt.Errorf("%w: %q uses recursive descent (..), which isn't supported", ErrInvalidJSONPath, path)
+			case key == "*":
+				return nil, fmt.Errorf("%w: %q uses a wildcard (.*), which isn't supported", ErrInvalidJSONPath, path)
+			case key == "":
+				return nil, fmt.Errorf("%w: %q has an empty key at %q", ErrInvalidJSONPath, path, rest)
+			}
+			steps = append(steps, jsonPathStep{key: key})
+			rest = rest[end+1:]
+		case '[':
+			// a quoted key may contain ] and ., it ends at its closing quote
+			if len(rest) > 1 && (rest[1] == '\'' || rest[1] == '"') {
+				end := strings.IndexByte(rest[2:], rest[1])
+				if end < 0 {
+					return nil, fmt.Errorf("%w: %q has an unclosed quote", ErrInvalidJSONPath, path)
+				}
+				key := rest[2 : end+2]
+				if !strings.HasPrefix(rest[end+3:], "]") {
+					return nil, fmt.Errorf("%w: %q has a quoted key [%s not followed by ]", ErrInvalidJSONPath, path, rest[:end+3])
+				}
+				if key == "" {
+					return nil, fmt.Errorf("%w: %q has an empty key", ErrInvalidJSONPath, path)
+				}
+				steps = append(steps, jsonPathStep{key: key})
+				rest = rest[end+4:]
+				continue
+			}
+
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %q has an unclosed bracket", ErrInvalidJSONPath, path)
+			}
+			inner := rest[1:end]
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q has an unsupported selector [%s]%s", ErrInvalidJSONPath, path, inner, unsupportedJSONPathFeature(inner))
+			}
+			steps = append(steps, jsonPathStep{index: index})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("%w: %q has an unexpected %q", ErrInvalidJSONPath, path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// unsupportedJSONPathFeature names the JSONPath feature of an unsupported bracket selector for the error message.
+func unsupportedJSONPathFeature(selector string) string {
+	switch {
+	case selector == "*":
+		return ", wildcards aren't supported"
+	case strings.HasPrefix(selector, "?"):
+		return ", filters aren't supported"
+	case strings.Contains(selector, ":"):
+		return ", slices aren't supported"
+	case strings.Contains(selector, ","):
+		return ", unions aren't supported"
+	}
+	return ""
+}
+
+// evaluateJSONPath returns the value at the path in the decoded JSON document (as decoded into an any).
+// It returns ErrJSONPathNotFound naming the part of the path that resolved when the path doesn't resolve.
+func evaluateJSONPath(document any, path string) (any, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := document
+	resolved := "$"
+	for _, step := range steps {
+		switch current := value.(type) {
+		case map[string]any:
+			next, ok := current[step.key]
+			if step.key == "" || !ok {
+				return nil, fmt.Errorf("%w: %s has no %s", ErrJSONPathNotFound, resolved, step)
+			}
+			value = next
+		case []any:
+			index := step.index
+			if index < 0 {
+				index += len(current)
+			}
+			if step.key != "" || index < 0 || index >= len(current) {
+				return nil, fmt.Errorf("%w: %s has no %s", ErrJSONPathNotFound, resolved, step)
+			}
+			value = current[index]
+		default:
+			return nil, fmt.Errorf("%w: %s is not an object or array", ErrJSONPathNotFound, resolved)
+		}
+		resolved += step.String()
+	}
+	return value, nil
+}
@@ -0,0 +1,71 @@
+package workflow
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateJSONPath(t *testing.T) {
+	var document any
+	require.NoError(t, json.Unmarshal([]byte(`{"data":{"price":42.5,"items":[{"name":"a"},{"name":"b"}],"odd key":true,"a]b.c":"x"}}`), &document))
+
+	tests := []struct {
+		label       string
+		path        string
+		want        any
+		errExpected error
+		errMessage  string
+	}{
+		{label: "root", path: "$", want: document},
+		{label: "dot keys", path: "$.data.price", want: 42.5},
+		{label: "array index", path: "$.data.items[1].name", want: "b"},
+		{label: "negative index", path: "$.data.items[-2].name", want: "a"},
+		{label: "bracketed key", path: `$.data['odd key']`, want: true},
+		{label: "double quoted key", path: `$["data"]["price"]`, want: 42.5},
+		{label: "quoted key with a bracket and a dot", path: `$.data['a]b.c']`, want: "x"},
+		{
+			label:       "error: missing key",
+			path:        "$.data.items[0].price",
+			errExpected: ErrJSONPathNotFound,
+			errMessage:  "JSONPath does not resolve in the response: $.data.items[0] has no .price",
+		},
+		{label: "error: index out of range", path: "$.data.items[2]", errExpected: ErrJSONPathNotFound},
+		{label: "error: key of a value", path: "$.data.price.amount", errExpected: ErrJSONPathNotFound},
+		{label: "error: no root", path: "data.price", errExpected: ErrInvalidJSONPath},
+		{
+			label:       "error: wildcard",
+			path:        "$.data.items[*]",
+			errExpected: ErrInvalidJSONPath,
+			errMessage:  `invalid JSONPath: "$.data.items[*]" has an unsupported selector [*], wildcards aren't supported`,
+		},
+		{label: "error: dot wildcard", path: "$.data.*", errExpected: ErrInvalidJSONPath},
+		{
+			label:       "error: recursive descent",
+			path:        "$..price",
+			errExpected: ErrInvalidJSONPath,
+			errMessage:  `invalid JSONPath: "$..price" uses recursive descent (..), which isn't supported`,
+		},
+		{label: "error: slice", path: "$.data.items[0:1]", errExpected: ErrInvalidJSONPath},
+		{label: "error: filter", path: "$.data.items[?(@.name=='a')]", errExpected: ErrInvalidJSONPath},
+		{label: "error: unclosed bracket", path: "$.data.items[0", errExpected: ErrInvalidJSONPath},
+		{label: "error: unclosed quote", path: "$.data['price]", errExpected: ErrInvalidJSONPath},
+		{label: "error: quoted key without bracket", path: "$.data['price'", errExpected: ErrInvalidJSONPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := evaluateJSONPath(document, tt.path)
+			if tt.errExpected != nil {
+				require.ErrorIs(t, err, tt.errExpected)
+				if tt.errMessage != "" {
+					require.EqualError(t, err, tt.errMessage)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package workflow
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// this file logging.go contains the request scoped logger carrying the request (correlation) ID.
+
+const (
+	requestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds the client provided request ID written to the logs.
+	maxRequestIDLength = 128
+)
+
+type loggerContextKey struct{}
+
+// contextWithLogger returns a copy of ctx carrying the logger.
+func contextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// loggerFromContext returns the logger attached to ctx, or the default logger when there's none
+// (e.g a node processor called directly from a test).
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// requestIDMiddleware reads the request ID from the X-Request-ID header, or generates one, returns it in the
+// response headers and attaches a logger carrying it to the request context so every log line of the request
+// (handlers and workflow execution) can be correlated.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !isValidRequestID(requestID) {
+			// the ID format is the same as the workflow ids
+			id, err := newWorkflowID()
+			if err != nil {
+				slog.Error("Failed to generate request id", "error", err)
+			}
+			requestID = id
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		logger := slog.Default().With("request id", requestID)
+		next.ServeHTTP(w, r.WithContext(contextWithLogger(r.Context(), logger)))
+	})
+}
+
+// isValidRequestID reports whether a client provided request ID can be used as is. It must be non-empty,
+// reasonably short and printable ASCII so it can't forge log lines.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
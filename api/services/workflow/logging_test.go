@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loggerFromContext(r.Context()).Info("handled")
+	}))
+
+	tests := []struct {
+		label     string
+		requestID string
+		wantID    string
+	}{
+		{label: "client request id is reused", requestID: "abc-123", wantID: "abc-123"},
+		{label: "missing request id is generated"},
+		{label: "request id with spaces is replaced", requestID: "abc 123"},
+		{label: "request id too long is replaced", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			logs.Reset()
+			r := httptest.NewRequest(http.MethodGet, "/workflows/1", nil)
+			if tt.requestID != "" {
+				r.Header.Set(requestIDHeader, tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			got := rec.Header().Get(requestIDHeader)
+			if tt.wantID != "" {
+				require.Equal(t, tt.wantID, got)
+			} else {
+				require.Len(t, got, 36)
+			}
+			require.Contains(t, logs.String(), `"request id"=`+got)
+		})
+	}
+}
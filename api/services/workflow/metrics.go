@@ -0,0 +1,57 @@
+package workflow
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// this file metrics.go contains the Prometheus metrics of the workflow executions.
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	executionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "workflow",
+		Name:      "executions_total",
+		Help:      "Workflow executions by final status.",
+	}, []string{"status"})
+
+	nodeDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "workflow",
+		Name:      "node_duration_seconds",
+		Help:      "Duration of the node executions by node type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"node_type"})
+
+	nodeFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "workflow",
+		Name:      "node_failures_total",
+		Help:      "Failed node executions by node type.",
+	}, []string{"node_type"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(executionsTotal, nodeDurationSeconds, nodeFailuresTotal)
+}
+
+// MetricsHandler serves the workflow execution metrics in the Prometheus exposition format, e.g to be mounted
+// at /metrics. The metrics are shared by every execution of the process.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// observeNode records the duration of a processed node, and its failure.
+func observeNode(nodeType string, duration time.Duration, failed bool) {
+	nodeDurationSeconds.WithLabelValues(nodeType).Observe(duration.Seconds())
+	if failed {
+		nodeFailuresTotal.WithLabelValues(nodeType).Inc()
+	}
+}
+
+// observeExecution records a finished execution.
+func observeExecution(status string) {
+	executionsTotal.WithLabelValues(status).Inc()
+}
@@ -0,0 +1,44 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionMetrics(t *testing.T) {
+	RegisterNodeProcessor("flaky", NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+		return nil, errors.New("flaky")
+	}))
+	defer UnregisterNodeProcessor("flaky")
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: "flaky-1", Type: "flaky"}, {ID: EndNodeID}},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: "flaky-1"},
+			{Source: "flaky-1", Target: EndNodeID},
+		},
+	}
+
+	failedBefore := testutil.ToFloat64(executionsTotal.WithLabelValues(StatusFailed))
+	nodeFailuresBefore := testutil.ToFloat64(nodeFailuresTotal.WithLabelValues("flaky"))
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, got.Status)
+
+	require.Equal(t, failedBefore+1, testutil.ToFloat64(executionsTotal.WithLabelValues(StatusFailed)))
+	require.Equal(t, nodeFailuresBefore+1, testutil.ToFloat64(nodeFailuresTotal.WithLabelValues("flaky")))
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `workflow_executions_total{status="failed"}`)
+	require.Contains(t, rec.Body.String(), `workflow_node_duration_seconds_count{node_type="flaky"}`)
+	require.Contains(t, rec.Body.String(), `workflow_node_failures_total{node_type="flaky"}`)
+}
@@ -5,8 +5,12 @@ package workflow
 // worflow definition holds the id and nodes + edges
 type WorkflowDefinition struct {
 	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
 	Nodes []Node `json:"nodes"`
 	Edges []Edge `json:"edges"`
+	// EntryNodeID is the node the execution starts from when the payload doesn't choose one, e.g for a sub-flow.
+	// Defaults to StartNodeID.
+	EntryNodeID string `json:"entryNodeId,omitempty"`
 }
 
 type Node struct {
@@ -28,14 +32,35 @@ type NodeData struct {
 }
 
 type NodeMetadata struct {
-	HasHandles      HasHandles        `json:"hasHandles"`
-	InputFields     []string          `json:"inputFields,omitempty"`
-	OutputVariables []string          `json:"outputVariables,omitempty"`
-	InputVariables  []string          `json:"inputVariables,omitempty"`
-	EmailTemplate   *EmailTemplate    `json:"emailTemplate,omitempty"`
-	APIEndpoint     string            `json:"apiEndpoint,omitempty"`
-	Options         []CityCoordinates `json:"options,omitempty"`
-	ConditionExpr   string            `json:"conditionExpression,omitempty"`
+	HasHandles          HasHandles        `json:"hasHandles"`
+	InputFields         []string          `json:"inputFields,omitempty"`
+	OutputVariables     []string          `json:"outputVariables,omitempty"`
+	InputVariables      []string          `json:"inputVariables,omitempty"`
+	EmailTemplate       *EmailTemplate    `json:"emailTemplate,omitempty"`
+	VerifyMX            bool              `json:"verifyMx,omitempty"` // check the recipient domain has MX records before sending
+	Retry               *RetryPolicy      `json:"retry,omitempty"`    // email node retry on failure (single attempt by default), weather node retry on 429 (single retry by default)
+	SMSTemplate         *SMSTemplate      `json:"smsTemplate,omitempty"`
+	SlackTemplate       *SlackTemplate    `json:"slackTemplate,omitempty"`
+	HTTPRequest         *HTTPRequest      `json:"httpRequest,omitempty"`
+	Archive             *ArchiveConfig    `json:"archive,omitempty"`
+	History             *HistoryConfig    `json:"history,omitempty"`
+	MissingPlaceholders string            `json:"missingPlaceholders,omitempty"` // "keep" (default) or "empty" for template placeholders without a value
+	APIEndpoint         string            `json:"apiEndpoint,omitempty"`
+	APIHeaders          map[string]string `json:"apiHeaders,omitempty"` // headers sent to the apiEndpoint (e.g Authorization), redacted in the step output
+	Provider            string            `json:"provider,omitempty"`   // weather provider, "open-meteo" (default) or "openweathermap"
+	Options             []CityCoordinates `json:"options,omitempty"`
+	Metrics             []string          `json:"metrics,omitempty"`       // weather metrics to record, defaults to temperature
+	MaxAgeMinutes       int               `json:"maxAgeMinutes,omitempty"` // weather readings older than this are stale, see weatherStaleness
+	Geocoding           *GeocodingConfig  `json:"geocoding,omitempty"`
+	ConditionExpr       string            `json:"conditionExpression,omitempty"`
+	Field               string            `json:"field,omitempty"` // contextData key evaluated by the condition node
+	Conditions          []ConditionClause `json:"conditions,omitempty"`
+	TimeoutMs           int               `json:"timeoutMs,omitempty"`       // per-node deadline, nodes without it inherit the execution deadline
+	ValueSource         string            `json:"valueSource,omitempty"`     // "context" (default) or "payload" to compare condition.value from the execute payload
+	Expression          string            `json:"expression,omitempty"`      // transform node expression, e.g "feels_like = temperature - windspeed * 0.5"
+	OutputKey           string            `json:"outputKey,omitempty"`       // contextData key the transform result is stored under
+	MessageTemplate     string            `json:"messageTemplate,omitempty"` // condition message, e.g "{{label}} {{value}}{{unit}} is {{operator}} {{threshold}}{{unit}}"
+	Unit                string            `json:"unit,omitempty"`            // unit shown in the condition message, defaults to the weather metric unit
 }
 
 type HasHandles struct {
@@ -44,14 +69,84 @@ type HasHandles struct {
 }
 
 type EmailTemplate struct {
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
+	Subject     string `json:"subject"`
+	Body        string `json:"body"`
+	FromAddress string `json:"fromAddress,omitempty"` // defaults to the service's default from address
+}
+
+// RetryPolicy configures how many times a failing node is attempted.
+type RetryPolicy struct {
+	MaxAttempts int `json:"maxAttempts"`         // total attempts including the first one
+	BackoffMs   int `json:"backoffMs,omitempty"` // delay before a retry, multiplied by the number of attempts so far
+}
+
+// SMSTemplate configures the sms node. The message supports the same placeholders as the email template.
+type SMSTemplate struct {
+	Message     string `json:"message"`
+	PhoneNumber string `json:"phoneNumber,omitempty"` // defaults to the phone number in the form data
+}
+
+// SlackTemplate configures the slack node. The message supports the same placeholders as the email template.
+type SlackTemplate struct {
+	WebhookURL string `json:"webhookUrl"` // Slack incoming-webhook URL, never included in the step output
+	Message    string `json:"message,omitempty"`
+}
+
+// ArchiveConfig configures the archive node.
+type ArchiveConfig struct {
+	Destination string `json:"destination"`       // local file path (or file:// URL), or http(s) URL the document is PUT to
+	Content     string `json:"content,omitempty"` // "result" (default) for the steps so far or "context" for contextData
+}
+
+// GeocodingConfig disambiguates the city names matching several places (e.g Springfield) for the weather node.
+// The form data country and region take precedence over the ones configured here.
+type GeocodingConfig struct {
+	Country string `json:"country,omitempty"` // country name or ISO code, e.g "US"
+	Region  string `json:"region,omitempty"`  // first-level administrative area, e.g "Illinois"
+	Count   int    `json:"count,omitempty"`   // geocoding results to choose from, defaults to 10 with a hint and 1 without
+	// Strict fails with ErrAmbiguousCity when several places match the hint, instead of taking the best ranked one.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// HistoryConfig configures the values the history node loads from the previous execution.
+type HistoryConfig struct {
+	// Values maps the context keys to set to the keys of the previous execution context,
+	// e.g {"previousTemperature": "weather.temperature"}
+	Values map[string]string `json:"values"`
+}
+
+// HTTPRequest configures the outbound call made by the http-request node.
+// The url, header values and body support the same placeholders as the email template (e.g {{city}}).
+type HTTPRequest struct {
+	Method  string            `json:"method,omitempty"` // defaults to GET
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// Extract maps contextData keys to JSONPaths in the JSON response, e.g {"price": "$.data.price"}. Only the
+	// subset of JSONPath described in jsonpath.go is supported, other paths fail the workflow validation.
+	Extract map[string]string `json:"extract,omitempty"`
+}
+
+// ConditionClause is one branch of a multi-way condition node.
+// Clauses are evaluated in order and the node routes to the edge with the first matching clause's source handle.
+type ConditionClause struct {
+	Field          string   `json:"field,omitempty"` // defaults to the node's condition field
+	Operator       string   `json:"operator"`
+	Threshold      float64  `json:"threshold"`
+	UpperThreshold float64  `json:"upperThreshold,omitempty"` // upper bound of the between operator
+	Tolerance      *float64 `json:"tolerance,omitempty"`      // max difference still matching the equals operator
+	SourceHandle   string   `json:"sourceHandle"`
 }
 
 type CityCoordinates struct {
-	City string  `json:"city"`
-	Lat  float64 `json:"lat"`
-	Lon  float64 `json:"lon"`
+	City string   `json:"city"`
+	Lat  *float64 `json:"lat,omitempty"`
+	Lon  *float64 `json:"lon,omitempty"`
+}
+
+// hasCoordinates reports whether the option sets its coordinates, a lat or lon of 0 (e.g on the equator) is set.
+func (c CityCoordinates) hasCoordinates() bool {
+	return c.Lat != nil || c.Lon != nil
 }
 
 type Edge struct {
@@ -1,10 +1,22 @@
 package workflow
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log/slog"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -14,9 +26,148 @@ type ExecutionResult struct {
 	ExecutedAt string       `json:"executedAt"`
 	Status     string       `json:"status"`
 	Steps      []StepResult `json:"steps"`
+	Warnings   []string     `json:"warnings,omitempty"`
+	DurationMs int64        `json:"durationMs"` // wall-clock time of the whole execution
+	// Error describes the first failed step, the status is failed whenever a step failed.
+	Error *ExecutionError `json:"error,omitempty"`
+	// Context is the final contextData (e.g weather.temperature), only included when requested as it can be large.
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// ExecutionError describes the step that failed an execution.
+type ExecutionError struct {
+	NodeID  string `json:"nodeId"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	err error // the node error
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("node %s failed: %s", e.NodeID, e.Message)
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.err
+}
+
+// ExecutionOptions configures how a workflow is executed.
+type ExecutionOptions struct {
+	// DryRun walks the graph without calling external services; side-effecting nodes are recorded as skipped.
+	DryRun bool
+	// DefaultEmailFrom is the sender address for email nodes without a configured from address.
+	DefaultEmailFrom string
+	// WeatherAPI holds the base URLs used by the weather nodes.
+	WeatherAPI WeatherAPIConfig
+	// HTTPClient makes the outbound node requests, defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// DefaultCity is used by the form and weather nodes (and the {{city}} placeholder) when the payload has no city.
+	DefaultCity string
+	// MaxSteps caps the number of steps recorded in an execution, defaults to defaultMaxSteps.
+	MaxSteps int
+	// Strict fails the execution up front when some nodes can't be reached from the start node,
+	// otherwise they are reported in the result warnings. It also stops the execution at a node of
+	// an unknown type instead of recording it as skipped, and fails weather nodes with a stale reading
+	// (see NodeMetadata.MaxAgeMinutes) instead of warning in their output.
+	Strict bool
+	// IncludeContext adds the final contextData to the result for debugging.
+	IncludeContext bool
+	// SortSteps orders the result steps by the topological order of the graph instead of the order they were
+	// recorded in, so the output is stable whatever the completion order. Each step keeps its execution Index.
+	SortSteps bool
+	// FailurePolicy chooses what happens after a node fails (FailurePolicyStopOnError or FailurePolicyContinue).
+	// By default the failed node's path stops but the other branches are still executed.
+	FailurePolicy string
+	// OnStep, when set, is called with every step as soon as it is recorded (e.g to stream progress).
+	OnStep func(step StepResult)
+	// OnNodeStart, when set, is called right before a node is processed (e.g to emit metrics or progress events).
+	OnNodeStart func(node Node)
+	// OnNodeComplete, when set, is called with the node and its completed or failed step right after it's processed.
+	OnNodeComplete func(node Node, step StepResult)
+	// ArchiveWriter writes the documents of the archive nodes, defaults to writing local files or PUTting to
+	// http(s) URLs (e.g a presigned S3 URL).
+	ArchiveWriter ArchiveWriter
+	// EmailSender sends the emails of the email nodes, defaults to a sender only logging them.
+	EmailSender EmailSender
+	// LastExecution, when set, returns the most recent stored execution of the workflow for the history nodes,
+	// nil when there is none. Without it, history nodes find no previous execution.
+	LastExecution func(ctx context.Context, workflowID string) (*ExecutionRecord, error)
+	// OnExecutionComplete, when set, is called with the result and the final contextData once the execution
+	// finished (e.g to store it for the history nodes of the next executions).
+	OnExecutionComplete func(ctx context.Context, result *ExecutionResult, contextData map[string]any)
+	// OnDeadLetter, when set, is called with the email an email node still failed to send after every retry,
+	// so it can be stored instead of being lost.
+	OnDeadLetter func(ctx context.Context, letter EmailDeadLetter) error
+}
+
+const (
+	// FailurePolicyStopOnError aborts the whole execution at the first failed node.
+	FailurePolicyStopOnError = "stopOnError"
+	// FailurePolicyContinue skips the failed node and keeps traversing its children.
+	// A failed condition node still stops its path since no branch was chosen.
+	FailurePolicyContinue = "continue"
+)
+
+// supportedFailurePolicies are the accepted ExecutionOptions.FailurePolicy values, the empty value being the default.
+var supportedFailurePolicies = map[string]bool{
+	"":                       true,
+	FailurePolicyStopOnError: true,
+	FailurePolicyContinue:    true,
+}
+
+// errExecutionStopped stops the traversal after a failed node with the stopOnError policy.
+// The failure itself is already reported by the failed step and the execution error.
+var errExecutionStopped = errors.New("execution stopped on error")
+
+type httpClientContextKey struct{}
+
+// contextWithHTTPClient returns a copy of ctx carrying the client used by the outbound node requests.
+func contextWithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientContextKey{}, client)
+}
+
+// httpClientFromContext returns the client attached to ctx, or http.DefaultClient when there's none
+// (e.g a node processor called directly from a test).
+func httpClientFromContext(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientContextKey{}).(*http.Client); ok && client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
+
+// EmailDeadLetter is an email that couldn't be sent after every retry.
+type EmailDeadLetter struct {
+	WorkflowID string
+	NodeID     string
+	Recipient  string
+	Subject    string
+	Body       string
+	Attempts   int
+	LastError  string
+}
+
+// WeatherAPIConfig holds the base URLs of the geocoding and weather APIs (e.g to point at a mock or self-hosted Open-Meteo).
+type WeatherAPIConfig struct {
+	// GeocodingBaseURL replaces the default Open-Meteo geocoding API when set.
+	GeocodingBaseURL string
+	// WeatherBaseURL replaces the scheme and host of the node apiEndpoint when set.
+	WeatherBaseURL string
+	// OpenWeatherMapAPIKey is required by weather nodes using the openweathermap provider.
+	OpenWeatherMapAPIKey string
+	// OpenWeatherMapBaseURL replaces the default OpenWeatherMap API when set.
+	OpenWeatherMapBaseURL string
+	// RateLimit caps the weather provider calls (geocoding and weather) per second across every execution
+	// of the service. 0 disables the limit.
+	RateLimit float64
+	// RateBurst is how many calls can be made at once before RateLimit applies, defaults to 1.
+	RateBurst int
+
+	// limiter enforces RateLimit. It's created by NewService so every execution shares it.
+	limiter *tokenBucket
 }
 
 type StepResult struct {
+	Index       int                    `json:"index"` // position of the step in the execution order
 	NodeID      string                 `json:"nodeId"`
 	Type        string                 `json:"type"`
 	Label       string                 `json:"label"`
@@ -27,31 +178,141 @@ type StepResult struct {
 
 const (
 	// valid node IDs (types)
-	StartNodeID      = "start"
-	EndNodeID        = "end"
-	FormNodeID       = "form"
-	WeatherAPINodeID = "weather-api"
-	ConditionNodeID  = "condition"
-	EmailNodeID      = "email"
+	StartNodeID       = "start"
+	EndNodeID         = "end"
+	FormNodeID        = "form"
+	WeatherAPINodeID  = "weather-api"
+	ConditionNodeID   = "condition"
+	EmailNodeID       = "email"
+	HTTPRequestNodeID = "http-request"
+	SMSNodeID         = "sms"
+	SlackNodeID       = "slack"
+	LoopNodeID        = "loop"
+	TransformNodeID   = "transform"
+	MetricsNodeID     = "metrics"
+	ArchiveNodeID     = "archive"
+	HistoryNodeID     = "history"
 
 	// node status
 	StatusCompleted = "completed"
 	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
 
 	ConditionMetString    = "condition met"
 	ConditionNotMetString = "condition not met"
+
+	// source handles of the binary condition node edges
+	ConditionMetHandle    = "true"
+	ConditionNotMetHandle = "false"
+	// ConditionDefaultHandle is the source handle of the fallback edge, taken when no other edge matches the outcome
+	ConditionDefaultHandle = "default"
+
+	// legacy edge labels used to route the binary condition node when edges have no source handle
+	conditionMetLabel    = "✓ Condition Met"
+	conditionNotMetLabel = "✗ No Alert Needed"
+
+	// supported condition operators
+	OperatorGreaterThan        = "greater_than"
+	OperatorLessThan           = "less_than"
+	OperatorEquals             = "equals"
+	OperatorGreaterThanOrEqual = "greater_than_or_equal"
+	OperatorLessThanOrEqual    = "less_than_or_equal"
+	OperatorBetween            = "between" // inclusive range from threshold to upperThreshold
+
+	// defaultEqualsTolerance is the absolute difference under which the equals operator treats two values as equal
+	defaultEqualsTolerance = 1e-6
+
+	// supported weather metrics (stored in contextData as "weather.<metric>")
+	WeatherMetricTemperature   = "temperature"
+	WeatherMetricWindSpeed     = "windspeed"
+	WeatherMetricWindDirection = "winddirection"
+	WeatherMetricWeatherCode   = "weathercode"
+	WeatherMetricHumidity      = "humidity"
+
+	// sources of the value compared by the condition node
+	ConditionValueSourceContext = "context"
+	ConditionValueSourcePayload = "payload"
 )
 
+// supportedOperators is the set of operators the condition node can evaluate.
+var supportedOperators = map[string]bool{
+	OperatorGreaterThan:        true,
+	OperatorLessThan:           true,
+	OperatorEquals:             true,
+	OperatorGreaterThanOrEqual: true,
+	OperatorLessThanOrEqual:    true,
+	OperatorBetween:            true,
+}
+
+// sideEffectNodes are the nodes that call external services and are skipped in a dry run.
+var sideEffectNodes = map[string]bool{
+	WeatherAPINodeID:  true,
+	EmailNodeID:       true,
+	HTTPRequestNodeID: true,
+	SMSNodeID:         true,
+	SlackNodeID:       true,
+	LoopNodeID:        true,
+	ArchiveNodeID:     true,
+}
+
 // this is done so that it can be overridden to return mock data in unit tests.
 var processWeatherNodeFn = processWeatherNode
 var processEmailNodeFn = processEmailNode
+var processHTTPRequestNodeFn = processHTTPRequestNode
+var processSMSNodeFn = processSMSNode
+var processSlackNodeFn = processSlackNode
+var processLoopNodeFn = processLoopNode
+var processArchiveNodeFn = processArchiveNode
+var processHistoryNodeFn = processHistoryNode
+
+// defaultMaxSteps is the step limit applied when ExecutionOptions doesn't set one.
+const defaultMaxSteps = 1000
+
+// ExecuteWorkflow validates the payload and executes the workflow with the default execution options.
+// It doesn't need the HTTP server or the database, so workflows can be run from other programs (e.g a CLI
+// or a batch job). Invalid payloads are rejected with ValidationErrors before any node is processed.
+func ExecuteWorkflow(ctx context.Context, wf *WorkflowDefinition, payload *ExecutePayload) (*ExecutionResult, error) {
+	return ExecuteWorkflowWithOptions(ctx, wf, payload, ExecutionOptions{})
+}
+
+// ExecuteWorkflowWithOptions is ExecuteWorkflow with the given execution options.
+func ExecuteWorkflowWithOptions(ctx context.Context, wf *WorkflowDefinition, payload *ExecutePayload, opts ExecutionOptions) (*ExecutionResult, error) {
+	if payload == nil {
+		payload = &ExecutePayload{}
+	}
+	if err := payload.withDefaultCity(opts.DefaultCity).ValidateFor(wf); err != nil {
+		return nil, err
+	}
+
+	return processNodes(ctx, wf, payload, opts)
+}
 
 // processNodes processes each node in sequence from the workflow.
-func processNodes(wf *WorkflowDefinition, payload *ExecutePayload) (*ExecutionResult, error) {
-	// record the each node execution in steps
-	steps := []StepResult{}
+// Each node is handled by the NodeProcessor registered for its type (see RegisterNodeProcessor).
+// The execution is aborted when ctx is cancelled or its deadline is exceeded.
+func processNodes(ctx context.Context, wf *WorkflowDefinition, payload *ExecutePayload, opts ExecutionOptions) (*ExecutionResult, error) {
+	executionStart := time.Now()
+	// the request scoped logger carries the request id
+	logger := loggerFromContext(ctx)
+
 	// this stores node outputs (e.g temperature from the weather check node)
-	contextData := make(map[string]any)
+	contextData := NewContext()
+	// a blank city falls back to the configured default city
+	payload = payload.withDefaultCity(opts.DefaultCity)
+
+	// validate the workflow graph structure (start/end nodes, dangling edges, cycles) before executing anything
+	if err := ValidateWorkflow(wf); err != nil {
+		return nil, err
+	}
+
+	// disconnected graph fragments are most likely a mistake in the definition
+	var warnings []string
+	if unreachable := unreachableNodes(wf); len(unreachable) > 0 {
+		if opts.Strict {
+			return nil, fmt.Errorf("%w: %s", ErrUnreachableNodes, strings.Join(unreachable, ", "))
+		}
+		warnings = append(warnings, fmt.Sprintf("%s: %s", ErrUnreachableNodes.Error(), strings.Join(unreachable, ", ")))
+	}
 
 	// store each node in a map
 	nodeMap := make(map[string]Node)
@@ -59,12 +320,10 @@ func processNodes(wf *WorkflowDefinition, payload *ExecutePayload) (*ExecutionRe
 		nodeMap[node.ID] = node
 	}
 
-	// validate that the workflow graph contains start and end nodes
-	if _, ok := nodeMap[StartNodeID]; !ok {
-		return nil, ErrMissingStartNode
-	}
-	if _, ok := nodeMap[EndNodeID]; !ok {
-		return nil, ErrMissingEndNode
+	// the traversal starts from the entry node chosen by the payload or the definition
+	entryID := entryNodeID(wf, payload)
+	if _, ok := nodeMap[entryID]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNodeNotFound, entryID)
 	}
 
 	// build adjacency map (sourceID > list of targetIDs) to store node connections.
@@ -73,365 +332,1562 @@ func processNodes(wf *WorkflowDefinition, payload *ExecutePayload) (*ExecutionRe
 		adj[edge.Source] = append(adj[edge.Source], edge.Target)
 	}
 
+	// processors maps each node type to the processor handling it
+	processors := nodeProcessors(opts)
+
+	// record the each node execution in steps, assembled by the collector
+	steps := newStepCollector()
+	// stats exposes the execution progress to the metrics node
+	stats := &executionStats{startedAt: executionStart, workflowID: wf.ID, steps: steps}
+	ctx = contextWithExecutionStats(ctx, stats)
+	// the node processors make their outbound requests with the configured client
+	if opts.HTTPClient != nil {
+		ctx = contextWithHTTPClient(ctx, opts.HTTPClient)
+	}
+
+	// failStep records a failed step, the first failure is reported as the execution error
+	var executionErr *ExecutionError
+	var recordStep func(node Node, status string, startTime, finishTime time.Time, output map[string]interface{})
+	failStep := func(node Node, err error, startTime, finishTime time.Time, output map[string]interface{}) {
+		if executionErr == nil {
+			executionErr = &ExecutionError{NodeID: node.ID, Code: errorCode(err), Message: err.Error(), err: err}
+		}
+		output["error"] = err.Error()
+		recordStep(node, StatusFailed, startTime, finishTime, output)
+	}
+
+	// recordStep sends the step to the collector and notifies the OnStep callback
+	var lastStep StepResult
+	recordStep = func(node Node, status string, startTime, finishTime time.Time, output map[string]interface{}) {
+		lastStep = steps.record(newStepResult(node, status, startTime, finishTime, output))
+		if opts.OnStep != nil {
+			opts.OnStep(lastStep)
+		}
+	}
+
+	// nodeComplete notifies the OnNodeComplete hook with the step just recorded for the processed node
+	nodeComplete := func(node Node) {
+		if opts.OnNodeComplete != nil {
+			opts.OnNodeComplete(node, lastStep)
+		}
+	}
+
+	// maxSteps guards against pathological definitions producing an enormous step list
+	maxSteps := opts.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+
 	// visited map keeps track of the nodes that have been visited in this traversal
 	visited := make(map[string]bool)
 
-	// traverse the graph from the input node id using DFS (Depth First Search) algorithm.
-	// the time complexity of DFS is O(V+E) vertices + edges
-	var traverse func(id string) error
-	traverse = func(id string) error {
-		if visited[id] {
-			return nil
+	// visit processes the node and returns the ids of the next nodes to traverse, in edge order.
+	visit := func(id string) ([]string, error) {
+		if stats.counts().recorded >= maxSteps {
+			return nil, fmt.Errorf("%w: limit is %d", ErrTooManySteps, maxSteps)
 		}
-		visited[id] = true
 
 		// get current node by id
 		node, ok := nodeMap[id]
 		if !ok {
-			return fmt.Errorf("node %s not found in nodeMap", id)
+			return nil, fmt.Errorf("node %s not found in nodeMap", id)
+		}
+
+		// stop before processing the next node if the execution has been aborted
+		if ctx.Err() != nil {
+			abortErr := executionAbortedError(ctx)
+			now := time.Now()
+			failStep(node, abortErr, now, now, map[string]interface{}{})
+			return nil, abortErr
+		}
+
+		// look up the processor for the node type. nodes without one are recorded as skipped and passed through,
+		// or fail and stop the execution in strict mode
+		nodeType, ok := resolveNodeType(node, processors)
+		if !ok {
+			unknownErr := fmt.Errorf("%w: node %s has type %q", ErrUnknownNodeType, node.ID, node.Type)
+			now := time.Now()
+			if opts.Strict {
+				failStep(node, unknownErr, now, now, map[string]interface{}{})
+				return nil, errExecutionStopped
+			}
+			logger.Warn("Skipping node without a processor", "node id", node.ID, "node type", node.Type)
+			recordStep(node, StatusSkipped, now, now, map[string]interface{}{
+				"reason": unknownErr.Error(),
+			})
+			return adj[id], nil
 		}
 
-		// process the node depending on the node type (node id)
-		switch node.ID {
-		case StartNodeID:
-			// keep track of node processing time
-			startTime := time.Now()
-			err := processStartNode(node)
-			duration := time.Since(startTime).Milliseconds()
+		// in a dry run, side-effecting nodes are skipped but their children are still traversed
+		if opts.DryRun && sideEffectNodes[nodeType] {
+			now := time.Now()
+			recordStep(node, StatusSkipped, now, now, map[string]interface{}{
+				"reason": "dry run",
+			})
+			return adj[id], nil
+		}
 
-			// if there's an error with the node processing, we want to append it to the steps as a failed step and stop there.
-			if err != nil {
-				appendStep(&steps, node, StatusFailed, map[string]interface{}{
-					"error":    err.Error(),
-					"duration": duration,
-				})
-				return nil
+		// in a dry run the condition value may come from a skipped node, so every branch is explored instead
+		if opts.DryRun && nodeType == ConditionNodeID && !conditionInputsAvailable(node, conditionData(node, payload, contextData)) {
+			now := time.Now()
+			recordStep(node, StatusSkipped, now, now, map[string]interface{}{
+				"reason": "dry run: condition input not available, exploring every branch",
+			})
+			return adj[id], nil
+		}
+
+		// keep track of node processing time
+		// a node can override the execution deadline with its own (shorter) timeout
+		nodeCtx, cancelNode := ctx, context.CancelFunc(func() {})
+		if timeoutMs := node.Data.Metadata.TimeoutMs; timeoutMs > 0 {
+			nodeCtx, cancelNode = context.WithTimeout(ctx, time.Duration(timeoutMs)*time.Millisecond)
+		}
+
+		logger.Debug("Processing node", "node id", node.ID, "node type", nodeType)
+		if opts.OnNodeStart != nil {
+			opts.OnNodeStart(node)
+		}
+		startTime := time.Now()
+		output, err := runProcessor(nodeCtx, processors[nodeType], node, payload, contextData)
+		finishTime := time.Now()
+		if err != nil && ctx.Err() == nil && errors.Is(nodeCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %dms: %v", ErrNodeTimeout, node.Data.Metadata.TimeoutMs, err)
+		}
+		cancelNode()
+		observeNode(nodeType, finishTime.Sub(startTime), err != nil)
+		duration := finishTime.Sub(startTime).Milliseconds()
+
+		if output == nil {
+			output = make(map[string]interface{})
+		}
+		output["duration"] = duration
+
+		// if there's an error with the node processing, we want to append it to the steps as a failed step.
+		// the failure policy decides whether to stop there, abort the whole execution or carry on.
+		if err != nil {
+			logger.Debug("Node failed", "node id", node.ID, "error", err)
+			failStep(node, err, startTime, finishTime, output)
+			nodeComplete(node)
+			switch {
+			case opts.FailurePolicy == FailurePolicyStopOnError:
+				return nil, errExecutionStopped
+			case opts.FailurePolicy == FailurePolicyContinue && nodeType != ConditionNodeID:
+				return adj[id], nil
 			}
+			return nil, nil
+		}
+
+		// success - append completed step
+		recordStep(node, StatusCompleted, startTime, finishTime, output)
+		nodeComplete(node)
 
-			// success - create output map with custom data and append completed step
-			output := map[string]interface{}{
-				"duration": duration,
+		// route to the edge connected to the source handle chosen by the node (e.g the condition node), or to its
+		// default edge when none is. only the chosen branch is traversed, the node's other outgoing edges are never followed
+		if handle, ok := output[outputSourceHandle].(string); ok {
+			edge, ok := conditionRoute(wf, node.ID, handle)
+			if !ok {
+				return nil, fmt.Errorf("no matching conditional edge for node %s", node.ID)
 			}
-			appendStep(&steps, node, StatusCompleted, output)
+			if edge.SourceHandle == ConditionDefaultHandle && handle != ConditionDefaultHandle {
+				logger.Debug("Taking the default edge", "node id", node.ID, "source handle", handle)
+			}
+			return []string{edge.Target}, nil
+		}
 
-		case EndNodeID:
-			startTime := time.Now()
-			err := processEndNode(node)
-			duration := time.Since(startTime).Milliseconds()
+		return adj[id], nil
+	}
 
-			if err != nil {
-				appendStep(&steps, node, StatusFailed, map[string]interface{}{
-					"error":    err.Error(),
-					"duration": duration,
-				})
-				return nil
+	// traverse the graph from the input node id using DFS (Depth First Search) algorithm.
+	// the traversal is iterative with an explicit stack so very long workflows can't overflow the goroutine stack.
+	// the next nodes are pushed in reverse so they are popped in edge order, visiting the nodes in the same
+	// order as a recursive DFS.
+	// the time complexity of DFS is O(V+E) vertices + edges
+	traverse := func(id string) error {
+		stack := []string{id}
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited[id] {
+				continue
 			}
+			visited[id] = true
 
-			output := map[string]interface{}{
-				"duration": duration,
+			next, err := visit(id)
+			if err != nil {
+				return err
 			}
-			appendStep(&steps, node, StatusCompleted, output)
+			for i := len(next) - 1; i >= 0; i-- {
+				stack = append(stack, next[i])
+			}
+		}
+		return nil
+	}
 
-		case FormNodeID:
-			startTime := time.Now()
-			err := processFormNode(node, payload)
-			duration := time.Since(startTime).Milliseconds()
+	// traverse the graph starting from the entry node.
+	// a node interrupted by the deadline is recorded as failed but doesn't return an error, so check ctx as well.
+	err := traverse(entryID)
+	if errors.Is(err, errExecutionStopped) {
+		err = nil
+	}
+	if err == nil && ctx.Err() != nil {
+		err = executionAbortedError(ctx)
+	}
 
+	// a failed step fails the execution even when the traversal itself completed
+	status := StatusCompleted
+	if err != nil || stats.counts().failed > 0 {
+		status = StatusFailed
+	}
+	result := &ExecutionResult{
+		ExecutedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Status:     status,
+		Steps:      steps.close(),
+		Warnings:   warnings,
+		DurationMs: time.Since(executionStart).Milliseconds(),
+		Error:      executionErr,
+	}
+	if opts.IncludeContext {
+		result.Context = contextData.Snapshot()
+	}
+	if opts.SortSteps {
+		sortStepsTopologically(result.Steps, wf)
+	}
+	if opts.OnExecutionComplete != nil {
+		opts.OnExecutionComplete(ctx, result, contextData.Snapshot())
+	}
+	observeExecution(result.Status)
+	logger.Info("Workflow execution finished", "status", result.Status, "steps", len(result.Steps), "duration ms", result.DurationMs, "error", err)
+
+	return result, err
+}
+
+// entryNodeID returns the id of the node the execution starts from: the payload entry node, else the workflow
+// entry node, else the start node.
+func entryNodeID(wf *WorkflowDefinition, payload *ExecutePayload) string {
+	switch {
+	case payload.EntryNodeID != "":
+		return payload.EntryNodeID
+	case wf.EntryNodeID != "":
+		return wf.EntryNodeID
+	}
+	return StartNodeID
+}
+
+// outputSourceHandle is the output key a processor uses to choose the outgoing edge to follow.
+const outputSourceHandle = "sourceHandle"
+
+// builtinProcessors returns the processors for the built-in node types, keyed by node ID.
+func builtinProcessors(opts ExecutionOptions) map[string]NodeProcessor {
+	return map[string]NodeProcessor{
+		StartNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			return nil, processStartNode(node)
+		}),
+		EndNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			return nil, processEndNode(node)
+		}),
+		FormNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			output := FormOutput{
+				Name:    payload.FormData.Name,
+				Email:   payload.FormData.Email,
+				City:    payload.FormData.City,
+				Phone:   payload.FormData.Phone,
+				Country: payload.FormData.Country,
+				Region:  payload.FormData.Region,
+				Inputs:  payload.FormData.Fields,
+			}
+			err := processFormNode(node, payload)
+			// list every invalid field in the step output
+			var validationErrs ValidationErrors
+			if errors.As(err, &validationErrs) {
+				output.Fields = validationErrs
+			}
+			return stepOutput(output, err)
+		}),
+		WeatherAPINodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			err := processWeatherNodeFn(ctx, node, payload, contextData, opts.WeatherAPI)
+
+			var output WeatherOutput
+			if u, ok := contextData.Get(nodeContextKey(node, weatherURLContextKeys[geocodingAPIName])); ok {
+				output.GeocodingURL, _ = u.(string)
+			}
+			if u, ok := contextData.Get(nodeContextKey(node, weatherURLContextKeys[weatherAPIName])); ok {
+				output.WeatherURL, _ = u.(string)
+			}
+			if headers, ok := contextData.Get(nodeContextKey(node, weatherHeadersContextKey)); ok {
+				output.WeatherHeaders, _ = headers.(map[string]string)
+			}
+			if wait, ok := contextData.Get(nodeContextKey(node, weatherRetryWaitContextKey)); ok {
+				output.RetryWaitMs, _ = wait.(int64)
+			}
 			if err != nil {
-				appendStep(&steps, node, StatusFailed, map[string]interface{}{
-					"error":    err.Error(),
-					"duration": duration,
-				})
-				return nil
+				return stepOutput(output, err)
 			}
 
-			output := map[string]interface{}{
-				"name":     payload.FormData.Name,
-				"email":    payload.FormData.Email,
-				"city":     payload.FormData.City,
-				"duration": duration,
+			output.Location = payload.FormData.City
+			if lat, lon, err := formCoordinates(payload.FormData); err == nil {
+				output.Coordinates = &Coordinates{Lat: lat, Lon: lon}
+			}
+			for _, metric := range weatherMetrics(node) {
+				value, _ := contextData.Get(weatherContextKey(metric))
+				output.set(metric, value)
+			}
+			if readingTime, ok := contextData.Get(weatherContextKey(weatherReadingTimeKey)); ok {
+				output.ReadingTime, _ = readingTime.(string)
 			}
-			appendStep(&steps, node, StatusCompleted, output)
-
-		case WeatherAPINodeID:
-			startTime := time.Now()
-			err := processWeatherNodeFn(node, payload, contextData)
-			duration := time.Since(startTime).Milliseconds()
 
+			// a stale reading fails the node in strict mode, otherwise it's only reported
+			if err := weatherStaleness(node, contextData, time.Now()); err != nil {
+				if opts.Strict {
+					return stepOutput(output, err)
+				}
+				output.Warning = err.Error()
+			}
+			return stepOutput(output, nil)
+		}),
+		LoopNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			iterations, err := processLoopNodeFn(ctx, node, payload, contextData, opts.WeatherAPI)
+			return stepOutput(LoopOutput{Iterations: iterations}, err)
+		}),
+		TransformNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			key, value, err := processTransformNode(node, contextData)
 			if err != nil {
-				appendStep(&steps, node, StatusFailed, map[string]interface{}{
-					"error":    err.Error(),
-					"duration": duration,
-				})
-				return nil
+				return nil, err
 			}
 
-			output := map[string]interface{}{
-				"temperature": contextData["weather.temperature"],
-				"location":    payload.FormData.City,
-				"duration":    duration,
+			return stepOutput(TransformOutput{Key: key, Value: value}, nil)
+		}),
+		MetricsNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			return stepOutput(processMetricsNode(ctx, node, contextData), nil)
+		}),
+		ConditionNodeID: NodeProcessorFunc(processConditionStep),
+		EmailNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			from, err := emailFromAddress(node, opts.DefaultEmailFrom)
+			if err != nil {
+				return nil, err
+			}
+			// the deliverability check is opt-in as it adds latency and requires network access
+			if node.Data.Metadata.VerifyMX {
+				if err := verifyEmailDomain(ctx, payload.FormData.Email); err != nil {
+					return nil, err
+				}
+			}
+			subject, body := renderEmailTemplate(node, payload, contextData)
+			messageID, err := newWorkflowID()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate email message id: %w", err)
+			}
+			msg := EmailMessage{ID: messageID, From: from, To: payload.FormData.Email, Subject: subject, Body: body}
+			sender := opts.EmailSender
+			if sender == nil {
+				sender = noopEmailSender{}
 			}
-			appendStep(&steps, node, StatusCompleted, output)
 
-		case ConditionNodeID:
-			startTime := time.Now()
-			conditionMet, err := processConditionNode(node, payload, contextData)
-			duration := time.Since(startTime).Milliseconds()
+			attempts, err := sendEmailWithRetry(ctx, node, sender, msg)
+			if err != nil {
+				output := EmailOutput{
+					Attempts:       attempts,
+					DeliveryStatus: "failed",
+				}
+				// keep the undelivered alert so it isn't silently lost
+				if opts.OnDeadLetter != nil {
+					letter := EmailDeadLetter{
+						NodeID:    node.ID,
+						Recipient: payload.FormData.Email,
+						Subject:   subject,
+						Body:      body,
+						Attempts:  attempts,
+						LastError: err.Error(),
+					}
+					if dlErr := opts.OnDeadLetter(ctx, letter); dlErr != nil {
+						loggerFromContext(ctx).Error("Failed to save email dead letter", "node id", node.ID, "error", dlErr)
+					} else {
+						output.DeadLettered = true
+					}
+				}
+				return stepOutput(output, err)
+			}
 
+			return stepOutput(EmailOutput{
+				EmailDraft: &EmailDraft{
+					To:        msg.To,
+					From:      msg.From,
+					Subject:   msg.Subject,
+					Body:      msg.Body,
+					Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+				},
+				Attempts:       attempts,
+				DeliveryStatus: "sent",
+				MessageID:      msg.ID,
+				EmailSent:      true,
+			}, nil)
+		}),
+		SMSNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			to, err := smsRecipient(node, payload)
 			if err != nil {
-				appendStep(&steps, node, StatusFailed, map[string]interface{}{
-					"error":    err.Error(),
-					"duration": duration,
-				})
-				return nil
+				return nil, err
+			}
+			message := renderPlaceholders(smsMessageTemplate(node), payload, contextData, node.Data.Metadata.MissingPlaceholders)
+			loggerFromContext(ctx).Debug("Sending sms", "node id", node.ID, "to", to, "length", len(message))
+			if err := processSMSNodeFn(node, to, message); err != nil {
+				return nil, err
 			}
 
-			// this is to build the human readable message in the output
-			operatorReadable := strings.ReplaceAll(payload.Condition.Operator, "_", " ")
-			actualValue := contextData["weather.temperature"].(float64)
-			threshold := payload.Condition.Threshold
+			// build mock sms output
+			return stepOutput(SMSOutput{
+				SMS: &SMSMessage{
+					To:        to,
+					Message:   message,
+					Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+				},
+				DeliveryStatus: "sent",
+				MessageID:      "sms_abc123def456",
+				SMSSent:        true,
+			}, nil)
+		}),
+		SlackNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			message := renderPlaceholders(slackMessageTemplate(node), payload, contextData, node.Data.Metadata.MissingPlaceholders)
+			loggerFromContext(ctx).Debug("Posting slack message", "node id", node.ID, "length", len(message))
+			status, err := processSlackNodeFn(ctx, node, message)
+			if err != nil {
+				return stepOutput(SlackOutput{WebhookStatus: status}, err)
+			}
 
-			conditionText := ConditionNotMetString
-			if conditionMet {
-				conditionText = ConditionMetString
+			return stepOutput(SlackOutput{
+				Slack: &SlackMessage{
+					Message:   message,
+					Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+				},
+				WebhookStatus: status,
+				SlackSent:     true,
+			}, nil)
+		}),
+		ArchiveNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			writer := opts.ArchiveWriter
+			if writer == nil {
+				writer = defaultArchiveWriter{}
+			}
+			destination, written, err := processArchiveNodeFn(ctx, node, contextData, writer)
+			output := ArchiveOutput{
+				Destination: redactURL(destination),
+				Content:     archiveContent(node),
+			}
+			if err != nil {
+				return stepOutput(output, err)
 			}
 
-			output := map[string]interface{}{
-				"conditionMet": conditionMet,
-				"threshold":    payload.Condition.Threshold,
-				"operator":     payload.Condition.Operator,
-				"actualValue":  contextData["weather.temperature"],
-				"message":      fmt.Sprintf("Temperature %.1f°C is %s %.1f°C - %s", actualValue, operatorReadable, threshold, conditionText),
-				"duration":     duration,
+			output.BytesWritten = written
+			return stepOutput(output, nil)
+		}),
+		HistoryNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			previous, missing, err := processHistoryNodeFn(ctx, node, contextData, opts.LastExecution)
+			if err != nil {
+				return nil, err
+			}
+			if previous == nil {
+				return stepOutput(HistoryOutput{Found: false}, nil)
 			}
-			appendStep(&steps, node, StatusCompleted, output)
 
-			// route based on conditionMet and edge label
-			for _, edge := range wf.Edges {
-				if edge.Source != node.ID {
-					continue
-				}
-				if conditionMet && edge.Label == "✓ Condition Met" {
-					return traverse(edge.Target)
+			loaded := make(map[string]any, len(node.Data.Metadata.History.Values))
+			for key := range node.Data.Metadata.History.Values {
+				if value, ok := contextData.Get(key); ok {
+					loaded[key] = value
 				}
-				if !conditionMet && edge.Label == "✗ No Alert Needed" {
-					return traverse(edge.Target)
+			}
+			return stepOutput(HistoryOutput{
+				Found:       true,
+				ExecutionID: previous.ID,
+				ExecutedAt:  previous.Result.ExecutedAt,
+				Values:      loaded,
+				Missing:     missing,
+			}, nil)
+		}),
+		HTTPRequestNodeID: NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+			err := processHTTPRequestNodeFn(ctx, node, payload, contextData)
+			var output HTTPRequestOutput
+			if status, ok := contextData.Get(nodeContextKey(node, "status")); ok {
+				output.Status, _ = status.(int)
+			}
+			if err != nil {
+				return stepOutput(output, err)
+			}
+
+			output.Method = httpRequestMethod(node)
+			output.URL = renderPlaceholders(node.Data.Metadata.HTTPRequest.URL, payload, contextData, node.Data.Metadata.MissingPlaceholders)
+			output.Body, _ = contextData.Get(nodeContextKey(node, "body"))
+			if extract := node.Data.Metadata.HTTPRequest.Extract; len(extract) > 0 {
+				output.Extracted = make(map[string]any, len(extract))
+				for key := range extract {
+					output.Extracted[key], _ = contextData.Get(key)
 				}
 			}
-			return fmt.Errorf("no matching conditional edge for node %s", node.ID)
-		case EmailNodeID:
-			startTime := time.Now()
-			err := processEmailNodeFn(node, payload)
-			duration := time.Since(startTime).Milliseconds()
+			return stepOutput(output, nil)
+		}),
+	}
+}
 
-			if err != nil {
-				appendStep(&steps, node, StatusFailed, map[string]interface{}{
-					"error":    err.Error(),
-					"duration": duration,
-				})
-				return nil
-			}
-
-			// build mock email output
-			output := map[string]interface{}{
-				"emailDraft": map[string]interface{}{
-					"to":      payload.FormData.Email,
-					"from":    "weather-alerts@example.com",
-					"subject": node.Data.Metadata.EmailTemplate.Subject,
-					"body": strings.ReplaceAll(
-						strings.ReplaceAll(
-							node.Data.Metadata.EmailTemplate.Body,
-							"{{city}}", payload.FormData.City,
-						),
-						"{{temperature}}", fmt.Sprintf("%.1f", contextData["weather.temperature"]),
-					),
-					"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
-				},
-				"deliveryStatus": "sent",
-				"messageId":      "msg_abc123def456",
-				"emailSent":      true,
-				"duration":       duration,
+// processConditionStep evaluates the condition node and builds its step output, including the
+// source handle of the edge to route to.
+func processConditionStep(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+	contextData = conditionData(node, payload, contextData)
+
+	handle, err := processConditionNode(node, payload, contextData)
+	if err != nil {
+		return nil, err
+	}
+
+	// for clause based conditions, report the clause that matched (or the first one when nothing matched)
+	conditionMet := handle != "" && handle != ConditionNotMetHandle
+	field := conditionField(node)
+	condition := payload.resolvedCondition()
+	if clauses := node.Data.Metadata.Conditions; len(clauses) > 0 {
+		clause := clauses[0]
+		for _, c := range clauses {
+			if c.SourceHandle == handle {
+				clause = c
+				break
 			}
-			appendStep(&steps, node, StatusCompleted, output)
 		}
+		field = clauseField(node, clause)
+		condition = clause.condition()
+	}
 
-		// recursively call traverse on next nodes
-		for _, next := range adj[id] {
-			if err := traverse(next); err != nil {
-				return err
-			}
+	// for expressions, report the left-hand side as the compared value
+	var expression *comparison
+	if expr, ok := conditionComparison(node); ok {
+		cmp, err := evaluateComparison(expr, contextData)
+		if err != nil {
+			return nil, err
 		}
-		return nil
+		expression = &cmp
+		field = cmp.left
+		condition = cmp.condition
 	}
 
-	// recursively traverse the graph starting from the start node
-	if err := traverse(StartNodeID); err != nil {
-		return &ExecutionResult{
-			ExecutedAt: time.Now().UTC().Format(time.RFC3339Nano),
-			Status:     StatusFailed,
-			Steps:      steps,
-		}, err
+	// this is to build the human readable message in the output
+	conditionText := ConditionNotMetString
+	if conditionMet {
+		conditionText = ConditionMetString
 	}
 
-	return &ExecutionResult{
-		ExecutedAt: time.Now().UTC().Format(time.RFC3339Nano),
-		Status:     StatusCompleted,
-		Steps:      steps,
-	}, nil
+	metric := strings.TrimPrefix(field, weatherContextKey(""))
+	label, ok := weatherMetricLabels[metric]
+	if !ok {
+		label = field
+	}
+	unit := node.Data.Metadata.Unit
+	if unit == "" {
+		unit = weatherMetricUnits[metric]
+	}
+
+	// the value was checked by processConditionNode, but the reported clause may read a different field
+	var actualValue float64
+	if expression != nil {
+		actualValue = expression.value
+	} else if actualValue, err = conditionValue(field, contextData); err != nil {
+		return nil, err
+	}
+
+	message := conditionMessage(node.Data.Metadata.MessageTemplate, conditionMessageValues{
+		Label:     label,
+		Value:     actualValue,
+		Unit:      unit,
+		Condition: condition,
+		Result:    conditionText,
+	})
+
+	output := ConditionOutput{
+		ConditionMet: conditionMet,
+		SourceHandle: handle,
+		Threshold:    condition.Threshold,
+		Operator:     condition.Operator,
+		ActualValue:  actualValue,
+		Message:      message,
+	}
+	if condition.Operator == OperatorBetween {
+		output.UpperThreshold = &condition.UpperThreshold
+	}
+	if expression != nil {
+		output.Expression = node.Data.Metadata.ConditionExpr
+	}
+
+	return stepOutput(output, nil)
+}
+
+// runProcessor runs the node processor and converts a panic into an error, so a handler bug or
+// unexpected data fails the step instead of crashing the request.
+func runProcessor(ctx context.Context, processor NodeProcessor, node Node, payload *ExecutePayload, contextData *Context) (output map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			loggerFromContext(ctx).Error("Node processor panicked", "node id", node.ID, "panic", r, "stack", string(debug.Stack()))
+			output = nil
+			err = fmt.Errorf("%w: %v", ErrNodePanicked, r)
+		}
+	}()
+
+	return processor.Process(ctx, node, payload, contextData)
+}
+
+// executionAbortedError returns the error describing why the execution context was aborted.
+func executionAbortedError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrExecutionTimeout
+	}
+	return ErrExecutionCanceled
 }
 
 // node handlers
 
 // processStartNode doesn't do much but custom logic can be added later (e.g metrics?).
 func processStartNode(node Node) error {
-	slog.Debug("Processing node", "node id", node.ID)
 	return nil
 }
 
 // processEndNode is similar to the the start node.
 func processEndNode(node Node) error {
-	slog.Debug("Processing node", "node id", node.ID)
 	return nil
 }
 
-// processFormNode ensures the required fields are not empty.
+// processFormNode ensures the required fields are not empty and the email is well-formed. The required fields
+// are the node's InputFields, or the name, email and city when it lists none.
+// Every invalid field is reported at once in the returned ValidationErrors.
 func processFormNode(node Node, payload *ExecutePayload) error {
-	slog.Debug("Processing node", "node id", node.ID)
+	if errs := formNodeErrors(node, payload.FormData); len(errs) > 0 {
+		return errs
+	}
 
-	if payload.FormData.Name == "" {
-		return ErrMissingFormFieldName
+	return nil
+}
+
+// formNodeErrors validates the form data against the form node: its InputFields when it declares some,
+// otherwise the name, email and city of the default form.
+func formNodeErrors(node Node, formData FormData) ValidationErrors {
+	if inputFields := node.Data.Metadata.InputFields; len(inputFields) > 0 {
+		return validateInputFields(formData, inputFields)
 	}
-	if payload.FormData.Email == "" {
-		return ErrMissingFormFieldEmail
+	return validateFormData(formData)
+}
+
+// missingFormFieldErrors are the errors reported for the named form fields when they're missing.
+// Other fields are reported with ErrMissingRequiredField.
+var missingFormFieldErrors = map[string]error{
+	"name":  ErrMissingFormFieldName,
+	"email": ErrMissingFormFieldEmail,
+	"city":  ErrMissingFormFieldCity,
+	"phone": ErrMissingFormFieldPhone,
+}
+
+// validateInputFields collects the input fields missing from the form data, looked up by name in the named
+// fields and FormData.Fields. As with validateFormData, the email must be well-formed, the coordinates in range,
+// and a city isn't needed when the coordinates are given.
+func validateInputFields(formData FormData, inputFields []string) ValidationErrors {
+	var errs ValidationErrors
+	values := formData.values()
+
+	for _, name := range inputFields {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if name == "city" && (formData.Lat != nil || formData.Lon != nil) {
+			continue
+		}
+		if err, ok := missingFormFieldErrors[name]; ok {
+			errs = append(errs, newFieldError("formData."+name, err))
+			continue
+		}
+		errs = append(errs, newFieldError("formData.fields."+name, ErrMissingRequiredField))
 	}
-	// can also add to check email is in email format
-	if payload.FormData.City == "" {
-		return ErrMissingFormFieldCity
+
+	if formData.Email != "" {
+		if _, err := parseEmailAddress(formData.Email); err != nil {
+			errs = append(errs, newFieldError("formData.email", err))
+		}
+	}
+	if formData.Lat != nil || formData.Lon != nil {
+		if _, _, err := formCoordinates(formData); err != nil {
+			var validationErrs ValidationErrors
+			errors.As(err, &validationErrs)
+			errs = append(errs, validationErrs...)
+		}
 	}
 
-	return nil
+	return errs
+}
+
+// validateFormData collects every missing or invalid form field.
+func validateFormData(formData FormData) ValidationErrors {
+	var errs ValidationErrors
+
+	if formData.Name == "" {
+		errs = append(errs, newFieldError("formData.name", ErrMissingFormFieldName))
+	}
+	if formData.Email == "" {
+		errs = append(errs, newFieldError("formData.email", ErrMissingFormFieldEmail))
+	} else if _, err := parseEmailAddress(formData.Email); err != nil {
+		errs = append(errs, newFieldError("formData.email", err))
+	}
+	// the city is only needed to geocode when the coordinates aren't given
+	if formData.Lat == nil && formData.Lon == nil {
+		if formData.City == "" {
+			errs = append(errs, newFieldError("formData.city", ErrMissingFormFieldCity))
+		}
+	} else if _, _, err := formCoordinates(formData); err != nil {
+		var validationErrs ValidationErrors
+		errors.As(err, &validationErrs)
+		errs = append(errs, validationErrs...)
+	}
+
+	return errs
+}
+
+// formCoordinates returns the latitude and longitude given in the form data. It returns ValidationErrors when
+// only one of them is set or they are out of range (-90 to 90 and -180 to 180).
+func formCoordinates(formData FormData) (float64, float64, error) {
+	var errs ValidationErrors
+	switch {
+	case formData.Lat == nil:
+		errs = append(errs, newFieldError("formData.lat", ErrIncompleteCoordinates))
+	case *formData.Lat < -90 || *formData.Lat > 90:
+		errs = append(errs, newFieldError("formData.lat", fmt.Errorf("%w: %g", ErrInvalidLatitude, *formData.Lat)))
+	}
+	switch {
+	case formData.Lon == nil:
+		errs = append(errs, newFieldError("formData.lon", ErrIncompleteCoordinates))
+	case *formData.Lon < -180 || *formData.Lon > 180:
+		errs = append(errs, newFieldError("formData.lon", fmt.Errorf("%w: %g", ErrInvalidLongitude, *formData.Lon)))
+	}
+	if len(errs) > 0 {
+		return 0, 0, errs
+	}
+	return *formData.Lat, *formData.Lon, nil
 }
 
 // structs for geocoding response.
 type GeoCodingResponse struct {
 	Results []struct {
-		Latitude  float64 `json:"latitude"`
-		Longitude float64 `json:"longitude"`
+		Name        string  `json:"name"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+		Country     string  `json:"country"`
+		CountryCode string  `json:"country_code"`
+		Admin1      string  `json:"admin1"` // first-level administrative area, e.g a state
 	} `json:"results"`
 }
 
 // struct for Open-Meteo weather response.
+// The blocks are pointers so a response without them (e.g an error object) isn't read as 0 values.
 type WeatherResponse struct {
-	CurrentWeather struct {
-		Temperature float64 `json:"temperature"`
+	UTCOffsetSeconds int `json:"utc_offset_seconds"` // timezone of the times, GMT by default
+	CurrentWeather   *struct {
+		Temperature   float64         `json:"temperature"`
+		WindSpeed     float64         `json:"windspeed"`
+		WindDirection float64         `json:"winddirection"`
+		WeatherCode   float64         `json:"weathercode"`
+		Time          json.RawMessage `json:"time"` // local time of the reading, or unix time with timeformat=unixtime
 	} `json:"current_weather"`
+	// Current is only populated when extra variables (e.g humidity) are requested with the "current" query param.
+	Current *struct {
+		RelativeHumidity float64 `json:"relative_humidity_2m"`
+	} `json:"current"`
 }
 
-// processWeatherNode calls an external API to retrieve the current weather for the input city.
-func processWeatherNode(node Node, payload *ExecutePayload, contextData map[string]any) error {
-	slog.Debug("Processing node", "node id", node.ID)
+// weatherMetricValues maps each supported metric to its value in the weather response.
+var weatherMetricValues = map[string]func(w *WeatherResponse) float64{
+	WeatherMetricTemperature:   func(w *WeatherResponse) float64 { return w.CurrentWeather.Temperature },
+	WeatherMetricWindSpeed:     func(w *WeatherResponse) float64 { return w.CurrentWeather.WindSpeed },
+	WeatherMetricWindDirection: func(w *WeatherResponse) float64 { return w.CurrentWeather.WindDirection },
+	WeatherMetricWeatherCode:   func(w *WeatherResponse) float64 { return w.CurrentWeather.WeatherCode },
+	WeatherMetricHumidity:      func(w *WeatherResponse) float64 { return w.Current.RelativeHumidity },
+}
+
+// weatherMetricLabels and weatherMetricUnits describe each metric in the condition message.
+var weatherMetricLabels = map[string]string{
+	WeatherMetricTemperature:   "Temperature",
+	WeatherMetricWindSpeed:     "Wind speed",
+	WeatherMetricWindDirection: "Wind direction",
+	WeatherMetricWeatherCode:   "Weather code",
+	WeatherMetricHumidity:      "Humidity",
+}
+
+var weatherMetricUnits = map[string]string{
+	WeatherMetricTemperature:   "°C",
+	WeatherMetricWindSpeed:     " km/h",
+	WeatherMetricWindDirection: "°",
+	WeatherMetricHumidity:      "%",
+}
+
+// weatherMetrics returns the metrics the weather node should record, defaulting to temperature.
+func weatherMetrics(node Node) []string {
+	if len(node.Data.Metadata.Metrics) == 0 {
+		return []string{WeatherMetricTemperature}
+	}
+	return node.Data.Metadata.Metrics
+}
 
+// weatherContextKey returns the contextData key a weather metric is stored under.
+func weatherContextKey(metric string) string {
+	return "weather." + metric
+}
+
+// weatherURLContextKeys maps the weather provider APIs to the node-scoped contextData key (and step output key)
+// of the URL called, e.g "weather-api.weatherUrl".
+var weatherURLContextKeys = map[string]string{
+	geocodingAPIName: "geocodingUrl",
+	weatherAPIName:   "weatherUrl",
+}
+
+// weatherHeadersContextKey is the node-scoped contextData key (and step output key) of the redacted apiHeaders.
+const weatherHeadersContextKey = "weatherHeaders"
+
+// weatherRetryWaitContextKey is the node-scoped contextData key (and step output key) of the time in milliseconds
+// waited before retrying rate limited requests, only set when the weather API asked to retry later.
+const weatherRetryWaitContextKey = "retryWaitMs"
+
+// processWeatherNode calls an external API to retrieve the current weather for the input city.
+// Coordinates given in the form data are used directly, skipping the geocoding of the city.
+func processWeatherNode(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
 	city := payload.FormData.City
-	if city == "" {
+	hasCoordinates := payload.FormData.Lat != nil || payload.FormData.Lon != nil
+	if city == "" && !hasCoordinates {
 		return ErrMissingFormFieldCity
 	}
 
-	// get coordinates from city (required in the weather check API)
-	geoURL := fmt.Sprintf("https://geocoding-api.open-meteo.com/v1/search?name=%s&count=1", city)
-	resp, err := http.Get(geoURL)
+	provider, err := newWeatherProvider(node, weatherAPI)
 	if err != nil {
-		return fmt.Errorf("geocoding API request failed: %w", err)
+		return err
+	}
+	// record the called URLs (on failure too) so bad apiEndpoint templates can be diagnosed
+	if headers := node.Data.Metadata.APIHeaders; len(headers) > 0 {
+		contextData.Set(nodeContextKey(node, weatherHeadersContextKey), redactHeaders(headers))
+	}
+	defer func() {
+		for name, key := range weatherURLContextKeys {
+			if u, ok := provider.requestedURLs()[name]; ok {
+				contextData.Set(nodeContextKey(node, key), u)
+			}
+		}
+		if wait := provider.retryWait(); wait > 0 {
+			contextData.Set(nodeContextKey(node, weatherRetryWaitContextKey), wait.Milliseconds())
+		}
+	}()
+
+	// validate the requested metrics before calling the weather API
+	metrics := weatherMetrics(node)
+	for _, metric := range metrics {
+		if _, ok := weatherMetricValues[metric]; !ok {
+			return fmt.Errorf("unsupported weather metric: %s", metric)
+		}
 	}
-	defer resp.Body.Close()
 
-	var geoData GeoCodingResponse
-	if err := json.NewDecoder(resp.Body).Decode(&geoData); err != nil {
-		return ErrResponseDecodeFailed
+	// get coordinates from city (required in the weather check API) unless they were given
+	var lat, lon float64
+	if hasCoordinates {
+		lat, lon, err = formCoordinates(payload.FormData)
+	} else {
+		lat, lon, err = provider.geocode(ctx, city, newGeocodingHint(node, payload.FormData))
 	}
-	if len(geoData.Results) == 0 {
-		return fmt.Errorf("no results found for city: %s", city)
+	if err != nil {
+		return err
 	}
 
-	lat := geoData.Results[0].Latitude
-	lon := geoData.Results[0].Longitude
+	values, err := provider.currentWeather(ctx, node, lat, lon, metrics)
+	if err != nil {
+		return err
+	}
 
-	// replace placeholders in definition API URL
-	apiEndpoint := node.Data.Metadata.APIEndpoint
-	apiEndpoint = strings.ReplaceAll(apiEndpoint, "{lat}", fmt.Sprintf("%f", lat))
-	apiEndpoint = strings.ReplaceAll(apiEndpoint, "{lon}", fmt.Sprintf("%f", lon))
+	// put the requested metrics to contextData
+	for _, metric := range metrics {
+		contextData.SetFloat(weatherContextKey(metric), values[metric])
+	}
+	if observedAt := provider.readingTime(); !observedAt.IsZero() {
+		contextData.Set(weatherContextKey(weatherReadingTimeKey), observedAt.UTC().Format(time.RFC3339))
+	}
 
-	// fetch weather data from API URL
-	weatherResp, err := http.Get(apiEndpoint)
+	return nil
+}
+
+// weatherReadingTimeKey is the weatherContextKey (and step output key) of the RFC 3339 time of the weather reading.
+const weatherReadingTimeKey = "readingTime"
+
+// weatherStaleness returns an ErrStaleWeatherData error when the weather reading stored in contextData is older
+// than the node maxAgeMinutes. Nodes without a max age, and readings without a time, are never stale.
+func weatherStaleness(node Node, contextData *Context, now time.Time) error {
+	maxAge := time.Duration(node.Data.Metadata.MaxAgeMinutes) * time.Minute
+	if maxAge <= 0 {
+		return nil
+	}
+	raw, ok := contextData.Get(weatherContextKey(weatherReadingTimeKey))
+	if !ok {
+		return nil
+	}
+	readingTime, ok := raw.(string)
+	if !ok {
+		return nil
+	}
+	observedAt, err := time.Parse(time.RFC3339, readingTime)
 	if err != nil {
-		return fmt.Errorf("failed to fetch weather data: %w", err)
+		return nil
 	}
-	defer weatherResp.Body.Close()
 
-	if weatherResp.StatusCode != http.StatusOK {
-		return fmt.Errorf("weather API returned status: %d", weatherResp.StatusCode)
+	if age := now.Sub(observedAt); age > maxAge {
+		return fmt.Errorf("%w: reading from %s is %s old, the max age is %s", ErrStaleWeatherData, readingTime, age.Truncate(time.Minute), maxAge)
+	}
+	return nil
+}
+
+// defaultGeocodingBaseURL is the Open-Meteo geocoding API used when no base URL is configured.
+const defaultGeocodingBaseURL = "https://geocoding-api.open-meteo.com"
+
+// geocodingURL returns the geocoding search URL for the city, asking for count results.
+func geocodingURL(weatherAPI WeatherAPIConfig, city string, count int) string {
+	base := weatherAPI.GeocodingBaseURL
+	if base == "" {
+		base = defaultGeocodingBaseURL
 	}
+	query := url.Values{"name": {city}, "count": {strconv.Itoa(count)}}
+
+	return strings.TrimSuffix(base, "/") + "/v1/search?" + query.Encode()
+}
 
-	var weather WeatherResponse
-	if err := json.NewDecoder(weatherResp.Body).Decode(&weather); err != nil {
-		return ErrResponseDecodeFailed
+// weatherURL points the node apiEndpoint at the configured weather base URL, keeping its path and query.
+// The endpoint is returned unchanged when no base URL is configured.
+func weatherURL(weatherAPI WeatherAPIConfig, apiEndpoint string) (string, error) {
+	if weatherAPI.WeatherBaseURL == "" {
+		return apiEndpoint, nil
 	}
 
-	// put temperature to contextData map
-	contextData["weather.temperature"] = weather.CurrentWeather.Temperature
+	base, err := url.Parse(weatherAPI.WeatherBaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid weather API base url: %w", err)
+	}
+	endpoint, err := url.Parse(apiEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid weather API endpoint: %w", err)
+	}
+	endpoint.Scheme = base.Scheme
+	endpoint.Host = base.Host
+	endpoint.Path = strings.TrimSuffix(base.Path, "/") + endpoint.Path
 
-	return nil
+	return endpoint.String(), nil
 }
 
-// processConditionNode evaluates the condition and returns a bool
-func processConditionNode(node Node, payload *ExecutePayload, contextData map[string]any) (bool, error) {
-	slog.Debug("Processing node", "node id", node.ID)
+// processLoopNode looks up the weather of every city in the node options, at the option coordinates when it has
+// some (otherwise the city is geocoded), and returns one result per iteration. The results are stored in
+// contextData under "<nodeID>.results" and each metric in an array under "<nodeID>.<metric>" (e.g
+// "loop.temperature"). A failed lookup is recorded in its iteration and doesn't stop the remaining cities.
+func processLoopNode(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) ([]LoopIteration, error) {
+	options := node.Data.Metadata.Options
+	if len(options) == 0 {
+		return nil, fmt.Errorf("loop node %s has no options configured", node.ID)
+	}
+
+	metrics := weatherMetrics(node)
+	iterations := make([]LoopIteration, 0, len(options))
+	values := make(map[string][]any, len(metrics))
+	var errs []error
 
-	// get the temperature from the map recorded in the weather node
-	tempVal, ok := contextData["weather.temperature"]
-	if !ok {
-		return false, fmt.Errorf("weather temp not in map")
+	for i, option := range options {
+		if err := ctx.Err(); err != nil {
+			return iterations, err
+		}
+
+		// each iteration runs the weather lookup for its own city with an isolated context, at the option
+		// coordinates when it has some so the city isn't geocoded again
+		iterPayload := *payload
+		iterPayload.FormData.City = option.City
+		iterPayload.FormData.Lat, iterPayload.FormData.Lon = nil, nil
+		if option.hasCoordinates() {
+			iterPayload.FormData.Lat, iterPayload.FormData.Lon = option.Lat, option.Lon
+		}
+		iterData := NewContext()
+
+		iteration := LoopIteration{Index: i, Location: option.City}
+		if err := processWeatherNodeFn(ctx, node, &iterPayload, iterData, weatherAPI); err != nil {
+			iteration.Status = StatusFailed
+			iteration.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", option.City, err))
+		} else {
+			iteration.Status = StatusCompleted
+			for _, metric := range metrics {
+				value, _ := iterData.Get(weatherContextKey(metric))
+				iteration.set(metric, value)
+				values[metric] = append(values[metric], value)
+			}
+		}
+		iterations = append(iterations, iteration)
+	}
+
+	contextData.Set(nodeContextKey(node, "results"), iterations)
+	for _, metric := range metrics {
+		contextData.Set(nodeContextKey(node, metric), values[metric])
 	}
 
-	temperature, ok := tempVal.(float64)
+	return iterations, errors.Join(errs...)
+}
+
+// executionStats is the progress of the running execution, read by the metrics node.
+type executionStats struct {
+	startedAt  time.Time
+	workflowID string
+	steps      *stepCollector // steps recorded so far, read by the archive node and counted by counts
+}
+
+// counts returns the number of steps recorded so far and how many of them failed, accumulated by the step
+// collector so it's safe while nodes are processed concurrently. It's zero without a collector.
+func (s *executionStats) counts() stepCounts {
+	if s.steps == nil {
+		return stepCounts{}
+	}
+	return s.steps.stepCounts()
+}
+
+type executionStatsContextKey struct{}
+
+// contextWithExecutionStats returns a copy of ctx carrying the execution stats.
+func contextWithExecutionStats(ctx context.Context, stats *executionStats) context.Context {
+	return context.WithValue(ctx, executionStatsContextKey{}, stats)
+}
+
+// processMetricsNode stores summary values of the execution so far in contextData under node-scoped keys
+// (e.g "metrics.stepCount") so downstream templates can reference them, and returns them as the step output.
+// It has no side effects so it also runs in a dry run.
+func processMetricsNode(ctx context.Context, node Node, contextData *Context) MetricsOutput {
+	stats, ok := ctx.Value(executionStatsContextKey{}).(*executionStats)
 	if !ok {
-		return false, fmt.Errorf("weather temp is not a float64")
-	}
-
-	operator := payload.Condition.Operator
-	threshold := payload.Condition.Threshold
-
-	switch operator {
-	case "greater_than":
-		return temperature > threshold, nil
-	case "less_than":
-		return temperature < threshold, nil
-	case "equals":
-		return temperature == threshold, nil
-	case "greater_than_or_equal":
-		return temperature >= threshold, nil
-	case "less_than_or_equal":
-		return temperature <= threshold, nil
+		// called outside of processNodes, e.g from a test
+		stats = &executionStats{startedAt: time.Now()}
+	}
+
+	counts := stats.counts()
+	output := MetricsOutput{
+		StepCount:          counts.recorded,
+		FailedSteps:        counts.failed,
+		ElapsedMs:          time.Since(stats.startedAt).Milliseconds(),
+		ExecutionStartedAt: stats.startedAt.UTC().Format(time.RFC3339Nano),
+	}
+	metrics := map[string]any{
+		"stepCount":   output.StepCount,
+		"failedSteps": output.FailedSteps,
+		"elapsedMs":   output.ElapsedMs,
+		"startedAt":   output.ExecutionStartedAt,
+	}
+	for name, value := range metrics {
+		contextData.Set(nodeContextKey(node, name), value)
+	}
+
+	return output
+}
+
+// processTransformNode evaluates the node expression against contextData and stores the result under the
+// output key, so downstream nodes (e.g a condition) can use the derived value. The key is either set with
+// outputKey or as the left-hand side of the expression, e.g "feels_like = temperature - windspeed * 0.5".
+func processTransformNode(node Node, contextData *Context) (string, float64, error) {
+	key, expr := transformOutputKey(node)
+	if key == "" {
+		return "", 0, fmt.Errorf("transform node %s has no output key", node.ID)
+	}
+
+	value, err := evaluateExpression(expr, contextData)
+	if err != nil {
+		return "", 0, err
+	}
+
+	contextData.SetFloat(key, value)
+	return key, value, nil
+}
+
+// transformOutputKey splits the transform node expression into the output key and the expression to evaluate.
+// The output key set with outputKey takes precedence over the left-hand side of the expression.
+func transformOutputKey(node Node) (string, string) {
+	key := node.Data.Metadata.OutputKey
+	expr := node.Data.Metadata.Expression
+	if lhs, rhs, ok := strings.Cut(expr, "="); ok {
+		if key == "" {
+			key = strings.TrimSpace(lhs)
+		}
+		expr = rhs
+	}
+	return key, expr
+}
+
+// processConditionNode evaluates the condition and returns the source handle of the edge to route to.
+// When the node defines condition clauses they are evaluated top to bottom and the handle of the first
+// matching clause is returned (an empty handle means no clause matched). Otherwise the payload condition
+// is evaluated and ConditionMetHandle or ConditionNotMetHandle is returned.
+func processConditionNode(node Node, payload *ExecutePayload, contextData *Context) (string, error) {
+	contextData = conditionData(node, payload, contextData)
+
+	if clauses := node.Data.Metadata.Conditions; len(clauses) > 0 {
+		for _, clause := range clauses {
+			value, err := conditionValue(clauseField(node, clause), contextData)
+			if err != nil {
+				return "", err
+			}
+
+			met, err := evaluateCondition(value, clause.condition())
+			if err != nil {
+				return "", err
+			}
+			if met {
+				return clause.SourceHandle, nil
+			}
+		}
+		return "", nil
+	}
+
+	// a condition expression is a complete comparison, e.g "abs(temperature - 20) < 5"
+	if expr, ok := conditionComparison(node); ok {
+		cmp, err := evaluateComparison(expr, contextData)
+		if err != nil {
+			return "", err
+		}
+		return conditionHandle(evaluateCondition(cmp.value, cmp.condition))
+	}
+
+	// get the value to compare (weather temperature by default) from the map recorded by an upstream node
+	value, err := conditionValue(conditionField(node), contextData)
+	if err != nil {
+		return "", err
+	}
+
+	return conditionHandle(evaluateCondition(value, payload.resolvedCondition()))
+}
+
+// conditionHandle returns the source handle of the binary condition node outcome.
+func conditionHandle(met bool, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if met {
+		return ConditionMetHandle, nil
+	}
+	return ConditionNotMetHandle, nil
+}
+
+// conditionData returns the values the condition node reads. When the node's valueSource is "payload"
+// every field it compares resolves to condition.value from the execute payload instead of an upstream node,
+// so a condition can be used without a preceding weather node. contextData itself is never modified.
+func conditionData(node Node, payload *ExecutePayload, contextData *Context) *Context {
+	if node.Data.Metadata.ValueSource != ConditionValueSourcePayload || payload.Condition.Value == nil {
+		return contextData
+	}
+
+	data := newContextFrom(contextData.Snapshot())
+	data.SetFloat(conditionField(node), *payload.Condition.Value)
+	for _, clause := range node.Data.Metadata.Conditions {
+		data.SetFloat(clauseField(node, clause), *payload.Condition.Value)
+	}
+	return data
+}
+
+// conditionValue returns the numeric contextData value the condition compares.
+func conditionValue(field string, contextData *Context) (float64, error) {
+	value, err := contextData.GetFloat(field)
+	if err != nil {
+		return 0, fmt.Errorf("condition field %w", err)
+	}
+
+	return value, nil
+}
+
+// evaluateCondition compares the value against the condition threshold(s) using its operator.
+func evaluateCondition(value float64, condition Condition) (bool, error) {
+	threshold := condition.Threshold
+	switch condition.Operator {
+	case OperatorGreaterThan:
+		return value > threshold, nil
+	case OperatorLessThan:
+		return value < threshold, nil
+	case OperatorEquals:
+		return math.Abs(value-threshold) <= condition.equalsTolerance(), nil
+	case OperatorGreaterThanOrEqual:
+		return value >= threshold, nil
+	case OperatorLessThanOrEqual:
+		return value <= threshold, nil
+	case OperatorBetween:
+		return value >= threshold && value <= condition.UpperThreshold, nil
 	default:
-		return false, fmt.Errorf("unsupported operator: %s", operator)
+		return false, fmt.Errorf("unsupported operator: %s", condition.Operator)
+	}
+}
+
+// condition returns the clause comparison as a Condition.
+func (c ConditionClause) condition() Condition {
+	return Condition{Operator: c.Operator, Threshold: c.Threshold, UpperThreshold: c.UpperThreshold, Tolerance: c.Tolerance}
+}
+
+// equalsTolerance returns the tolerance of the equals operator, defaulting to defaultEqualsTolerance.
+func (c Condition) equalsTolerance() float64 {
+	if c.Tolerance == nil {
+		return defaultEqualsTolerance
+	}
+	return *c.Tolerance
+}
+
+// clauseField returns the contextData key a condition clause evaluates, defaulting to the node's condition field.
+func clauseField(node Node, clause ConditionClause) string {
+	if clause.Field != "" {
+		return clause.Field
+	}
+	return conditionField(node)
+}
+
+// conditionInputsAvailable reports whether every contextData value the condition node reads is present.
+func conditionInputsAvailable(node Node, contextData *Context) bool {
+	for _, keys := range conditionInputKeys(node) {
+		if !slices.ContainsFunc(keys, func(key string) bool {
+			_, ok := contextData.Get(key)
+			return ok
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionInputKeys returns the contextData values the condition node reads, as the keys each value
+// can be resolved from (an expression variable resolves from its name or as a weather metric).
+func conditionInputKeys(node Node) [][]string {
+	if expr, ok := conditionComparison(node); ok {
+		// an invalid expression fails when the node is processed
+		names, _ := expressionVariables(expr)
+		inputs := make([][]string, 0, len(names))
+		for _, name := range names {
+			inputs = append(inputs, expressionVariableKeys(name))
+		}
+		return inputs
+	}
+
+	if clauses := node.Data.Metadata.Conditions; len(clauses) > 0 {
+		inputs := make([][]string, 0, len(clauses))
+		for _, clause := range clauses {
+			inputs = append(inputs, []string{clauseField(node, clause)})
+		}
+		return inputs
+	}
+	return [][]string{{conditionField(node)}}
+}
+
+// conditionComparison returns the condition expression of the node when it's a complete comparison evaluated
+// on its own (e.g "abs(temperature - 20) < 5" or "max(temperature, feels_like) > 30"), rather than a template
+// completed with the payload operator and threshold (e.g "temperature {{operator}} {{threshold}}").
+// Condition clauses take precedence over the expression.
+func conditionComparison(node Node) (string, bool) {
+	expr := strings.TrimSpace(node.Data.Metadata.ConditionExpr)
+	if expr == "" || strings.Contains(expr, "{{") || len(node.Data.Metadata.Conditions) > 0 {
+		return "", false
+	}
+	return expr, true
+}
+
+// conditionRoute returns the outgoing edge of the node connected to the handle, falling back to the node's
+// default edge (see ConditionDefaultHandle) when no edge is. It returns false when the node has neither.
+func conditionRoute(wf *WorkflowDefinition, nodeID, handle string) (Edge, bool) {
+	var fallback *Edge
+	for i, edge := range wf.Edges {
+		if edge.Source != nodeID {
+			continue
+		}
+		if conditionEdgeMatches(edge, handle) {
+			return edge, true
+		}
+		if fallback == nil && edge.SourceHandle == ConditionDefaultHandle {
+			fallback = &wf.Edges[i]
+		}
+	}
+	if fallback == nil {
+		return Edge{}, false
+	}
+	return *fallback, true
+}
+
+// conditionEdgeMatches reports whether an outgoing condition edge is connected to the handle.
+// Edges without a source handle fall back to the legacy met/not met labels.
+func conditionEdgeMatches(edge Edge, handle string) bool {
+	if handle == "" {
+		return false
+	}
+	if edge.SourceHandle != "" {
+		return edge.SourceHandle == handle
+	}
+	switch handle {
+	case ConditionMetHandle:
+		return edge.Label == conditionMetLabel
+	case ConditionNotMetHandle:
+		return edge.Label == conditionNotMetLabel
+	}
+	return false
+}
+
+// conditionField returns the contextData key the condition node evaluates.
+// An explicit metadata field (e.g "weather.windspeed") takes precedence, otherwise the metric is taken
+// from the first token of the condition expression (e.g "windspeed {{operator}} {{threshold}}")
+// and defaults to the weather temperature.
+func conditionField(node Node) string {
+	if node.Data.Metadata.Field != "" {
+		return node.Data.Metadata.Field
+	}
+
+	fields := strings.Fields(node.Data.Metadata.ConditionExpr)
+	if len(fields) > 0 {
+		if _, ok := weatherMetricValues[fields[0]]; ok {
+			return weatherContextKey(fields[0])
+		}
 	}
+	return weatherContextKey(WeatherMetricTemperature)
 }
 
-// processEmailNode is suppose to send emails but this is just a placeholder as no live emails are sent.
-func processEmailNode(node Node, payload *ExecutePayload) error {
-	slog.Debug("Processing node", "node id", node.ID)
-	slog.Debug("Sending email", "email", payload.FormData.Email)
+// defaultEmailFrom is the sender address used when neither the node nor the service configure one.
+const defaultEmailFrom = "weather-alerts@example.com"
+
+// emailFromAddress resolves the email sender address from the node's template, falling back to the default.
+func emailFromAddress(node Node, defaultFrom string) (string, error) {
+	from := defaultFrom
+	if tmpl := node.Data.Metadata.EmailTemplate; tmpl != nil && tmpl.FromAddress != "" {
+		from = tmpl.FromAddress
+	}
+	if from == "" {
+		from = defaultEmailFrom
+	}
+
+	return parseEmailAddress(from)
+}
+
+// parseEmailAddress validates that the address is a well-formed email address and returns it.
+func parseEmailAddress(address string) (string, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidEmailAddress, address)
+	}
+	return parsed.Address, nil
+}
+
+// lookupMX is done so that it can be overridden in unit tests.
+var lookupMX = net.DefaultResolver.LookupMX
+
+// verifyEmailDomain checks that the email's domain has MX records and can therefore receive mail.
+func verifyEmailDomain(ctx context.Context, email string) error {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return fmt.Errorf("%w: %s", ErrInvalidEmailAddress, email)
+	}
+	domain := email[at+1:]
+
+	records, err := lookupMX(ctx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return fmt.Errorf("%w: %s", ErrEmailDomainUndeliverable, domain)
+		}
+		return fmt.Errorf("mx lookup failed for %s: %w", domain, err)
+	}
+
+	// a single "." record is a null MX, meaning the domain explicitly accepts no mail
+	if len(records) == 0 || (len(records) == 1 && records[0].Host == ".") {
+		return fmt.Errorf("%w: %s", ErrEmailDomainUndeliverable, domain)
+	}
+
+	return nil
+}
+
+// sendEmailWithRetry sends the email, retrying on failure as configured by the node retry policy.
+// It returns the number of attempts made and the last error when every attempt failed.
+func sendEmailWithRetry(ctx context.Context, node Node, sender EmailSender, msg EmailMessage) (int, error) {
+	maxAttempts, backoff := 1, time.Duration(0)
+	if retry := node.Data.Metadata.Retry; retry != nil {
+		maxAttempts = max(retry.MaxAttempts, 1)
+		backoff = time.Duration(retry.BackoffMs) * time.Millisecond
+	}
+
+	logger := loggerFromContext(ctx)
+	for attempt := 1; ; attempt++ {
+		logger.Debug("Sending email", "node id", node.ID, "email", msg.To, "attempt", attempt)
+		err := processEmailNodeFn(ctx, sender, msg)
+		if err == nil {
+			return attempt, nil
+		}
+		if attempt >= maxAttempts {
+			if attempt == 1 {
+				return attempt, err
+			}
+			return attempt, fmt.Errorf("email failed after %d attempts: %w", attempt, err)
+		}
+		logger.Debug("Email failed, retrying", "node id", node.ID, "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return attempt, fmt.Errorf("email failed after %d attempts: %w", attempt, err)
+		case <-time.After(backoff * time.Duration(attempt)):
+		}
+	}
+}
+
+// processEmailNode sends the email with the configured sender, giving up once the execution context is done.
+func processEmailNode(ctx context.Context, sender EmailSender, msg EmailMessage) error {
+	if err := sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("%w: %w", ErrEmailSendFailed, err)
+	}
+	return nil
+}
+
+// phoneNumberPattern matches an international phone number once spaces, dashes and brackets are removed.
+var phoneNumberPattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+// defaultSMSMessage is sent when the sms node doesn't configure a message template.
+const defaultSMSMessage = "Weather alert for {{city}}! Temperature is {{temperature}}°C!"
+
+// smsRecipient resolves the phone number from the node's template, falling back to the form data.
+func smsRecipient(node Node, payload *ExecutePayload) (string, error) {
+	phone := payload.FormData.Phone
+	if tmpl := node.Data.Metadata.SMSTemplate; tmpl != nil && tmpl.PhoneNumber != "" {
+		phone = tmpl.PhoneNumber
+	}
+	if phone == "" {
+		return "", ErrMissingFormFieldPhone
+	}
+
+	normalized := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	if !phoneNumberPattern.MatchString(normalized) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPhoneNumber, phone)
+	}
+	return normalized, nil
+}
+
+// smsMessageTemplate returns the configured sms message template or the default one.
+func smsMessageTemplate(node Node) string {
+	if tmpl := node.Data.Metadata.SMSTemplate; tmpl != nil && tmpl.Message != "" {
+		return tmpl.Message
+	}
+	return defaultSMSMessage
+}
+
+// processSMSNode is suppose to send an sms but this is just a placeholder as no live sms are sent.
+func processSMSNode(node Node, to string, message string) error {
+	return nil
+}
+
+// defaultSlackMessage is posted when the slack node doesn't configure a message template.
+const defaultSlackMessage = "Weather alert for {{city}}! Temperature is {{temperature}}°C!"
+
+// slackMessageTemplate returns the configured slack message template or the default one.
+func slackMessageTemplate(node Node) string {
+	if tmpl := node.Data.Metadata.SlackTemplate; tmpl != nil && tmpl.Message != "" {
+		return tmpl.Message
+	}
+	return defaultSlackMessage
+}
+
+// processSlackNode posts the message to the node's Slack incoming-webhook and returns the webhook response status.
+func processSlackNode(ctx context.Context, node Node, message string) (int, error) {
+	tmpl := node.Data.Metadata.SlackTemplate
+	if tmpl == nil || tmpl.WebhookURL == "" {
+		return 0, fmt.Errorf("slack node %s has no webhookUrl configured", node.ID)
+	}
+
+	body, err := json.Marshal(map[string]string{"text": message})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tmpl.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid slack webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClientFromContext(ctx).Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("slack webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%w: %d", ErrSlackWebhookFailed, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// maxHTTPResponseBytes caps how much of an http-request node response body is read.
+const maxHTTPResponseBytes = 1 << 20
+
+// nodeContextKey returns the node-scoped contextData key for a node value (e.g "webhook.status").
+func nodeContextKey(node Node, name string) string {
+	return node.ID + "." + name
+}
+
+// httpRequestMethod returns the configured request method, defaulting to GET.
+func httpRequestMethod(node Node) string {
+	if node.Data.Metadata.HTTPRequest == nil || node.Data.Metadata.HTTPRequest.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(node.Data.Metadata.HTTPRequest.Method)
+}
+
+// processHTTPRequestNode performs the configured outbound HTTP call and stores the response status
+// and (JSON parsed when possible) body in contextData under node-scoped keys.
+func processHTTPRequestNode(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) error {
+	cfg := node.Data.Metadata.HTTPRequest
+	if cfg == nil || cfg.URL == "" {
+		return fmt.Errorf("http-request node %s has no url configured", node.ID)
+	}
+
+	var body io.Reader
+	if cfg.Body != "" {
+		body = strings.NewReader(renderPlaceholders(cfg.Body, payload, contextData, node.Data.Metadata.MissingPlaceholders))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpRequestMethod(node), renderPlaceholders(cfg.URL, payload, contextData, node.Data.Metadata.MissingPlaceholders), body)
+	if err != nil {
+		return fmt.Errorf("invalid http request: %w", err)
+	}
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, renderPlaceholders(value, payload, contextData, node.Data.Metadata.MissingPlaceholders))
+	}
+
+	resp, err := httpClientFromContext(ctx).Do(req)
+	if err != nil {
+		return fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPResponseBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read http response: %w", err)
+	}
+
+	// store the parsed JSON body when possible, otherwise the raw body
+	var parsed any
+	if err := json.Unmarshal(respBytes, &parsed); err != nil {
+		parsed = string(respBytes)
+	}
+
+	contextData.Set(nodeContextKey(node, "status"), resp.StatusCode)
+	contextData.Set(nodeContextKey(node, "body"), parsed)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http request returned status: %d", resp.StatusCode)
+	}
+
+	// every path must resolve, so downstream nodes never read a value missing from the response
+	extracted := make(map[string]any, len(cfg.Extract))
+	for key, path := range cfg.Extract {
+		value, err := evaluateJSONPath(parsed, path)
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", key, err)
+		}
+		extracted[key] = value
+	}
+	for key, value := range extracted {
+		contextData.Set(key, value)
+	}
 
 	return nil
 }
 
-// appendStep is a helper method to add to the execution steps
-func appendStep(steps *[]StepResult, node Node, status string, output map[string]interface{}) {
-	*steps = append(*steps, StepResult{
-		NodeID:      node.ID,
-		Type:        node.Type,
-		Label:       node.Data.Label,
-		Description: node.Data.Description,
-		Status:      status,
-		Output:      output,
+// sortStepsTopologically sorts the steps by the topological order of their nodes in the graph.
+// Steps of the same node keep their execution order.
+func sortStepsTopologically(steps []StepResult, wf *WorkflowDefinition) {
+	order := topologicalOrder(wf)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return order[steps[i].NodeID] < order[steps[j].NodeID]
 	})
 }
@@ -1,7 +1,16 @@
 package workflow
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -61,6 +70,24 @@ func TestProcessNodes(t *testing.T) {
 			expectErr:   true,
 			missingNode: EndNodeID,
 		},
+		{
+			label: "error: dangling edge",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID, Type: "start", Data: NodeData{Label: "Start", Description: "Begin"}},
+					{ID: EndNodeID, Type: "end", Data: NodeData{Label: "End", Description: "Finish"}},
+				},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: EndNodeID},
+					{ID: "e2", Source: StartNodeID, Target: "ghost"},
+				},
+			},
+			payload:     &ExecutePayload{},
+			wantStatus:  StatusFailed,
+			wantStepLen: 0,
+			expectErr:   true,
+			missingNode: "e2",
+		},
 		{
 			label: "happy path: full workflow with mocked process weather-api node and email node",
 			workflow: &WorkflowDefinition{
@@ -86,6 +113,7 @@ func TestProcessNodes(t *testing.T) {
 					{Source: FormNodeID, Target: WeatherAPINodeID},
 					{Source: WeatherAPINodeID, Target: ConditionNodeID},
 					{Source: ConditionNodeID, Target: EmailNodeID, Label: "✓ Condition Met"},
+					{Source: ConditionNodeID, Target: EndNodeID, Label: "✗ No Alert Needed"},
 					{Source: EmailNodeID, Target: EndNodeID},
 				},
 			},
@@ -104,11 +132,11 @@ func TestProcessNodes(t *testing.T) {
 			wantStepLen: 6,
 			expectErr:   false,
 			setup: func() {
-				processWeatherNodeFn = func(node Node, payload *ExecutePayload, contextData map[string]any) error {
-					contextData["weather.temperature"] = 21.0
+				processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+					contextData.SetFloat("weather.temperature", 21.0)
 					return nil
 				}
-				processEmailNodeFn = func(node Node, payload *ExecutePayload) error {
+				processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error {
 					// mock email send success
 					return nil
 				}
@@ -132,7 +160,7 @@ func TestProcessNodes(t *testing.T) {
 				}
 			}()
 
-			got, err := processNodes(tt.workflow, tt.payload)
+			got, err := processNodes(context.Background(), tt.workflow, tt.payload, ExecutionOptions{})
 
 			if tt.expectErr {
 				require.Error(t, err)
@@ -145,17 +173,257 @@ func TestProcessNodes(t *testing.T) {
 				require.NotNil(t, got)
 				require.Equal(t, tt.wantStatus, got.Status)
 				require.Len(t, got.Steps, tt.wantStepLen)
+				for i, step := range got.Steps {
+					require.Equal(t, i, step.Index)
+					require.Contains(t, step.Output, "startedAt")
+					require.Contains(t, step.Output, "finishedAt")
+					require.Contains(t, step.Output, "duration")
+				}
 			}
 		})
 	}
 }
 
+// executionErrorFields returns the execution error without the node error it wraps, to compare the reported fields.
+func executionErrorFields(err *ExecutionError) *ExecutionError {
+	if err == nil {
+		return nil
+	}
+	return &ExecutionError{NodeID: err.NodeID, Code: err.Code, Message: err.Message}
+}
+
+func TestProcessNodesCustomProcessor(t *testing.T) {
+	RegisterNodeProcessor("double", NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+		contextData.Set("custom.value", 21.0*2)
+		value, _ := contextData.Get("custom.value")
+		return map[string]interface{}{"value": value}, nil
+	}))
+	defer UnregisterNodeProcessor("double")
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: "double-1", Type: "double"},
+			{ID: "double-2", Type: "double"},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: "double-1"},
+			{Source: "double-1", Target: "double-2"},
+			{Source: "double-2", Target: EndNodeID},
+		},
+	}
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Len(t, got.Steps, 4)
+	require.Equal(t, "double-1", got.Steps[1].NodeID)
+	require.Equal(t, 42.0, got.Steps[1].Output["value"])
+	require.Equal(t, "double-2", got.Steps[2].NodeID)
+}
+
+func TestProcessNodesPanicRecovery(t *testing.T) {
+	RegisterNodeProcessor("broken", NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+		v, _ := contextData.Get("weather.temperature")
+		_ = v.(float64)
+		return nil, nil
+	}))
+	defer UnregisterNodeProcessor("broken")
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: "broken-1", Type: "broken"}, {ID: EndNodeID}},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: "broken-1"},
+			{Source: "broken-1", Target: EndNodeID},
+		},
+	}
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Len(t, got.Steps, 2)
+	require.Equal(t, StatusFailed, got.Steps[1].Status)
+	require.Contains(t, got.Steps[1].Output["error"], ErrNodePanicked.Error())
+	require.Contains(t, got.Steps[1].Output["error"], "interface conversion")
+	require.Equal(t, StatusFailed, got.Status)
+	require.Equal(t, &ExecutionError{NodeID: "broken-1", Code: ErrNodePanicked.Code, Message: got.Steps[1].Output["error"].(string)}, executionErrorFields(got.Error))
+}
+
+func TestProcessNodesFailedStepFailsExecution(t *testing.T) {
+	processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error {
+		return errors.New("smtp unavailable")
+	}
+	defer func() { processEmailNodeFn = processEmailNode }()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{}}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: EmailNodeID},
+			{Source: EmailNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{Email: "jane@example.com"}}
+
+	got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, got.Status)
+	require.Len(t, got.Steps, 2)
+	require.Equal(t, StatusFailed, got.Steps[1].Status)
+	require.Equal(t, &ExecutionError{NodeID: EmailNodeID, Code: codeInternal, Message: "smtp unavailable"}, executionErrorFields(got.Error))
+}
+
+func TestProcessNodesTimeout(t *testing.T) {
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	defer func() { processWeatherNodeFn = processWeatherNode }()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: WeatherAPINodeID}, {ID: EndNodeID}},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: WeatherAPINodeID},
+			{Source: WeatherAPINodeID, Target: EndNodeID},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	got, err := processNodes(ctx, wf, &ExecutePayload{}, ExecutionOptions{})
+	require.ErrorIs(t, err, ErrExecutionTimeout)
+	require.NotNil(t, got)
+	require.Equal(t, StatusFailed, got.Status)
+	require.Len(t, got.Steps, 2)
+	require.Equal(t, WeatherAPINodeID, got.Steps[1].NodeID)
+	require.Equal(t, StatusFailed, got.Steps[1].Status)
+	require.GreaterOrEqual(t, got.DurationMs, int64(10))
+}
+
+func TestProcessNodesMaxSteps(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: EndNodeID}},
+		Edges: []Edge{{Source: StartNodeID, Target: EndNodeID}},
+	}
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{MaxSteps: 1})
+	require.ErrorIs(t, err, ErrTooManySteps)
+	require.Equal(t, StatusFailed, got.Status)
+	require.Len(t, got.Steps, 1)
+
+	got, err = processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{MaxSteps: 2})
+	require.NoError(t, err)
+	require.Len(t, got.Steps, 2)
+}
+
+func TestProcessNodesUnreachableNodes(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: FormNodeID}, {ID: EndNodeID}},
+		Edges: []Edge{{Source: FormNodeID, Target: EndNodeID}},
+	}
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Len(t, got.Steps, 1)
+	require.Len(t, got.Warnings, 1)
+	require.Contains(t, got.Warnings[0], "form, end")
+
+	got, err = processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{Strict: true})
+	require.ErrorIs(t, err, ErrUnreachableNodes)
+	require.Nil(t, got)
+}
+
+func TestProcessNodesNodeTimeout(t *testing.T) {
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	defer func() { processWeatherNodeFn = processWeatherNode }()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: WeatherAPINodeID, Data: NodeData{Metadata: NodeMetadata{TimeoutMs: 10}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: WeatherAPINodeID},
+			{Source: WeatherAPINodeID, Target: EndNodeID},
+		},
+	}
+
+	// only the node is aborted, the execution itself doesn't time out
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Len(t, got.Steps, 2)
+	require.Equal(t, StatusFailed, got.Steps[1].Status)
+	require.Contains(t, got.Steps[1].Output["error"], ErrNodeTimeout.Error()+" after 10ms")
+}
+
+func TestProcessNodesDryRun(t *testing.T) {
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		t.Fatal("weather node must not be called in a dry run")
+		return nil
+	}
+	processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error {
+		t.Fatal("email node must not be called in a dry run")
+		return nil
+	}
+	defer func() {
+		processWeatherNodeFn = processWeatherNode
+		processEmailNodeFn = processEmailNode
+	}()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: FormNodeID},
+			{ID: WeatherAPINodeID},
+			{ID: ConditionNodeID},
+			{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{}}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: FormNodeID},
+			{Source: FormNodeID, Target: WeatherAPINodeID},
+			{Source: WeatherAPINodeID, Target: ConditionNodeID},
+			{Source: ConditionNodeID, Target: EmailNodeID, SourceHandle: ConditionMetHandle},
+			{Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+			{Source: EmailNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{
+		FormData:  FormData{Name: "Jane", Email: "jane@example.com", City: "Melbourne"},
+		Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+	}
+
+	got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+
+	statuses := map[string]string{}
+	for _, step := range got.Steps {
+		statuses[step.NodeID] = step.Status
+	}
+	require.Equal(t, map[string]string{
+		StartNodeID:      StatusCompleted,
+		FormNodeID:       StatusCompleted,
+		WeatherAPINodeID: StatusSkipped,
+		ConditionNodeID:  StatusSkipped,
+		EmailNodeID:      StatusSkipped,
+		EndNodeID:        StatusCompleted,
+	}, statuses)
+}
+
 func TestProcessConditionNode(t *testing.T) {
 	tests := []struct {
 		label       string
 		node        Node
 		payload     *ExecutePayload
-		contextData map[string]any
+		contextData *Context
 		wantResult  bool
 		expectErr   bool
 		errContains string
@@ -168,7 +436,18 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 10,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
+			wantResult:  true,
+		},
+		{
+			label: "greater_than true from the form data",
+			payload: &ExecutePayload{
+				FormData: FormData{
+					Operator:  "greater_than",
+					Threshold: 10,
+				},
+			},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  true,
 		},
 		{
@@ -179,7 +458,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 20,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  false,
 		},
 		{
@@ -190,7 +469,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 20,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  true,
 		},
 		{
@@ -201,7 +480,31 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 10,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
+			wantResult:  false,
+		},
+		{
+			label: "between true (inclusive bound)",
+			payload: &ExecutePayload{
+				Condition: Condition{
+					Operator:       "between",
+					Threshold:      15.5,
+					UpperThreshold: 24,
+				},
+			},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
+			wantResult:  true,
+		},
+		{
+			label: "between false",
+			payload: &ExecutePayload{
+				Condition: Condition{
+					Operator:       "between",
+					Threshold:      18,
+					UpperThreshold: 24,
+				},
+			},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  false,
 		},
 		{
@@ -212,7 +515,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 15.5,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  true,
 		},
 		{
@@ -223,7 +526,42 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 10,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
+			wantResult:  false,
+		},
+		{
+			label: "equals true (near-equal float)",
+			payload: &ExecutePayload{
+				Condition: Condition{
+					Operator:  "equals",
+					Threshold: 21.0,
+				},
+			},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 21.00000001}),
+			wantResult:  true,
+		},
+		{
+			label: "equals true (within custom tolerance)",
+			payload: &ExecutePayload{
+				Condition: Condition{
+					Operator:  "equals",
+					Threshold: 21,
+					Tolerance: ptr(0.5),
+				},
+			},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 21.4}),
+			wantResult:  true,
+		},
+		{
+			label: "equals false (zero tolerance is exact)",
+			payload: &ExecutePayload{
+				Condition: Condition{
+					Operator:  "equals",
+					Threshold: 21.0,
+					Tolerance: ptr(0.0),
+				},
+			},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 21.00000001}),
 			wantResult:  false,
 		},
 		{
@@ -234,7 +572,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 15.5,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  true,
 		},
 		{
@@ -245,7 +583,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 10,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  true,
 		},
 		{
@@ -256,7 +594,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 20,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  false,
 		},
 		{
@@ -267,7 +605,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 15.5,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  true,
 		},
 		{
@@ -278,7 +616,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 20,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			wantResult:  true,
 		},
 		{
@@ -289,22 +627,84 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 10,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
+			wantResult:  false,
+		},
+		{
+			label: "greater_than true on windspeed from condition expression",
+			node: Node{Data: NodeData{Metadata: NodeMetadata{
+				ConditionExpr: "windspeed {{operator}} {{threshold}}",
+			}}},
+			payload: &ExecutePayload{
+				Condition: Condition{
+					Operator:  "greater_than",
+					Threshold: 30,
+				},
+			},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5, "weather.windspeed": 42.0}),
+			wantResult:  true,
+		},
+		{
+			label: "comparison expression with a function",
+			node: Node{Data: NodeData{Metadata: NodeMetadata{
+				ConditionExpr: "abs(temperature - 20) < 5",
+			}}},
+			payload:     &ExecutePayload{Condition: Condition{Operator: "greater_than", Threshold: 100}},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 17.0}),
+			wantResult:  true,
+		},
+		{
+			label: "comparison expression on several fields",
+			node: Node{Data: NodeData{Metadata: NodeMetadata{
+				ConditionExpr: "max(temperature, feels_like) > 30",
+			}}},
+			payload:     &ExecutePayload{Condition: Condition{Operator: "greater_than", Threshold: 0}},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 28.0, "feels_like": 29.5}),
 			wantResult:  false,
 		},
+		{
+			label: "error: comparison expression with an unknown function",
+			node: Node{Data: NodeData{Metadata: NodeMetadata{
+				ConditionExpr: "median(temperature) > 30",
+			}}},
+			payload:     &ExecutePayload{Condition: Condition{Operator: "greater_than"}},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 28.0}),
+			expectErr:   true,
+			errContains: "unknown function median",
+		},
+		{
+			label: "less_than true on custom field",
+			node:  Node{Data: NodeData{Metadata: NodeMetadata{Field: "custom.score"}}},
+			payload: &ExecutePayload{
+				Condition: Condition{
+					Operator:  "less_than",
+					Threshold: 5,
+				},
+			},
+			contextData: newContextFrom(map[string]any{"custom.score": 3.0}),
+			wantResult:  true,
+		},
+		{
+			label:       "error: missing custom field",
+			node:        Node{Data: NodeData{Metadata: NodeMetadata{Field: "custom.score"}}},
+			payload:     &ExecutePayload{Condition: Condition{Operator: "less_than"}},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
+			expectErr:   true,
+			errContains: "condition field custom.score not in context data",
+		},
 		{
 			label:       "error: missing temperature",
 			payload:     &ExecutePayload{},
-			contextData: map[string]any{},
+			contextData: NewContext(),
 			expectErr:   true,
-			errContains: "weather temp not in map",
+			errContains: "condition field weather.temperature not in context data",
 		},
 		{
 			label:       "error: temperature wrong type",
 			payload:     &ExecutePayload{},
-			contextData: map[string]any{"weather.temperature": "not a float"},
+			contextData: newContextFrom(map[string]any{"weather.temperature": "not a float"}),
 			expectErr:   true,
-			errContains: "weather temp is not a float64",
+			errContains: "condition field weather.temperature is not numeric",
 		},
 		{
 			label: "error: unsupported operator",
@@ -314,7 +714,7 @@ func TestProcessConditionNode(t *testing.T) {
 					Threshold: 10,
 				},
 			},
-			contextData: map[string]any{"weather.temperature": 15.5},
+			contextData: newContextFrom(map[string]any{"weather.temperature": 15.5}),
 			expectErr:   true,
 			errContains: "unsupported operator",
 		},
@@ -329,18 +729,185 @@ func TestProcessConditionNode(t *testing.T) {
 				require.Contains(t, err.Error(), tt.errContains)
 			} else {
 				require.NoError(t, err)
-				require.Equal(t, tt.wantResult, got)
+				require.Equal(t, tt.wantResult, got == ConditionMetHandle)
 			}
 		})
 	}
 }
 
-func TestProcessFormNode(t *testing.T) {
+func TestProcessConditionStepMissingValue(t *testing.T) {
+	payload := &ExecutePayload{Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20}}
+
+	for _, contextData := range []map[string]any{{}, {"weather.temperature": nil}, {"weather.temperature": "warm"}} {
+		require.NotPanics(t, func() {
+			_, err := processConditionStep(context.Background(), Node{ID: ConditionNodeID}, payload, newContextFrom(contextData))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestProcessConditionNodePayloadValue(t *testing.T) {
+	value := 30.0
+	node := Node{ID: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{ValueSource: ConditionValueSourcePayload}}}
+	payload := &ExecutePayload{Condition: Condition{Operator: OperatorGreaterThan, Threshold: 25, Value: &value}}
+	contextData := NewContext()
+
+	got, err := processConditionNode(node, payload, contextData)
+	require.NoError(t, err)
+	require.Equal(t, ConditionMetHandle, got)
+	require.Empty(t, contextData.Snapshot())
+
+	output, err := processConditionStep(context.Background(), node, payload, contextData)
+	require.NoError(t, err)
+	require.Equal(t, 30.0, output["actualValue"])
+
+	// without the flag the value still comes from the context data
+	node.Data.Metadata.ValueSource = ""
+	_, err = processConditionNode(node, payload, contextData)
+	require.Error(t, err)
+}
+
+func TestProcessConditionNodeClauses(t *testing.T) {
+	node := Node{ID: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{
+		Conditions: []ConditionClause{
+			{Operator: OperatorLessThan, Threshold: 10, SourceHandle: "cold"},
+			{Operator: OperatorLessThan, Threshold: 25, SourceHandle: "mild"},
+			{Operator: OperatorGreaterThanOrEqual, Threshold: 25, SourceHandle: "hot"},
+		},
+	}}}
+
 	tests := []struct {
 		label       string
-		payload     *ExecutePayload
-		expectErr   bool
-		errExpected error
+		temperature float64
+		wantHandle  string
+	}{
+		{label: "first clause matches", temperature: 5, wantHandle: "cold"},
+		{label: "second clause matches", temperature: 18, wantHandle: "mild"},
+		{label: "last clause matches", temperature: 31, wantHandle: "hot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := processConditionNode(node, &ExecutePayload{}, newContextFrom(map[string]any{"weather.temperature": tt.temperature}))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantHandle, got)
+		})
+	}
+
+	t.Run("routes to the matched handle", func(t *testing.T) {
+		processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+			contextData.SetFloat("weather.temperature", 18.0)
+			return nil
+		}
+		defer func() { processWeatherNodeFn = processWeatherNode }()
+
+		wf := &WorkflowDefinition{
+			Nodes: []Node{
+				{ID: StartNodeID},
+				{ID: WeatherAPINodeID},
+				node,
+				{ID: "cold-alert"},
+				{ID: "mild-alert"},
+				{ID: "hot-alert"},
+				{ID: EndNodeID},
+			},
+			Edges: []Edge{
+				{Source: StartNodeID, Target: WeatherAPINodeID},
+				{Source: WeatherAPINodeID, Target: ConditionNodeID},
+				{Source: ConditionNodeID, Target: "cold-alert", SourceHandle: "cold"},
+				{Source: ConditionNodeID, Target: "mild-alert", SourceHandle: "mild"},
+				{Source: ConditionNodeID, Target: "hot-alert", SourceHandle: "hot"},
+				{Source: "mild-alert", Target: EndNodeID},
+			},
+		}
+
+		got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+		require.NoError(t, err)
+		require.Equal(t, StatusCompleted, got.Status)
+
+		var nodeIDs []string
+		for _, step := range got.Steps {
+			nodeIDs = append(nodeIDs, step.NodeID)
+		}
+		require.Equal(t, []string{StartNodeID, WeatherAPINodeID, ConditionNodeID, "mild-alert", EndNodeID}, nodeIDs)
+		require.Equal(t, "mild", got.Steps[2].Output["sourceHandle"])
+		require.Equal(t, StatusSkipped, got.Steps[3].Status)
+	})
+
+	t.Run("routes to the default edge when no clause matches", func(t *testing.T) {
+		processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+			contextData.SetFloat("weather.temperature", 18.0)
+			return nil
+		}
+		defer func() { processWeatherNodeFn = processWeatherNode }()
+
+		coldOnly := node
+		coldOnly.Data.Metadata.Conditions = node.Data.Metadata.Conditions[:1]
+		wf := &WorkflowDefinition{
+			Nodes: []Node{
+				{ID: StartNodeID},
+				{ID: WeatherAPINodeID},
+				coldOnly,
+				{ID: "cold-alert"},
+				{ID: EndNodeID},
+			},
+			Edges: []Edge{
+				{Source: StartNodeID, Target: WeatherAPINodeID},
+				{Source: WeatherAPINodeID, Target: ConditionNodeID},
+				{Source: ConditionNodeID, Target: "cold-alert", SourceHandle: "cold"},
+				{Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionDefaultHandle},
+			},
+		}
+
+		got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+		require.NoError(t, err)
+		require.Equal(t, StatusCompleted, got.Status)
+
+		var nodeIDs []string
+		for _, step := range got.Steps {
+			nodeIDs = append(nodeIDs, step.NodeID)
+		}
+		require.Equal(t, []string{StartNodeID, WeatherAPINodeID, ConditionNodeID, EndNodeID}, nodeIDs)
+	})
+}
+
+func TestConditionRoute(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Edges: []Edge{
+			{ID: "e1", Source: ConditionNodeID, Target: "alert", SourceHandle: ConditionMetHandle},
+			{ID: "e2", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionDefaultHandle},
+			{ID: "e3", Source: "other", Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+		},
+	}
+
+	tests := []struct {
+		label    string
+		nodeID   string
+		handle   string
+		wantEdge string
+		wantOK   bool
+	}{
+		{label: "matching edge", nodeID: ConditionNodeID, handle: ConditionMetHandle, wantEdge: "e1", wantOK: true},
+		{label: "default edge", nodeID: ConditionNodeID, handle: ConditionNotMetHandle, wantEdge: "e2", wantOK: true},
+		{label: "no clause matched", nodeID: ConditionNodeID, handle: "", wantEdge: "e2", wantOK: true},
+		{label: "no default edge", nodeID: "other", handle: ConditionMetHandle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, ok := conditionRoute(wf, tt.nodeID, tt.handle)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.wantEdge, got.ID)
+		})
+	}
+}
+
+func TestProcessFormNode(t *testing.T) {
+	tests := []struct {
+		label        string
+		payload      *ExecutePayload
+		expectErr    bool
+		errsExpected []error
 	}{
 		{
 			label: "success: all fields present",
@@ -361,8 +928,8 @@ func TestProcessFormNode(t *testing.T) {
 					City:  "Sydney",
 				},
 			},
-			expectErr:   true,
-			errExpected: ErrMissingFormFieldName,
+			expectErr:    true,
+			errsExpected: []error{ErrMissingFormFieldName},
 		},
 		{
 			label: "error: missing email",
@@ -372,8 +939,8 @@ func TestProcessFormNode(t *testing.T) {
 					City: "Sydney",
 				},
 			},
-			expectErr:   true,
-			errExpected: ErrMissingFormFieldEmail,
+			expectErr:    true,
+			errsExpected: []error{ErrMissingFormFieldEmail},
 		},
 		{
 			label: "error: missing city",
@@ -383,8 +950,26 @@ func TestProcessFormNode(t *testing.T) {
 					Email: "alice@example.com",
 				},
 			},
-			expectErr:   true,
-			errExpected: ErrMissingFormFieldCity,
+			expectErr:    true,
+			errsExpected: []error{ErrMissingFormFieldCity},
+		},
+		{
+			label: "error: invalid email",
+			payload: &ExecutePayload{
+				FormData: FormData{
+					Name:  "Alice",
+					Email: "not-an-email",
+					City:  "Sydney",
+				},
+			},
+			expectErr:    true,
+			errsExpected: []error{ErrInvalidEmailAddress},
+		},
+		{
+			label:        "error: every field missing is reported",
+			payload:      &ExecutePayload{},
+			expectErr:    true,
+			errsExpected: []error{ErrMissingFormFieldName, ErrMissingFormFieldEmail, ErrMissingFormFieldCity},
 		},
 	}
 
@@ -393,7 +978,12 @@ func TestProcessFormNode(t *testing.T) {
 			err := processFormNode(Node{ID: FormNodeID}, tt.payload)
 			if tt.expectErr {
 				require.Error(t, err)
-				require.Equal(t, tt.errExpected, err)
+				var validationErrs ValidationErrors
+				require.ErrorAs(t, err, &validationErrs)
+				require.Len(t, validationErrs, len(tt.errsExpected))
+				for _, expected := range tt.errsExpected {
+					require.ErrorIs(t, err, expected)
+				}
 			} else {
 				require.NoError(t, err)
 			}
@@ -401,4 +991,1059 @@ func TestProcessFormNode(t *testing.T) {
 	}
 }
 
+func TestProcessFormNodeInputFields(t *testing.T) {
+	tests := []struct {
+		label       string
+		inputFields []string
+		formData    FormData
+		wantFields  []string
+	}{
+		{
+			label:       "success: custom fields present",
+			inputFields: []string{"email", "company"},
+			formData:    FormData{Email: "alice@example.com", Fields: map[string]any{"company": "Acme"}},
+		},
+		{
+			label:       "success: name and city aren't required",
+			inputFields: []string{"phone"},
+			formData:    FormData{Phone: "+61 400 000 000"},
+		},
+		{
+			label:       "success: coordinates replace the city",
+			inputFields: []string{"city"},
+			formData:    FormData{Lat: ptr(-33.87), Lon: ptr(151.21)},
+		},
+		{
+			label:       "error: missing fields",
+			inputFields: []string{"name", "company", "age"},
+			formData:    FormData{Fields: map[string]any{"company": ""}},
+			wantFields:  []string{"formData.name", "formData.fields.company", "formData.fields.age"},
+		},
+		{
+			label:       "error: invalid email even when not required",
+			inputFields: []string{"name"},
+			formData:    FormData{Name: "Alice", Email: "not-an-email"},
+			wantFields:  []string{"formData.email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			node := Node{ID: FormNodeID, Data: NodeData{Metadata: NodeMetadata{InputFields: tt.inputFields}}}
+			err := processFormNode(node, &ExecutePayload{FormData: tt.formData})
+			if len(tt.wantFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErrs ValidationErrors
+			require.ErrorAs(t, err, &validationErrs)
+			var fields []string
+			for _, fieldErr := range validationErrs {
+				fields = append(fields, fieldErr.Field)
+			}
+			require.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
+
+func TestProcessHTTPRequestNode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/alerts/Sydney", r.URL.Path)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, `{"city":"Sydney","temperature":"21.5"}`, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"accepted":true}`))
+	}))
+	defer server.Close()
+
+	node := Node{ID: "webhook", Data: NodeData{Metadata: NodeMetadata{
+		HTTPRequest: &HTTPRequest{
+			Method:  "post",
+			URL:     server.URL + "/alerts/{{city}}",
+			Headers: map[string]string{"Authorization": "Bearer token"},
+			Body:    `{"city":"{{city}}","temperature":"{{temperature}}"}`,
+		},
+	}}}
+	payload := &ExecutePayload{FormData: FormData{City: "Sydney"}}
+	contextData := newContextFrom(map[string]any{"weather.temperature": 21.5})
+
+	err := processHTTPRequestNode(context.Background(), node, payload, contextData)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, contextData.Snapshot()["webhook.status"])
+	require.Equal(t, map[string]any{"accepted": true}, contextData.Snapshot()["webhook.body"])
+}
+
+func TestProcessHTTPRequestNodeExtract(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"price":42.5,"tags":["new","sale"]}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		label       string
+		extract     map[string]string
+		want        map[string]any
+		errExpected error
+	}{
+		{
+			label:   "stores the values under the given keys",
+			extract: map[string]string{"price": "$.data.price", "tag": "$.data.tags[-1]"},
+			want:    map[string]any{"price": 42.5, "tag": "sale"},
+		},
+		{
+			label:       "error: path doesn't resolve",
+			extract:     map[string]string{"stock": "$.data.stock"},
+			errExpected: ErrJSONPathNotFound,
+		},
+		{
+			label:       "error: invalid path",
+			extract:     map[string]string{"price": "data.price"},
+			errExpected: ErrInvalidJSONPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			node := Node{ID: "pricing", Data: NodeData{Metadata: NodeMetadata{
+				HTTPRequest: &HTTPRequest{URL: server.URL, Extract: tt.extract},
+			}}}
+			contextData := NewContext()
+
+			err := processHTTPRequestNode(context.Background(), node, &ExecutePayload{}, contextData)
+			if tt.errExpected != nil {
+				require.ErrorIs(t, err, tt.errExpected)
+				return
+			}
+			require.NoError(t, err)
+			for key, value := range tt.want {
+				require.Equal(t, value, contextData.Snapshot()[key])
+			}
+		})
+	}
+}
+
+func TestProcessSlackNode(t *testing.T) {
+	tests := []struct {
+		label       string
+		status      int
+		expectErr   bool
+		errExpected string
+	}{
+		{label: "posted", status: http.StatusOK},
+		{label: "error: webhook rejected the message", status: http.StatusNotFound, expectErr: true, errExpected: "slack webhook returned an unexpected status: 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				require.JSONEq(t, `{"text":"Storm in Sydney"}`, string(body))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			node := Node{ID: SlackNodeID, Data: NodeData{Metadata: NodeMetadata{
+				SlackTemplate: &SlackTemplate{WebhookURL: server.URL},
+			}}}
+
+			status, err := processSlackNode(context.Background(), node, "Storm in Sydney")
+			require.Equal(t, tt.status, status)
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrSlackWebhookFailed)
+				require.EqualError(t, err, tt.errExpected)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestProcessNodesSlackMessage(t *testing.T) {
+	var posted string
+	processSlackNodeFn = func(ctx context.Context, node Node, message string) (int, error) {
+		posted = message
+		return http.StatusOK, nil
+	}
+	defer func() { processSlackNodeFn = processSlackNode }()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: SlackNodeID, Data: NodeData{Metadata: NodeMetadata{
+				SlackTemplate: &SlackTemplate{WebhookURL: "https://hooks.slack.com/services/T000/B000/XXX", Message: "{{city}} is {{temperature}}°C"},
+			}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: SlackNodeID},
+			{Source: SlackNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{City: "Sydney"}}
+
+	got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Equal(t, "Sydney is {{temperature}}°C", posted)
+	slack, err := got.Steps[1].SlackOutput()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, slack.WebhookStatus)
+	require.Equal(t, posted, slack.Slack.Message)
+	require.NotContains(t, fmt.Sprint(got.Steps[1].Output), "hooks.slack.com")
+}
+
+func TestEmailFromAddress(t *testing.T) {
+	tests := []struct {
+		label       string
+		template    *EmailTemplate
+		defaultFrom string
+		want        string
+		expectErr   bool
+	}{
+		{label: "node address takes precedence", template: &EmailTemplate{FromAddress: "alerts@brand.com"}, defaultFrom: "ops@example.com", want: "alerts@brand.com"},
+		{label: "service default", template: &EmailTemplate{}, defaultFrom: "ops@example.com", want: "ops@example.com"},
+		{label: "package default", template: &EmailTemplate{}, want: defaultEmailFrom},
+		{label: "error: malformed address", template: &EmailTemplate{FromAddress: "not-an-email"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			node := Node{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: tt.template}}}
+			got, err := emailFromAddress(node, tt.defaultFrom)
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrInvalidEmailAddress)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSMSRecipient(t *testing.T) {
+	tests := []struct {
+		label       string
+		template    *SMSTemplate
+		phone       string
+		want        string
+		errExpected error
+	}{
+		{label: "form data phone", phone: "+61 400 123-456", want: "+61400123456"},
+		{label: "metadata phone takes precedence", template: &SMSTemplate{PhoneNumber: "+15550001111"}, phone: "+61400123456", want: "+15550001111"},
+		{label: "error: missing phone", errExpected: ErrMissingFormFieldPhone},
+		{label: "error: invalid phone", phone: "call me", errExpected: ErrInvalidPhoneNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			node := Node{ID: SMSNodeID, Data: NodeData{Metadata: NodeMetadata{SMSTemplate: tt.template}}}
+			got, err := smsRecipient(node, &ExecutePayload{FormData: FormData{Phone: tt.phone}})
+			if tt.errExpected != nil {
+				require.ErrorIs(t, err, tt.errExpected)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVerifyEmailDomain(t *testing.T) {
+	defer func() { lookupMX = net.DefaultResolver.LookupMX }()
+
+	tests := []struct {
+		label       string
+		email       string
+		records     []*net.MX
+		lookupErr   error
+		errExpected error
+	}{
+		{label: "domain with mx records", email: "jane@example.com", records: []*net.MX{{Host: "mx.example.com.", Pref: 10}}},
+		{label: "error: no such domain", email: "jane@exmaple.con", lookupErr: &net.DNSError{IsNotFound: true}, errExpected: ErrEmailDomainUndeliverable},
+		{label: "error: null mx", email: "jane@example.com", records: []*net.MX{{Host: "."}}, errExpected: ErrEmailDomainUndeliverable},
+		{label: "error: malformed email", email: "jane", errExpected: ErrInvalidEmailAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			lookupMX = func(ctx context.Context, name string) ([]*net.MX, error) {
+				return tt.records, tt.lookupErr
+			}
+
+			err := verifyEmailDomain(context.Background(), tt.email)
+			if tt.errExpected != nil {
+				require.ErrorIs(t, err, tt.errExpected)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProcessWeatherNode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/search":
+			require.Equal(t, "Sydney", r.URL.Query().Get("name"))
+			w.Write([]byte(`{"results":[{"latitude":-33.87,"longitude":151.21}]}`))
+		case "/v1/forecast":
+			require.Equal(t, "-33.870000", r.URL.Query().Get("latitude"))
+			w.Write([]byte(`{"current_weather":{"temperature":21.5,"windspeed":12.3}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	node := Node{ID: WeatherAPINodeID, Data: NodeData{Metadata: NodeMetadata{
+		APIEndpoint: "https://api.open-meteo.com/v1/forecast?latitude={lat}&longitude={lon}&current_weather=true",
+		Metrics:     []string{WeatherMetricTemperature, WeatherMetricWindSpeed},
+	}}}
+	weatherAPI := WeatherAPIConfig{GeocodingBaseURL: server.URL, WeatherBaseURL: server.URL}
+	contextData := NewContext()
+
+	err := processWeatherNode(context.Background(), node, &ExecutePayload{FormData: FormData{City: "Sydney"}}, contextData, weatherAPI)
+	require.NoError(t, err)
+	require.Equal(t, 21.5, contextData.Snapshot()["weather.temperature"])
+	require.Equal(t, 12.3, contextData.Snapshot()["weather.windspeed"])
+	require.Equal(t, server.URL+"/v1/search?count=1&name=Sydney", contextData.Snapshot()["weather-api.geocodingUrl"])
+	require.Contains(t, contextData.Snapshot()["weather-api.weatherUrl"], server.URL+"/v1/forecast?")
+}
+
+func TestProcessWeatherNodeGeocodingStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	contextData := NewContext()
+	err := processWeatherNode(context.Background(), Node{ID: WeatherAPINodeID}, &ExecutePayload{FormData: FormData{City: "Sydney"}}, contextData, WeatherAPIConfig{GeocodingBaseURL: server.URL})
+	require.ErrorIs(t, err, ErrGeocodingRequestFailed)
+	require.Contains(t, err.Error(), "429")
+	require.Equal(t, server.URL+"/v1/search?count=1&name=Sydney", contextData.Snapshot()["weather-api.geocodingUrl"])
+}
+
+func TestProcessTransformNode(t *testing.T) {
+	contextData := newContextFrom(map[string]any{"weather.temperature": 20.0, "weather.windspeed": 10.0})
+
+	node := Node{ID: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "feels_like = temperature - windspeed * 0.5"}}}
+	key, value, err := processTransformNode(node, contextData)
+	require.NoError(t, err)
+	require.Equal(t, "feels_like", key)
+	require.Equal(t, 15.0, value)
+	require.Equal(t, 15.0, contextData.Snapshot()["feels_like"])
+
+	// the derived value can be used by a downstream condition
+	condition := Node{ID: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{Field: "feels_like"}}}
+	handle, err := processConditionNode(condition, &ExecutePayload{Condition: Condition{Operator: OperatorLessThan, Threshold: 16}}, contextData)
+	require.NoError(t, err)
+	require.Equal(t, ConditionMetHandle, handle)
+
+	node = Node{ID: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "temperature * 2", OutputKey: "double"}}}
+	key, value, err = processTransformNode(node, contextData)
+	require.NoError(t, err)
+	require.Equal(t, "double", key)
+	require.Equal(t, 40.0, value)
+
+	node = Node{ID: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "temperature * 2"}}}
+	_, _, err = processTransformNode(node, contextData)
+	require.Error(t, err)
+}
+
+func TestProcessLoopNodeOptionCoordinates(t *testing.T) {
+	var geocoded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/search" {
+			geocoded = append(geocoded, r.URL.Query().Get("name"))
+			w.Write([]byte(`{"results":[{"latitude":-37.81,"longitude":144.96}]}`))
+			return
+		}
+		temperatures := map[string]string{"-33.870000": "21.5", "-37.810000": "15", "0.000000": "27"}
+		w.Write([]byte(`{"current_weather":{"temperature":` + temperatures[r.URL.Query().Get("latitude")] + `}}`))
+	}))
+	defer server.Close()
+
+	node := Node{ID: LoopNodeID, Data: NodeData{Metadata: NodeMetadata{
+		APIEndpoint: server.URL + "/v1/forecast?latitude={lat}&longitude={lon}&current_weather=true",
+		Options: []CityCoordinates{
+			{City: "Sydney", Lat: ptr(-33.87), Lon: ptr(151.21)},
+			{City: "Melbourne"},
+			{City: "Null Island", Lat: ptr(0.0), Lon: ptr(0.0)},
+		},
+	}}}
+
+	iterations, err := processLoopNode(context.Background(), node, &ExecutePayload{}, NewContext(), WeatherAPIConfig{GeocodingBaseURL: server.URL})
+	require.NoError(t, err)
+	require.Equal(t, 21.5, *iterations[0].Temperature)
+	require.Equal(t, 15.0, *iterations[1].Temperature)
+	require.Equal(t, 27.0, *iterations[2].Temperature)
+	// only the option without coordinates is geocoded
+	require.Equal(t, []string{"Melbourne"}, geocoded)
+}
+
+func TestProcessLoopNode(t *testing.T) {
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		temperatures := map[string]float64{"Sydney": 21.5, "Melbourne": 15}
+		temperature, ok := temperatures[payload.FormData.City]
+		if !ok {
+			return fmt.Errorf("no results found for city: %s", payload.FormData.City)
+		}
+		contextData.SetFloat(weatherContextKey(WeatherMetricTemperature), temperature)
+		return nil
+	}
+	defer func() { processWeatherNodeFn = processWeatherNode }()
+
+	payload := &ExecutePayload{FormData: FormData{City: "Brisbane"}}
+
+	t.Run("collects a result per option", func(t *testing.T) {
+		node := Node{ID: LoopNodeID, Data: NodeData{Metadata: NodeMetadata{Options: []CityCoordinates{
+			{City: "Sydney"},
+			{City: "Melbourne"},
+		}}}}
+		contextData := NewContext()
+
+		iterations, err := processLoopNode(context.Background(), node, payload, contextData, WeatherAPIConfig{})
+		require.NoError(t, err)
+		require.Len(t, iterations, 2)
+		require.Equal(t, "Sydney", iterations[0].Location)
+		require.Equal(t, 21.5, *iterations[0].Temperature)
+		require.Equal(t, StatusCompleted, iterations[1].Status)
+		require.Equal(t, []any{21.5, 15.0}, contextData.Snapshot()["loop.temperature"])
+		require.Equal(t, "Brisbane", payload.FormData.City)
+	})
+
+	t.Run("failed iteration doesn't stop the loop", func(t *testing.T) {
+		node := Node{ID: LoopNodeID, Data: NodeData{Metadata: NodeMetadata{Options: []CityCoordinates{
+			{City: "Atlantis"},
+			{City: "Sydney"},
+		}}}}
+
+		iterations, err := processLoopNode(context.Background(), node, payload, NewContext(), WeatherAPIConfig{})
+		require.Error(t, err)
+		require.Len(t, iterations, 2)
+		require.Equal(t, StatusFailed, iterations[0].Status)
+		require.Equal(t, StatusCompleted, iterations[1].Status)
+	})
+
+	t.Run("error: no options", func(t *testing.T) {
+		_, err := processLoopNode(context.Background(), Node{ID: LoopNodeID}, payload, NewContext(), WeatherAPIConfig{})
+		require.Error(t, err)
+	})
+}
+
+func TestProcessNodesConditionTraversesOnlyMatchedBranch(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: WeatherAPINodeID},
+			{ID: ConditionNodeID},
+			{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{}}}},
+			{ID: "not-met-transform", Type: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "temperature * 2", OutputKey: "doubled"}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{ID: "e1", Source: StartNodeID, Target: WeatherAPINodeID},
+			{ID: "e2", Source: WeatherAPINodeID, Target: ConditionNodeID},
+			{ID: "e3", Source: ConditionNodeID, Target: EmailNodeID, SourceHandle: ConditionMetHandle},
+			{ID: "e4", Source: ConditionNodeID, Target: "not-met-transform", SourceHandle: ConditionNotMetHandle},
+			{ID: "e5", Source: EmailNodeID, Target: EndNodeID},
+			{ID: "e6", Source: "not-met-transform", Target: EndNodeID},
+		},
+	}
+
+	tests := []struct {
+		label       string
+		temperature float64
+		wantNodeIDs []string
+		wantEmails  int
+	}{
+		{
+			label:       "condition met: only the email branch runs",
+			temperature: 30,
+			wantNodeIDs: []string{StartNodeID, WeatherAPINodeID, ConditionNodeID, EmailNodeID, EndNodeID},
+			wantEmails:  1,
+		},
+		{
+			label:       "condition not met: only the transform branch runs",
+			temperature: 10,
+			wantNodeIDs: []string{StartNodeID, WeatherAPINodeID, ConditionNodeID, "not-met-transform", EndNodeID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			emails := 0
+			processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+				contextData.SetFloat("weather.temperature", tt.temperature)
+				return nil
+			}
+			processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error {
+				emails++
+				return nil
+			}
+			defer func() {
+				processWeatherNodeFn = processWeatherNode
+				processEmailNodeFn = processEmailNode
+			}()
+
+			payload := &ExecutePayload{Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20}}
+			got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{})
+			require.NoError(t, err)
+			require.Equal(t, StatusCompleted, got.Status)
+
+			var nodeIDs []string
+			for _, step := range got.Steps {
+				nodeIDs = append(nodeIDs, step.NodeID)
+			}
+			require.Equal(t, tt.wantNodeIDs, nodeIDs)
+			require.Equal(t, tt.wantEmails, emails)
+		})
+	}
+}
+
+func TestProcessMetricsNode(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: FormNodeID},
+			{ID: MetricsNodeID},
+			{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{
+				Body: "{{metrics.stepCount}} steps run",
+			}}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: FormNodeID},
+			{Source: FormNodeID, Target: MetricsNodeID},
+			{Source: MetricsNodeID, Target: EmailNodeID},
+			{Source: EmailNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{Name: "Jane", Email: "jane@example.com", City: "Sydney"}}
+
+	processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error { return nil }
+	defer func() { processEmailNodeFn = processEmailNode }()
+
+	got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Len(t, got.Steps, 5)
+
+	metrics, err := got.Steps[2].MetricsOutput()
+	require.NoError(t, err)
+	require.Equal(t, 2, metrics.StepCount)
+	require.Equal(t, 0, metrics.FailedSteps)
+	require.NotEmpty(t, metrics.ExecutionStartedAt)
+
+	draft := got.Steps[3].Output["emailDraft"].(map[string]interface{})
+	require.Equal(t, "2 steps run", draft["body"])
+}
+
+func TestProcessNodesIncludeContext(t *testing.T) {
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		contextData.SetFloat("weather.temperature", 21.0)
+		return nil
+	}
+	defer func() { processWeatherNodeFn = processWeatherNode }()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: WeatherAPINodeID}, {ID: EndNodeID}},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: WeatherAPINodeID},
+			{Source: WeatherAPINodeID, Target: EndNodeID},
+		},
+	}
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Nil(t, got.Context)
+
+	got, err = processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{IncludeContext: true})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"weather.temperature": 21.0}, got.Context)
+}
+
+func TestProcessNodesEmailRetry(t *testing.T) {
+	tests := []struct {
+		label            string
+		failures         int
+		expectStatus     string
+		expectAttempts   int
+		expectDeadLetter bool
+	}{
+		{
+			label:          "succeeds on a retry",
+			failures:       2,
+			expectStatus:   StatusCompleted,
+			expectAttempts: 3,
+		},
+		{
+			label:            "dead-lettered after the last attempt",
+			failures:         5,
+			expectStatus:     StatusFailed,
+			expectAttempts:   3,
+			expectDeadLetter: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			calls := 0
+			processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error {
+				calls++
+				if calls <= test.failures {
+					return errors.New("smtp unavailable")
+				}
+				return nil
+			}
+			defer func() { processEmailNodeFn = processEmailNode }()
+
+			wf := &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{
+						EmailTemplate: &EmailTemplate{Subject: "Weather alert", Body: "Hi {{name}}"},
+						Retry:         &RetryPolicy{MaxAttempts: 3, BackoffMs: 1},
+					}}},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{
+					{Source: StartNodeID, Target: EmailNodeID},
+					{Source: EmailNodeID, Target: EndNodeID},
+				},
+			}
+			payload := &ExecutePayload{FormData: FormData{Name: "Jane", Email: "jane@example.com"}}
+
+			var letters []EmailDeadLetter
+			opts := ExecutionOptions{
+				DefaultEmailFrom: "alerts@example.com",
+				OnDeadLetter: func(ctx context.Context, letter EmailDeadLetter) error {
+					letters = append(letters, letter)
+					return nil
+				},
+			}
+
+			got, err := processNodes(context.Background(), wf, payload, opts)
+			require.NoError(t, err)
+			require.Equal(t, test.expectStatus, got.Status)
+			email, err := got.Steps[1].EmailOutput()
+			require.NoError(t, err)
+			require.Equal(t, test.expectAttempts, email.Attempts)
+
+			if !test.expectDeadLetter {
+				require.Empty(t, letters)
+				return
+			}
+			require.Equal(t, true, got.Steps[1].Output["deadLettered"])
+			require.Equal(t, []EmailDeadLetter{{
+				NodeID:    EmailNodeID,
+				Recipient: "jane@example.com",
+				Subject:   "Weather alert",
+				Body:      "Hi Jane",
+				Attempts:  3,
+				LastError: "email failed after 3 attempts: smtp unavailable",
+			}}, letters)
+		})
+	}
+}
+
+func TestProcessNodesFailurePolicy(t *testing.T) {
+	RegisterNodeProcessor("fail", NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+		return nil, errors.New("boom")
+	}))
+	defer UnregisterNodeProcessor("fail")
+	RegisterNodeProcessor("ok", NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+		return nil, nil
+	}))
+	defer UnregisterNodeProcessor("ok")
+
+	// start -> fail-1 -> ok-1 -> end
+	//       -> ok-2 -> end
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: "fail-1", Type: "fail"},
+			{ID: "ok-1", Type: "ok"},
+			{ID: "ok-2", Type: "ok"},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: "fail-1"},
+			{Source: "fail-1", Target: "ok-1"},
+			{Source: "ok-1", Target: EndNodeID},
+			{Source: StartNodeID, Target: "ok-2"},
+			{Source: "ok-2", Target: EndNodeID},
+		},
+	}
+
+	tests := []struct {
+		label       string
+		policy      string
+		wantNodeIDs []string
+	}{
+		{
+			label:       "default stops the failed path only",
+			wantNodeIDs: []string{StartNodeID, "fail-1", "ok-2", EndNodeID},
+		},
+		{
+			label:       "stopOnError aborts the execution",
+			policy:      FailurePolicyStopOnError,
+			wantNodeIDs: []string{StartNodeID, "fail-1"},
+		},
+		{
+			label:       "continue traverses past the failed node",
+			policy:      FailurePolicyContinue,
+			wantNodeIDs: []string{StartNodeID, "fail-1", "ok-1", EndNodeID, "ok-2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{FailurePolicy: test.policy})
+			require.NoError(t, err)
+			require.Equal(t, StatusFailed, got.Status)
+			require.Equal(t, &ExecutionError{NodeID: "fail-1", Code: codeInternal, Message: "boom"}, executionErrorFields(got.Error))
+
+			var nodeIDs []string
+			for _, step := range got.Steps {
+				nodeIDs = append(nodeIDs, step.NodeID)
+			}
+			require.Equal(t, test.wantNodeIDs, nodeIDs)
+		})
+	}
+}
+
+func TestProcessNodesLongLinearWorkflow(t *testing.T) {
+	RegisterNodeProcessor("ok", NodeProcessorFunc(func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+		return nil, nil
+	}))
+	defer UnregisterNodeProcessor("ok")
+
+	// start -> ok-0 -> ok-1 -> ... -> end
+	const length = 20000
+	wf := &WorkflowDefinition{Nodes: []Node{{ID: StartNodeID}, {ID: EndNodeID}}}
+	prev := StartNodeID
+	for i := 0; i < length; i++ {
+		id := fmt.Sprintf("ok-%d", i)
+		wf.Nodes = append(wf.Nodes, Node{ID: id, Type: "ok"})
+		wf.Edges = append(wf.Edges, Edge{Source: prev, Target: id})
+		prev = id
+	}
+	wf.Edges = append(wf.Edges, Edge{Source: prev, Target: EndNodeID})
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{MaxSteps: length + 2})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Len(t, got.Steps, length+2)
+	require.Equal(t, "ok-0", got.Steps[1].NodeID)
+	require.Equal(t, EndNodeID, got.Steps[length+1].NodeID)
+}
+
+func TestProcessNodesDefaultCity(t *testing.T) {
+	var weatherCity string
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		weatherCity = payload.FormData.City
+		contextData.SetFloat(weatherContextKey(WeatherMetricTemperature), 21)
+		return nil
+	}
+	defer func() { processWeatherNodeFn = processWeatherNode }()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: FormNodeID},
+			{ID: WeatherAPINodeID},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: FormNodeID},
+			{Source: FormNodeID, Target: WeatherAPINodeID},
+			{Source: WeatherAPINodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{Name: "Jane", Email: "jane@example.com"}}
+
+	got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{})
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, got.Status, "no default city keeps the missing city error")
+
+	got, err = processNodes(context.Background(), wf, payload, ExecutionOptions{DefaultCity: "Sydney"})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Equal(t, "Sydney", weatherCity)
+}
+
+func TestProcessNodesNodeHooks(t *testing.T) {
+	processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error {
+		return errors.New("smtp unavailable")
+	}
+	defer func() { processEmailNodeFn = processEmailNode }()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{}}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: EmailNodeID},
+			{Source: EmailNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{Email: "jane@example.com"}}
+
+	var events []string
+	opts := ExecutionOptions{
+		OnNodeStart: func(node Node) {
+			events = append(events, "start "+node.ID)
+		},
+		OnNodeComplete: func(node Node, step StepResult) {
+			require.Equal(t, node.ID, step.NodeID)
+			events = append(events, "complete "+node.ID+" "+step.Status)
+		},
+	}
+
+	_, err := processNodes(context.Background(), wf, payload, opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"start start",
+		"complete start completed",
+		"start email",
+		"complete email failed",
+	}, events)
+}
+
+// roundTripFunc is an http.RoundTripper answering requests without a network call.
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestProcessNodesHTTPClient(t *testing.T) {
+	var requested []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requested = append(requested, r.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"ok":true}`)),
+			Request:    r,
+		}, nil
+	})}
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: "webhook", Type: HTTPRequestNodeID, Data: NodeData{Metadata: NodeMetadata{
+				HTTPRequest: &HTTPRequest{URL: "https://alerts.invalid/notify"},
+			}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: "webhook"},
+			{Source: "webhook", Target: EndNodeID},
+		},
+	}
+
+	got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{HTTPClient: client})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Equal(t, []string{"https://alerts.invalid/notify"}, requested)
+	require.Equal(t, map[string]any{"ok": true}, got.Steps[1].Output["body"])
+}
+
+func TestProcessNodesUnknownNodeType(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: "mystery", Type: "teleport"},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: "mystery"},
+			{Source: "mystery", Target: EndNodeID},
+		},
+	}
+
+	t.Run("recorded as skipped", func(t *testing.T) {
+		got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{})
+		require.NoError(t, err)
+		require.Equal(t, StatusCompleted, got.Status)
+		require.Len(t, got.Steps, 3)
+		require.Equal(t, "mystery", got.Steps[1].NodeID)
+		require.Equal(t, StatusSkipped, got.Steps[1].Status)
+		require.Equal(t, `no processor is registered for the node type: node mystery has type "teleport"`, got.Steps[1].Output["reason"])
+	})
+
+	t.Run("strict stops the execution", func(t *testing.T) {
+		got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{Strict: true})
+		require.NoError(t, err)
+		require.Equal(t, StatusFailed, got.Status)
+		require.Len(t, got.Steps, 2)
+		require.Equal(t, StatusFailed, got.Steps[1].Status)
+		require.NotNil(t, got.Error)
+		require.Equal(t, "mystery", got.Error.NodeID)
+		require.Equal(t, "UNKNOWN_NODE_TYPE", got.Error.Code)
+	})
+}
+
+func TestProcessNodesEntryNode(t *testing.T) {
+	newWorkflow := func(entryNodeID string) *WorkflowDefinition {
+		return &WorkflowDefinition{
+			Nodes: []Node{
+				{ID: StartNodeID},
+				{ID: MetricsNodeID},
+				{ID: "summary", Type: MetricsNodeID},
+				{ID: EndNodeID},
+			},
+			Edges: []Edge{
+				{Source: StartNodeID, Target: MetricsNodeID},
+				{Source: MetricsNodeID, Target: "summary"},
+				{Source: "summary", Target: EndNodeID},
+			},
+			EntryNodeID: entryNodeID,
+		}
+	}
+
+	tests := []struct {
+		label         string
+		workflowEntry string
+		payloadEntry  string
+		wantNodes     []string
+		errExpected   error
+	}{
+		{
+			label:     "start node by default",
+			wantNodes: []string{StartNodeID, MetricsNodeID, "summary", EndNodeID},
+		},
+		{
+			label:         "workflow entry node",
+			workflowEntry: MetricsNodeID,
+			wantNodes:     []string{MetricsNodeID, "summary", EndNodeID},
+		},
+		{
+			label:         "payload entry node overrides the workflow one",
+			workflowEntry: MetricsNodeID,
+			payloadEntry:  "summary",
+			wantNodes:     []string{"summary", EndNodeID},
+		},
+		{
+			label:        "error: unknown payload entry node",
+			payloadEntry: "missing",
+			errExpected:  ErrEntryNodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			payload := &ExecutePayload{EntryNodeID: tt.payloadEntry}
+			got, err := processNodes(context.Background(), newWorkflow(tt.workflowEntry), payload, ExecutionOptions{})
+			if tt.errExpected != nil {
+				require.ErrorIs(t, err, tt.errExpected)
+				return
+			}
+			require.NoError(t, err)
+
+			var nodeIDs []string
+			for _, step := range got.Steps {
+				nodeIDs = append(nodeIDs, step.NodeID)
+			}
+			require.Equal(t, tt.wantNodes, nodeIDs)
+		})
+	}
+}
+
+func TestProcessNodesSortSteps(t *testing.T) {
+	// the DFS reaches end through the first branch before visiting the second one
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: MetricsNodeID},
+			{ID: "summary", Type: MetricsNodeID},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: MetricsNodeID},
+			{Source: StartNodeID, Target: "summary"},
+			{Source: MetricsNodeID, Target: EndNodeID},
+			{Source: "summary", Target: EndNodeID},
+		},
+	}
+
+	tests := []struct {
+		label     string
+		sortSteps bool
+		wantNodes []string
+		wantIndex []int
+	}{
+		{
+			label:     "execution order",
+			wantNodes: []string{StartNodeID, MetricsNodeID, EndNodeID, "summary"},
+			wantIndex: []int{0, 1, 2, 3},
+		},
+		{
+			label:     "topological order",
+			sortSteps: true,
+			wantNodes: []string{StartNodeID, MetricsNodeID, "summary", EndNodeID},
+			wantIndex: []int{0, 1, 3, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := processNodes(context.Background(), wf, &ExecutePayload{}, ExecutionOptions{SortSteps: tt.sortSteps})
+			require.NoError(t, err)
+
+			var nodeIDs []string
+			var indexes []int
+			for _, step := range got.Steps {
+				nodeIDs = append(nodeIDs, step.NodeID)
+				indexes = append(indexes, step.Index)
+			}
+			require.Equal(t, tt.wantNodes, nodeIDs)
+			require.Equal(t, tt.wantIndex, indexes)
+		})
+	}
+}
+
+func TestExecuteWorkflow(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: FormNodeID}, {ID: EndNodeID}},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: FormNodeID},
+			{Source: FormNodeID, Target: EndNodeID},
+		},
+	}
+
+	t.Run("executes the workflow", func(t *testing.T) {
+		payload := &ExecutePayload{
+			FormData:  FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney"},
+			Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+		}
+
+		got, err := ExecuteWorkflow(context.Background(), wf, payload)
+		require.NoError(t, err)
+		require.Equal(t, StatusCompleted, got.Status)
+		require.Len(t, got.Steps, 3)
+	})
+
+	t.Run("rejects an invalid payload", func(t *testing.T) {
+		got, err := ExecuteWorkflow(context.Background(), wf, &ExecutePayload{})
+		require.Nil(t, got)
+
+		var validationErrs ValidationErrors
+		require.ErrorAs(t, err, &validationErrs)
+	})
+}
+
 // TODO: Add unit test for the rest of node processors.
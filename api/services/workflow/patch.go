@@ -0,0 +1,149 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// this file patch.go contains the merge of a partial workflow definition into a stored one (PATCH /workflows/{id}).
+//
+// The patch follows the JSON merge patch rules (RFC 7396): objects are merged recursively and a null value
+// removes the key. The nodes and edges arrays are merged by id instead of being replaced, so a single node can
+// be updated by sending only its id and the changed fields:
+//
+//	{"nodes":[{"id":"email","data":{"metadata":{"emailTemplate":{"subject":"Storm warning"}}}}]}
+//
+// Nodes and edges with a new id are appended. The workflow id can't be changed.
+
+// idMergedArrays are the top-level definition arrays merged element by element using the element id.
+var idMergedArrays = []string{"nodes", "edges"}
+
+// patchWorkflowRecord merges the patch into the stored workflow and validates the merged definition
+// (ValidateWorkflowSchema and ValidateWorkflow), invalid definitions are reported as ValidationErrors. It reports
+// whether the definition changed, an unchanged workflow is returned as stored (e.g with the same updated_at).
+func patchWorkflowRecord(stored *WorkflowRecord, patch []byte) (*WorkflowRecord, bool, error) {
+	merged, err := mergeWorkflowDefinition(stored.Definition, patch)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := ValidateWorkflowSchema(merged); err != nil {
+		return nil, false, err
+	}
+	var wf WorkflowDefinition
+	if err := json.Unmarshal(merged, &wf); err != nil {
+		return nil, false, ValidationErrors{newFieldError("definition", ErrInvalidWorkflowFormat)}
+	}
+	if err := ValidateWorkflow(&wf); err != nil {
+		return nil, false, ValidationErrors{newFieldError("definition", err)}
+	}
+
+	// compared as JSON values, the stored definition's key order and spacing don't matter
+	var storedDoc, mergedDoc any
+	if err := json.Unmarshal(stored.Definition, &storedDoc); err == nil && json.Unmarshal(merged, &mergedDoc) == nil && reflect.DeepEqual(storedDoc, mergedDoc) {
+		return stored, false, nil
+	}
+
+	record := *stored
+	record.Definition = merged
+	return &record, true, nil
+}
+
+// mergeWorkflowDefinition applies the patch to the stored definition and returns the merged definition.
+// A malformed patch is reported as ValidationErrors.
+func mergeWorkflowDefinition(stored, patch []byte) ([]byte, error) {
+	var patchDoc map[string]any
+	if err := json.Unmarshal(patch, &patchDoc); err != nil || patchDoc == nil {
+		return nil, ErrInvalidJSON
+	}
+
+	var storedDoc map[string]any
+	if err := json.Unmarshal(stored, &storedDoc); err != nil {
+		return nil, ErrInvalidWorkflowFormat
+	}
+
+	if id, ok := patchDoc["id"]; ok && id != storedDoc["id"] {
+		return nil, ValidationErrors{newFieldError("id", fmt.Errorf("%w: the workflow id can't be changed", ErrInvalidPayload))}
+	}
+
+	var errs ValidationErrors
+	for _, key := range idMergedArrays {
+		patchItems, ok := patchDoc[key]
+		if !ok || patchItems == nil {
+			continue
+		}
+		delete(patchDoc, key)
+
+		merged, fieldErrs := mergeArrayByID(key, storedDoc[key], patchItems)
+		errs = append(errs, fieldErrs...)
+		storedDoc[key] = merged
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return json.Marshal(mergePatch(storedDoc, patchDoc))
+}
+
+// mergeArrayByID merges the patch elements into the stored elements with the same id, appending the new ones.
+func mergeArrayByID(field string, stored, patch any) ([]any, ValidationErrors) {
+	patchItems, ok := patch.([]any)
+	if !ok {
+		return nil, ValidationErrors{newFieldError(field, fmt.Errorf("%w: must be an array", ErrInvalidPayload))}
+	}
+	storedItems, _ := stored.([]any)
+
+	index := make(map[string]int, len(storedItems))
+	for i, item := range storedItems {
+		if obj, ok := item.(map[string]any); ok {
+			if id, ok := obj["id"].(string); ok {
+				index[id] = i
+			}
+		}
+	}
+
+	var errs ValidationErrors
+	for i, item := range patchItems {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			errs = append(errs, newFieldError(fmt.Sprintf("%s[%d]", field, i), fmt.Errorf("%w: must be an object", ErrInvalidPayload)))
+			continue
+		}
+		id, ok := obj["id"].(string)
+		if !ok || id == "" {
+			errs = append(errs, newFieldError(fmt.Sprintf("%s[%d].id", field, i), ErrMissingRequiredField))
+			continue
+		}
+
+		if pos, ok := index[id]; ok {
+			storedItems[pos] = mergePatch(storedItems[pos], obj)
+			continue
+		}
+		index[id] = len(storedItems)
+		storedItems = append(storedItems, mergePatch(nil, obj))
+	}
+
+	return storedItems, errs
+}
+
+// mergePatch applies a JSON merge patch to the target value.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,111 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeWorkflowDefinition(t *testing.T) {
+	stored := `{"id":"wf-1","name":"Weather","nodes":[{"id":"start","type":"start"},{"id":"email","type":"email","data":{"label":"Send","metadata":{"emailTemplate":{"subject":"Alert","body":"Hi"}}}},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"email"},{"id":"e2","source":"email","target":"end"}]}`
+
+	tests := []struct {
+		label       string
+		patch       string
+		want        string
+		expectErr   bool
+		errExpected error
+		wantFields  []string
+	}{
+		{
+			label: "success: update a node's metadata only",
+			patch: `{"nodes":[{"id":"email","data":{"metadata":{"emailTemplate":{"subject":"Storm warning"}}}}]}`,
+			want:  `{"id":"wf-1","name":"Weather","nodes":[{"id":"start","type":"start"},{"id":"email","type":"email","data":{"label":"Send","metadata":{"emailTemplate":{"subject":"Storm warning","body":"Hi"}}}},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"email"},{"id":"e2","source":"email","target":"end"}]}`,
+		},
+		{
+			label: "success: append a node and an edge, remove a field",
+			patch: `{"name":null,"nodes":[{"id":"sms","type":"sms"}],"edges":[{"id":"e3","source":"email","target":"sms"}]}`,
+			want:  `{"id":"wf-1","nodes":[{"id":"start","type":"start"},{"id":"email","type":"email","data":{"label":"Send","metadata":{"emailTemplate":{"subject":"Alert","body":"Hi"}}}},{"id":"end","type":"end"},{"id":"sms","type":"sms"}],"edges":[{"id":"e1","source":"start","target":"email"},{"id":"e2","source":"email","target":"end"},{"id":"e3","source":"email","target":"sms"}]}`,
+		},
+		{
+			label:       "error: patch isn't a json object",
+			patch:       `[]`,
+			expectErr:   true,
+			errExpected: ErrInvalidJSON,
+		},
+		{
+			label:       "error: changing the workflow id",
+			patch:       `{"id":"wf-2"}`,
+			expectErr:   true,
+			errExpected: ErrInvalidPayload,
+			wantFields:  []string{"id"},
+		},
+		{
+			label:       "error: node without id",
+			patch:       `{"nodes":[{"type":"sms"}],"edges":{}}`,
+			expectErr:   true,
+			errExpected: ErrMissingRequiredField,
+			wantFields:  []string{"nodes[0].id", "edges"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := mergeWorkflowDefinition([]byte(stored), []byte(tt.patch))
+			if !tt.expectErr {
+				require.NoError(t, err)
+				require.JSONEq(t, tt.want, string(got))
+				return
+			}
+
+			require.ErrorIs(t, err, tt.errExpected)
+			if len(tt.wantFields) > 0 {
+				var validationErrs ValidationErrors
+				require.ErrorAs(t, err, &validationErrs)
+
+				var gotFields []string
+				for _, fe := range validationErrs {
+					gotFields = append(gotFields, fe.Field)
+				}
+				require.Equal(t, tt.wantFields, gotFields)
+			}
+		})
+	}
+}
+
+func TestPatchWorkflowRecord(t *testing.T) {
+	updatedAt := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC)
+	stored := &WorkflowRecord{
+		Definition: []byte(`{"id": "wf-1", "name": "Weather", "nodes": [{"id": "start", "type": "start"}, {"id": "end", "type": "end"}], "edges": [{"id": "e1", "source": "start", "target": "end"}]}`),
+		UpdatedAt:  updatedAt,
+	}
+
+	tests := []struct {
+		label       string
+		patch       string
+		wantChanged bool
+	}{
+		{label: "identical patch", patch: `{"name":"Weather","nodes":[{"id":"start","type":"start"}]}`},
+		{label: "empty patch", patch: `{}`},
+		{label: "changed name", patch: `{"name":"Storm"}`, wantChanged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			record, changed, err := patchWorkflowRecord(stored, []byte(tt.patch))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantChanged, changed)
+			if !tt.wantChanged {
+				// the unchanged workflow isn't written, so its updated_at stays
+				require.Equal(t, updatedAt, record.UpdatedAt)
+				require.Equal(t, string(stored.Definition), string(record.Definition))
+			}
+		})
+	}
+
+	t.Run("error: invalid merged definition", func(t *testing.T) {
+		_, _, err := patchWorkflowRecord(stored, []byte(`{"nodes":[{"id":"end","type":null}],"edges":[{"id":"e2","source":"end","target":"start"}]}`))
+		require.Error(t, err)
+	})
+}
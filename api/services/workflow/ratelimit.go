@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// this file ratelimit.go contains the token bucket limiting the outbound weather provider calls.
+
+// tokenBucket is a token bucket rate limiter safe for concurrent use. The bucket holds up to burst tokens and
+// refills at rate tokens per second, each call takes a token or waits for the next one.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket allowing rate calls per second with bursts of burst calls (at least 1).
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(max(burst, 1))
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// wait takes a token, waiting until one is available. It fails right away with ErrWeatherRateLimited when the
+// token wouldn't be available before the ctx deadline, and when ctx is done while waiting.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// reserve the token, a negative balance is the time to wait for it
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if deadline, ok := ctx.Deadline(); ok && delay > 0 && now.Add(delay).After(deadline) {
+		b.tokens++
+		b.mu.Unlock()
+		return fmt.Errorf("%w: next call allowed in %s", ErrWeatherRateLimited, delay.Round(time.Millisecond))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// the call won't be made, give the reserved token back
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %v", ErrWeatherRateLimited, ctx.Err())
+	}
+}
@@ -0,0 +1,44 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(20, 2)
+
+	// the burst is available right away
+	start := time.Now()
+	require.NoError(t, bucket.wait(context.Background()))
+	require.NoError(t, bucket.wait(context.Background()))
+	require.Less(t, time.Since(start), 25*time.Millisecond)
+
+	// a token can't be refilled before the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	err := bucket.wait(ctx)
+	require.ErrorIs(t, err, ErrWeatherRateLimited)
+
+	// the next call waits for a refilled token (one every 50ms)
+	start = time.Now()
+	require.NoError(t, bucket.wait(context.Background()))
+	require.GreaterOrEqual(t, time.Since(start), 25*time.Millisecond)
+}
+
+func TestNewServiceWeatherRateLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.WeatherAPI.RateLimit = 5
+
+	s, err := NewService(nil, config)
+	require.NoError(t, err)
+	require.NotNil(t, s.executionOptions().WeatherAPI.limiter)
+	require.Nil(t, config.WeatherAPI.limiter, "the caller's config is left untouched")
+
+	config.WeatherAPI.RateLimit = -1
+	_, err = NewService(nil, config)
+	require.Error(t, err)
+}
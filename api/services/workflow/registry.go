@@ -0,0 +1,83 @@
+package workflow
+
+import (
+	"context"
+	"sync"
+)
+
+// this file registry.go contains the NodeProcessor extension point used to add custom node types.
+
+// NodeProcessor processes a single node of a workflow.
+// The returned output is recorded in the node's step. A returned error marks the step as failed,
+// in which case the (optional) output is still recorded alongside the error.
+// A processor can route the execution by returning the source handle of the edge to follow
+// under the "sourceHandle" output key, like the condition node does.
+type NodeProcessor interface {
+	Process(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error)
+}
+
+// NodeProcessorFunc adapts an ordinary function to a NodeProcessor.
+type NodeProcessorFunc func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error)
+
+// Process calls f(ctx, node, payload, contextData).
+func (f NodeProcessorFunc) Process(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) (map[string]interface{}, error) {
+	return f(ctx, node, payload, contextData)
+}
+
+var (
+	customProcessorsMu sync.RWMutex
+	// customProcessors holds the processors registered with RegisterNodeProcessor.
+	customProcessors = make(map[string]NodeProcessor)
+)
+
+// RegisterNodeProcessor registers the processor for a node type.
+// Registering one of the built-in node types (e.g "email") replaces the built-in processor.
+func RegisterNodeProcessor(nodeType string, processor NodeProcessor) {
+	customProcessorsMu.Lock()
+	defer customProcessorsMu.Unlock()
+
+	customProcessors[nodeType] = processor
+}
+
+// UnregisterNodeProcessor removes a processor registered with RegisterNodeProcessor.
+func UnregisterNodeProcessor(nodeType string) {
+	customProcessorsMu.Lock()
+	defer customProcessorsMu.Unlock()
+
+	delete(customProcessors, nodeType)
+}
+
+// hasCustomProcessor reports whether a processor is registered for the node type with RegisterNodeProcessor.
+func hasCustomProcessor(nodeType string) bool {
+	customProcessorsMu.RLock()
+	defer customProcessorsMu.RUnlock()
+
+	_, ok := customProcessors[nodeType]
+	return ok
+}
+
+// nodeProcessors returns the registry used for an execution: the built-in processors
+// overlaid with the registered custom processors.
+func nodeProcessors(opts ExecutionOptions) map[string]NodeProcessor {
+	processors := builtinProcessors(opts)
+
+	customProcessorsMu.RLock()
+	defer customProcessorsMu.RUnlock()
+
+	for nodeType, processor := range customProcessors {
+		processors[nodeType] = processor
+	}
+	return processors
+}
+
+// resolveNodeType returns the registry key for a node. Built-in nodes are identified by their ID
+// (e.g "weather-api"), other nodes fall back to their type so several nodes can share one processor.
+func resolveNodeType(node Node, processors map[string]NodeProcessor) (string, bool) {
+	if _, ok := processors[node.ID]; ok {
+		return node.ID, true
+	}
+	if _, ok := processors[node.Type]; ok {
+		return node.Type, true
+	}
+	return "", false
+}
@@ -2,6 +2,14 @@ package workflow
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // This file repository.go contains workflow related DB methods.
@@ -25,13 +33,364 @@ func (s *Service) GetWorkflowDefinitionByID(ctx context.Context, id string) ([]b
 	return definition, nil
 }
 
-// UpdateWorkflowDefinitionByID is a helper method to update a workflow definition by id.
-func (s *Service) UpdateWorkflowDefinitionByID(ctx context.Context, id string, newDefinition []byte) error {
-	_, err := s.db.Exec(ctx, `
+// WorkflowsNotFoundError lists the requested workflow ids that aren't stored, in request order. It matches
+// ErrWorkflowNotFound with errors.Is.
+type WorkflowsNotFoundError struct {
+	IDs []string
+}
+
+func (e *WorkflowsNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrWorkflowNotFound.Message, strings.Join(e.IDs, ", "))
+}
+
+func (e *WorkflowsNotFoundError) Unwrap() error {
+	return ErrWorkflowNotFound
+}
+
+// GetWorkflowDefinitionsByIDs returns the definitions of the workflows with the ids, keyed by id, in a single query.
+// When some of the ids aren't stored, the definitions found are returned with a *WorkflowsNotFoundError listing
+// the missing ids.
+func (s *Service) GetWorkflowDefinitionsByIDs(ctx context.Context, ids []string) (map[string][]byte, error) {
+	definitions := make(map[string][]byte, len(ids))
+	if len(ids) == 0 {
+		return definitions, nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT definition->>'id', definition
+		FROM workflows
+		WHERE definition->>'id' = ANY($1)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var definition []byte
+		if err := rows.Scan(&id, &definition); err != nil {
+			return nil, err
+		}
+		definitions[id] = definition
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return definitions, workflowsNotFound(ids, definitions)
+}
+
+// workflowsNotFound returns a *WorkflowsNotFoundError with the requested ids without a definition, in request order
+// and without duplicates, or nil when every id has one.
+func workflowsNotFound(ids []string, definitions map[string][]byte) error {
+	var missing []string
+	for _, id := range ids {
+		if _, ok := definitions[id]; !ok && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &WorkflowsNotFoundError{IDs: missing}
+}
+
+// ExistsWorkflow reports whether a workflow with the id is stored without loading its definition.
+func (s *Service) ExistsWorkflow(ctx context.Context, id string) (bool, error) {
+	var one int
+
+	err := s.db.QueryRow(ctx, `
+		SELECT 1
+		FROM workflows
+		WHERE definition->>'id' = $1
+		LIMIT 1
+	`, id).Scan(&one)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// WorkflowRecord is a stored workflow definition with its row metadata.
+type WorkflowRecord struct {
+	Definition []byte
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// GetWorkflowByID returns a workflow definition by id along with its created_at and updated_at timestamps.
+func (s *Service) GetWorkflowByID(ctx context.Context, id string) (*WorkflowRecord, error) {
+	var record WorkflowRecord
+	var createdAt, updatedAt *time.Time
+
+	err := s.db.QueryRow(ctx, `
+		SELECT definition, created_at, updated_at
+		FROM workflows
+		WHERE definition->>'id' = $1
+	`, id).Scan(&record.Definition, &createdAt, &updatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the timestamp columns are nullable
+	if createdAt != nil {
+		record.CreatedAt = *createdAt
+	}
+	if updatedAt != nil {
+		record.UpdatedAt = *updatedAt
+	}
+
+	return &record, nil
+}
+
+// PatchWorkflowDefinitionByID merges a partial definition into the stored one (see patchWorkflowRecord) and
+// returns the updated workflow. The row is locked while it's read, merged and written so concurrent patches
+// aren't lost. Nothing is written when the patch leaves the definition unchanged, so its updated_at is kept.
+// It returns pgx.ErrNoRows when the workflow doesn't exist.
+func (s *Service) PatchWorkflowDefinitionByID(ctx context.Context, id string, patch []byte) (*WorkflowRecord, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var stored WorkflowRecord
+	var createdAt, updatedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT definition, created_at, updated_at
+		FROM workflows
+		WHERE definition->>'id' = $1
+		FOR UPDATE
+	`, id).Scan(&stored.Definition, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	// the timestamp columns are nullable
+	if createdAt != nil {
+		stored.CreatedAt = *createdAt
+	}
+	if updatedAt != nil {
+		stored.UpdatedAt = *updatedAt
+	}
+
+	record, changed, err := patchWorkflowRecord(&stored, patch)
+	if err != nil || !changed {
+		return record, err
+	}
+
+	err = tx.QueryRow(ctx, `
 		UPDATE workflows
 		SET definition = $1,
 		    updated_at = now()
 		WHERE definition->>'id' = $2
-	`, newDefinition, id)
+		RETURNING updated_at
+	`, record.Definition, id).Scan(&record.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// CreateWorkflow inserts a new workflow definition.
+// It returns ErrWorkflowAlreadyExists if a workflow with the same definition id is already stored,
+// and the ValidateWorkflowSchema errors if the definition is missing required fields.
+func (s *Service) CreateWorkflow(ctx context.Context, id string, name string, definition []byte) error {
+	if err := ValidateWorkflowSchema(definition); err != nil {
+		return err
+	}
+
+	tag, err := s.db.Exec(ctx, `
+		INSERT INTO workflows (name, definition)
+		SELECT $1, $2
+		WHERE NOT EXISTS (
+			SELECT 1 FROM workflows WHERE definition->>'id' = $3
+		)
+	`, name, definition, id)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrWorkflowAlreadyExists
+	}
+
+	return nil
+}
+
+// CloneWorkflow stores a copy of the workflow under a new id and returns the new id. The copy is named name,
+// or after the source workflow when name is empty. It returns pgx.ErrNoRows if the workflow doesn't exist.
+func (s *Service) CloneWorkflow(ctx context.Context, id string, name string) (string, error) {
+	stored, err := s.GetWorkflowDefinitionByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	cloneID, err := newWorkflowID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate workflow id: %w", err)
+	}
+	definition, name, err := cloneDefinition(stored, cloneID, name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.CreateWorkflow(ctx, cloneID, name, definition); err != nil {
+		return "", err
+	}
+	return cloneID, nil
+}
+
+// SaveEmailDeadLetter stores an email that couldn't be sent after every retry.
+func (s *Service) SaveEmailDeadLetter(ctx context.Context, letter EmailDeadLetter) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO email_dead_letters (workflow_id, node_id, recipient, subject, body, attempts, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, letter.WorkflowID, letter.NodeID, letter.Recipient, letter.Subject, letter.Body, letter.Attempts, letter.LastError)
 	return err
 }
+
+// storedExecutionsLimit is the number of executions kept per workflow, older ones are deleted as new ones are
+// saved so the stored contextData (e.g emails) doesn't grow unbounded.
+const storedExecutionsLimit = 10
+
+// SaveExecution stores the result and the final contextData of an execution of the workflow, and deletes the
+// executions of the workflow older than the last storedExecutionsLimit ones.
+func (s *Service) SaveExecution(ctx context.Context, workflowID string, result *ExecutionResult, contextData map[string]any) error {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution result: %w", err)
+	}
+	contextJSON, err := json.Marshal(contextData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution context: %w", err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO workflow_executions (workflow_id, status, result, context)
+		VALUES ($1, $2, $3, $4)
+	`, workflowID, result.Status, resultJSON, contextJSON)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM workflow_executions
+		WHERE workflow_id = $1
+		  AND id NOT IN (
+			SELECT id
+			FROM workflow_executions
+			WHERE workflow_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		  )
+	`, workflowID, storedExecutionsLimit)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetLastExecution returns the most recent stored execution of the workflow, pgx.ErrNoRows when there is none.
+func (s *Service) GetLastExecution(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+	record := ExecutionRecord{WorkflowID: workflowID}
+	var resultJSON, contextJSON []byte
+	var createdAt *time.Time
+
+	err := s.db.QueryRow(ctx, `
+		SELECT id::text, result, context, created_at
+		FROM workflow_executions
+		WHERE workflow_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, workflowID).Scan(&record.ID, &resultJSON, &contextJSON, &createdAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(resultJSON, &record.Result); err != nil {
+		return nil, fmt.Errorf("invalid stored execution result: %w", err)
+	}
+	if err := json.Unmarshal(contextJSON, &record.Context); err != nil {
+		return nil, fmt.Errorf("invalid stored execution context: %w", err)
+	}
+	// the timestamp column is nullable
+	if createdAt != nil {
+		record.CreatedAt = *createdAt
+	}
+
+	return &record, nil
+}
+
+// WorkflowSummary is a stored workflow without its definition, as listed by ListWorkflows.
+type WorkflowSummary struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// ListWorkflows returns a page of workflows, most recently created first, along with the total number of
+// workflows. Both queries run in the same read-only snapshot so the total matches the page.
+func (s *Service) ListWorkflows(ctx context.Context, limit, offset int) ([]WorkflowSummary, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var total int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM workflows`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT definition->>'id', name, created_at, updated_at
+		FROM workflows
+		ORDER BY created_at DESC, id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []WorkflowSummary{}
+	for rows.Next() {
+		var item WorkflowSummary
+		if err := rows.Scan(&item.ID, &item.Name, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, tx.Commit(ctx)
+}
+
+// PingDB checks the database can be reached with a trivial query.
+func (s *Service) PingDB(ctx context.Context) error {
+	var one int
+	return s.db.QueryRow(ctx, `SELECT 1`).Scan(&one)
+}
@@ -0,0 +1,40 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowsNotFound(t *testing.T) {
+	definitions := map[string][]byte{
+		"wf-1": []byte(`{"id":"wf-1"}`),
+		"wf-3": []byte(`{"id":"wf-3"}`),
+	}
+
+	tests := []struct {
+		label string
+		ids   []string
+		want  []string
+	}{
+		{label: "every id found", ids: []string{"wf-1", "wf-3"}},
+		{label: "missing ids in request order", ids: []string{"wf-4", "wf-1", "wf-2"}, want: []string{"wf-4", "wf-2"}},
+		{label: "duplicate missing id", ids: []string{"wf-2", "wf-2"}, want: []string{"wf-2"}},
+		{label: "no ids"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			err := workflowsNotFound(tt.ids, definitions)
+			if tt.want == nil {
+				require.NoError(t, err)
+				return
+			}
+			var notFound *WorkflowsNotFoundError
+			require.ErrorAs(t, err, &notFound)
+			require.Equal(t, tt.want, notFound.IDs)
+			require.ErrorIs(t, err, ErrWorkflowNotFound)
+			require.Equal(t, ErrWorkflowNotFound.Code, errorCode(err))
+		})
+	}
+}
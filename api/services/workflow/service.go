@@ -1,18 +1,146 @@
 package workflow
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Service struct {
-	db *pgx.Conn
+	// db is safe for concurrent use, each query runs on a connection of its own
+	db          *pgxpool.Pool
+	config      *Config
+	idempotency *idempotencyCache
+	// executions tracks the in-flight executions so they can be canceled
+	executions *executionRegistry
+	// httpClient makes every outbound node request (weather, http-request, slack)
+	httpClient *http.Client
 }
 
-func NewService(db *pgx.Conn) (*Service, error) {
-	return &Service{db: db}, nil
+// Config holds the service-level settings applied to every workflow execution.
+type Config struct {
+	// DefaultEmailFrom is the sender address used by email nodes that don't configure their own.
+	DefaultEmailFrom string
+	// WeatherAPI overrides the geocoding and weather API base URLs (e.g for a local mock server).
+	WeatherAPI WeatherAPIConfig
+	// DefaultCity is used when the execute payload has no city (e.g for workflows not asking the user for one).
+	// When empty, a missing city is rejected.
+	DefaultCity string
+	// HTTPClient is used by every outbound node request, e.g to configure a proxy or custom TLS.
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// ArchiveWriter writes the documents of the archive nodes, e.g to an object store.
+	// Defaults to writing local files or PUTting to http(s) URLs.
+	ArchiveWriter ArchiveWriter
+	// EmailSender sends the emails of the email nodes, e.g through an SMTP server.
+	// Defaults to a sender logging the emails without sending them.
+	EmailSender EmailSender
+	// MaxSteps caps the number of nodes processed in a single execution.
+	MaxSteps int
+	// IdempotencyWindow is how long an execution result is replayed for a repeated Idempotency-Key.
+	IdempotencyWindow time.Duration
+	// APIKeys enables the API key authentication of the workflow routes (see authMiddleware).
+	// Without keys, the routes are open.
+	APIKeys APIKeys
+	// MaxBodyBytes caps the size of a request body, larger bodies are rejected with 413 Request Entity Too Large.
+	// Defaults to defaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// BatchConcurrency is the number of payloads of a batch executed at once.
+	// Defaults to defaultBatchConcurrency.
+	BatchConcurrency int
+}
+
+// defaultMaxBodyBytes is the request body size limit used when the config doesn't set one.
+const defaultMaxBodyBytes = 1 << 20
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() *Config {
+	return &Config{
+		DefaultEmailFrom: defaultEmailFrom,
+		WeatherAPI: WeatherAPIConfig{
+			GeocodingBaseURL: defaultGeocodingBaseURL,
+		},
+		MaxSteps:          defaultMaxSteps,
+		IdempotencyWindow: defaultIdempotencyWindow,
+		MaxBodyBytes:      defaultMaxBodyBytes,
+		BatchConcurrency:  defaultBatchConcurrency,
+	}
+}
+
+func NewService(db *pgxpool.Pool, config *Config) (*Service, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if _, err := parseEmailAddress(config.DefaultEmailFrom); err != nil {
+		return nil, fmt.Errorf("invalid default email from address: %w", err)
+	}
+	for _, baseURL := range []string{config.WeatherAPI.GeocodingBaseURL, config.WeatherAPI.WeatherBaseURL, config.WeatherAPI.OpenWeatherMapBaseURL} {
+		if baseURL == "" {
+			continue
+		}
+		if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid weather API base url: %q", baseURL)
+		}
+	}
+
+	if config.BatchConcurrency < 0 {
+		return nil, fmt.Errorf("invalid batch concurrency: %d", config.BatchConcurrency)
+	}
+
+	window := config.IdempotencyWindow
+	if window <= 0 {
+		window = defaultIdempotencyWindow
+	}
+
+	// the limiter is shared by every execution of the service
+	if config.WeatherAPI.RateLimit < 0 {
+		return nil, fmt.Errorf("invalid weather API rate limit: %g", config.WeatherAPI.RateLimit)
+	}
+	if config.WeatherAPI.RateLimit > 0 {
+		limited := *config
+		limited.WeatherAPI.limiter = newTokenBucket(config.WeatherAPI.RateLimit, config.WeatherAPI.RateBurst)
+		config = &limited
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Service{db: db, config: config, idempotency: newIdempotencyCache(window), executions: newExecutionRegistry(), httpClient: httpClient}, nil
+}
+
+// executionOptions returns the execution options derived from the service config.
+func (s *Service) executionOptions() ExecutionOptions {
+	return ExecutionOptions{
+		DefaultEmailFrom: s.config.DefaultEmailFrom,
+		WeatherAPI:       s.config.WeatherAPI,
+		DefaultCity:      s.config.DefaultCity,
+		MaxSteps:         s.config.MaxSteps,
+		HTTPClient:       s.httpClient,
+		ArchiveWriter:    s.config.ArchiveWriter,
+		EmailSender:      s.config.EmailSender,
+	}
+}
+
+// maxBodyMiddleware limits the size of the request body to the configured MaxBodyBytes. Reading past the limit
+// fails with an *http.MaxBytesError, answered with 413 by the handlers (see writeBodyError).
+func (s *Service) maxBodyMiddleware(next http.Handler) http.Handler {
+	limit := s.config.MaxBodyBytes
+	if limit <= 0 {
+		limit = defaultMaxBodyBytes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 // jsonMiddleware sets the Content-Type header to application/json
@@ -27,8 +155,60 @@ func (s *Service) LoadRoutes(parentRouter *mux.Router, isProduction bool) {
 	router := parentRouter.PathPrefix("/workflows").Subrouter()
 	router.StrictSlash(false)
 	router.Use(jsonMiddleware)
+	router.Use(requestIDMiddleware)
+	router.Use(s.maxBodyMiddleware)
+	router.Use(s.authMiddleware)
 
+	router.HandleFunc("", s.adminOnly(s.HandleListWorkflows)).Methods("GET")
+	router.HandleFunc("", s.adminOnly(s.HandleCreateWorkflow)).Methods("POST")
+	router.HandleFunc("/validate", s.HandleValidateWorkflow).Methods("POST")
+	router.HandleFunc("/execute", s.adminOnly(s.HandleExecuteInlineWorkflow)).Methods("POST")
 	router.HandleFunc("/{id}", s.HandleGetWorkflow).Methods("GET")
-	router.HandleFunc("/{id}/execute", s.HandleExecuteWorkflow).Methods("POST")
+	router.HandleFunc("/{id}", s.HandleWorkflowExists).Methods("HEAD")
+	router.HandleFunc("/{id}", s.HandlePatchWorkflow).Methods("PATCH")
+	router.HandleFunc("/{id}/execute", s.HandleExecuteWorkflow).Methods("POST", "GET")
+	router.HandleFunc("/{id}/execute/batch", s.HandleExecuteWorkflowBatch).Methods("POST")
+	router.HandleFunc("/{id}/graph", s.HandleGetWorkflowGraph).Methods("GET")
+	router.HandleFunc("/{id}/clone", s.HandleCloneWorkflow).Methods("POST")
+	router.HandleFunc("/{id}/executions/{execId}/cancel", s.HandleCancelExecution).Methods("POST")
 
+	// registered last so it only handles requests that no route above matched
+	router.PathPrefix("").Name(fallbackRouteName).Handler(methodNotAllowedHandler(router))
+}
+
+// probedMethods are the methods checked when building the Allow header of a 405 response.
+var probedMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// fallbackRouteName names the catch-all route answering requests no other workflow route matched.
+const fallbackRouteName = "fallback"
+
+// methodNotAllowedHandler responds with 405 Method Not Allowed and an Allow header listing the
+// methods the router accepts for the requested path, or 404 when the path isn't routed at all.
+func methodNotAllowedHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range probedMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+
+			var match mux.RouteMatch
+			if router.Match(probe, &match) && match.MatchErr == nil && match.Route.GetName() != fallbackRouteName {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeJSONError(w, ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+	})
 }
@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	router := mux.NewRouter()
+	s := &Service{config: DefaultConfig()}
+	s.LoadRoutes(router, false)
+
+	tests := []struct {
+		label         string
+		method        string
+		path          string
+		allowExpected string
+	}{
+		{
+			label:         "put on the execute route",
+			method:        http.MethodPut,
+			path:          "/workflows/550e8400-e29b-41d4-a716-446655440000/execute",
+			allowExpected: "GET, POST",
+		},
+		{
+			label:         "post on the get workflow route",
+			method:        http.MethodPost,
+			path:          "/workflows/550e8400-e29b-41d4-a716-446655440000",
+			allowExpected: "GET, HEAD, PATCH",
+		},
+		{
+			label:         "delete on the create route",
+			method:        http.MethodDelete,
+			path:          "/workflows",
+			allowExpected: "GET, POST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+			require.Equal(t, tt.allowExpected, rec.Header().Get("Allow"))
+			require.JSONEq(t, errorToJSON(ErrMethodNotAllowed), rec.Body.String())
+		})
+	}
+}
+
+func TestUnknownRouteNotFound(t *testing.T) {
+	router := mux.NewRouter()
+	s := &Service{config: DefaultConfig()}
+	s.LoadRoutes(router, false)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/workflows/1/unknown", nil))
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Empty(t, rec.Header().Get("Allow"))
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	router := mux.NewRouter()
+	config := DefaultConfig()
+	config.MaxBodyBytes = 64
+	s := &Service{config: config}
+	s.LoadRoutes(router, false)
+
+	large := `{"id":"wf-1","nodes":[],"edges":[],"name":"` + strings.Repeat("x", 64) + `"}`
+
+	tests := []struct {
+		label      string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{label: "validate within the limit", path: "/workflows/validate", body: `{"id":"wf-1","nodes":[],"edges":[]}`, wantStatus: http.StatusOK},
+		{label: "validate over the limit", path: "/workflows/validate", body: large, wantStatus: http.StatusRequestEntityTooLarge},
+		{label: "create over the limit", path: "/workflows", body: large, wantStatus: http.StatusRequestEntityTooLarge},
+		{label: "inline execute over the limit", path: "/workflows/execute", body: large, wantStatus: http.StatusRequestEntityTooLarge},
+		{label: "execute over the limit", path: "/workflows/550e8400-e29b-41d4-a716-446655440000/execute", body: large, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusRequestEntityTooLarge {
+				require.Contains(t, rec.Body.String(), `"code":"REQUEST_TOO_LARGE"`)
+			}
+		})
+	}
+}
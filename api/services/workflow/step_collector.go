@@ -0,0 +1,109 @@
+package workflow
+
+import (
+	"time"
+)
+
+// stepCollector assembles the steps of an execution. The steps are sent over a channel to a single collector
+// goroutine owning the slice, so nodes processed on different goroutines (e.g parallel branches) never mutate it
+// directly. The collector numbers the steps in the order it receives them, and counts them for the execution stats.
+type stepCollector struct {
+	records   chan stepRecord
+	snapshots chan chan []StepResult
+	counts    chan chan stepCounts
+	done      chan []StepResult
+}
+
+// stepCounts is the number of steps recorded so far, and how many of them failed.
+type stepCounts struct {
+	recorded int
+	failed   int
+}
+
+// stepRecord is a step sent to the collector, the recorded step (with its index) is sent back on reply.
+type stepRecord struct {
+	step  StepResult
+	reply chan StepResult
+}
+
+// newStepCollector starts the collector goroutine. close must be called to stop it.
+func newStepCollector() *stepCollector {
+	c := &stepCollector{
+		records:   make(chan stepRecord),
+		snapshots: make(chan chan []StepResult),
+		counts:    make(chan chan stepCounts),
+		done:      make(chan []StepResult, 1),
+	}
+	go c.run()
+	return c
+}
+
+func (c *stepCollector) run() {
+	steps := []StepResult{}
+	failed := 0
+	for {
+		select {
+		case record, ok := <-c.records:
+			if !ok {
+				c.done <- steps
+				return
+			}
+			record.step.Index = len(steps)
+			steps = append(steps, record.step)
+			if record.step.Status == StatusFailed {
+				failed++
+			}
+			record.reply <- record.step
+		case reply := <-c.snapshots:
+			reply <- append([]StepResult(nil), steps...)
+		case reply := <-c.counts:
+			reply <- stepCounts{recorded: len(steps), failed: failed}
+		}
+	}
+}
+
+// record sends the step to the collector and returns it as recorded, once it has been appended.
+func (c *stepCollector) record(step StepResult) StepResult {
+	reply := make(chan StepResult, 1)
+	c.records <- stepRecord{step: step, reply: reply}
+	return <-reply
+}
+
+// snapshot returns a copy of the steps recorded so far.
+func (c *stepCollector) snapshot() []StepResult {
+	reply := make(chan []StepResult, 1)
+	c.snapshots <- reply
+	return <-reply
+}
+
+// stepCounts returns the number of steps recorded so far and how many of them failed.
+func (c *stepCollector) stepCounts() stepCounts {
+	reply := make(chan stepCounts, 1)
+	c.counts <- reply
+	return <-reply
+}
+
+// close stops the collector and returns the recorded steps in order. No step can be recorded afterwards.
+func (c *stepCollector) close() []StepResult {
+	close(c.records)
+	return <-c.done
+}
+
+// newStepResult builds the step of the node, its index is assigned by the collector.
+// The node start and finish timestamps are recorded in the step output.
+func newStepResult(node Node, status string, startTime, finishTime time.Time, output map[string]interface{}) StepResult {
+	if output == nil {
+		output = make(map[string]interface{})
+	}
+	output["startedAt"] = startTime.UTC().Format(time.RFC3339Nano)
+	output["finishedAt"] = finishTime.UTC().Format(time.RFC3339Nano)
+
+	return StepResult{
+		NodeID:      node.ID,
+		Type:        node.Type,
+		Label:       node.Data.Label,
+		Description: node.Data.Description,
+		Status:      status,
+		Output:      output,
+	}
+}
@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepCollector(t *testing.T) {
+	t.Run("numbers the steps in order", func(t *testing.T) {
+		collector := newStepCollector()
+		now := time.Now()
+
+		first := collector.record(newStepResult(Node{ID: StartNodeID}, StatusCompleted, now, now, nil))
+		second := collector.record(newStepResult(Node{ID: EndNodeID}, StatusCompleted, now, now, nil))
+		require.Equal(t, 0, first.Index)
+		require.Equal(t, 1, second.Index)
+		require.Len(t, collector.snapshot(), 2)
+
+		steps := collector.close()
+		require.Equal(t, []string{StartNodeID, EndNodeID}, []string{steps[0].NodeID, steps[1].NodeID})
+		require.Contains(t, steps[0].Output, "startedAt")
+	})
+
+	t.Run("no steps", func(t *testing.T) {
+		steps := newStepCollector().close()
+		require.NotNil(t, steps)
+		require.Empty(t, steps)
+	})
+
+	t.Run("records from several goroutines", func(t *testing.T) {
+		collector := newStepCollector()
+		now := time.Now()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				collector.record(newStepResult(Node{ID: TransformNodeID}, StatusCompleted, now, now, nil))
+			}()
+		}
+		wg.Wait()
+
+		steps := collector.close()
+		require.Len(t, steps, 50)
+		for i, step := range steps {
+			require.Equal(t, i, step.Index)
+		}
+	})
+
+	t.Run("counts the recorded and failed steps", func(t *testing.T) {
+		collector := newStepCollector()
+		now := time.Now()
+		require.Equal(t, stepCounts{}, collector.stepCounts())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			status := StatusCompleted
+			if i%5 == 0 {
+				status = StatusFailed
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				collector.record(newStepResult(Node{ID: TransformNodeID}, status, now, now, nil))
+				collector.stepCounts()
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, stepCounts{recorded: 50, failed: 10}, collector.stepCounts())
+		collector.close()
+	})
+}
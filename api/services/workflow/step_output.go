@@ -0,0 +1,319 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// this file step_output.go contains the typed step outputs of the built-in nodes.
+//
+// StepResult.Output stays a map so the JSON wire format doesn't depend on the node type. The built-in processors
+// build the typed outputs below and convert them with stepOutput, so the map always has the JSON shape of the typed
+// output and Go callers can decode it back with type safety:
+//
+//	weather, err := step.WeatherOutput()
+//	if err == nil && weather.Temperature != nil { ... }
+
+// StepTiming holds the fields every step output carries, set by processNodes once the processor returns.
+type StepTiming struct {
+	StartedAt  string `json:"startedAt"`
+	FinishedAt string `json:"finishedAt"`
+	Duration   int64  `json:"duration"`        // milliseconds
+	Error      string `json:"error,omitempty"` // set on failed steps
+}
+
+// FormOutput is the output of the form node.
+type FormOutput struct {
+	StepTiming
+	Name    string         `json:"name"`
+	Email   string         `json:"email"`
+	City    string         `json:"city"`
+	Phone   string         `json:"phone,omitempty"`
+	Country string         `json:"country,omitempty"`
+	Region  string         `json:"region,omitempty"`
+	Inputs  map[string]any `json:"inputs,omitempty"` // the form data fields of forms with input fields
+	Fields  []FieldError   `json:"fields,omitempty"` // the invalid fields when the form data is rejected
+}
+
+// Coordinates is a latitude and longitude.
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// WeatherMetrics are the metrics of a weather reading. Only the metrics requested by the node are set.
+type WeatherMetrics struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	WindSpeed     *float64 `json:"windspeed,omitempty"`
+	WindDirection *float64 `json:"winddirection,omitempty"`
+	WeatherCode   *float64 `json:"weathercode,omitempty"`
+	Humidity      *float64 `json:"humidity,omitempty"`
+}
+
+// set sets the metric to the value, ignoring unknown metrics and non numeric values.
+func (m *WeatherMetrics) set(metric string, value any) {
+	v, ok := toFloat64(value)
+	if !ok {
+		return
+	}
+	switch metric {
+	case WeatherMetricTemperature:
+		m.Temperature = &v
+	case WeatherMetricWindSpeed:
+		m.WindSpeed = &v
+	case WeatherMetricWindDirection:
+		m.WindDirection = &v
+	case WeatherMetricWeatherCode:
+		m.WeatherCode = &v
+	case WeatherMetricHumidity:
+		m.Humidity = &v
+	}
+}
+
+// WeatherOutput is the output of the weather node.
+type WeatherOutput struct {
+	StepTiming
+	WeatherMetrics
+	Location       string            `json:"location,omitempty"`
+	Coordinates    *Coordinates      `json:"coordinates,omitempty"`
+	ReadingTime    string            `json:"readingTime,omitempty"` // RFC 3339 time of the reading
+	Warning        string            `json:"warning,omitempty"`     // e.g a stale reading outside of strict mode
+	GeocodingURL   string            `json:"geocodingUrl,omitempty"`
+	WeatherURL     string            `json:"weatherUrl,omitempty"`
+	WeatherHeaders map[string]string `json:"weatherHeaders,omitempty"`
+	RetryWaitMs    int64             `json:"retryWaitMs,omitempty"` // time waited before retrying rate limited requests
+}
+
+// LoopIteration is the weather lookup of one city of the loop node.
+type LoopIteration struct {
+	WeatherMetrics
+	Index    int    `json:"index"`
+	Location string `json:"location"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// LoopOutput is the output of the loop node.
+type LoopOutput struct {
+	StepTiming
+	Iterations []LoopIteration `json:"iterations"`
+}
+
+// TransformOutput is the output of the transform node.
+type TransformOutput struct {
+	StepTiming
+	Key   string  `json:"key"`
+	Value float64 `json:"value"`
+}
+
+// MetricsOutput is the output of the metrics node.
+type MetricsOutput struct {
+	StepTiming
+	StepCount          int    `json:"stepCount"`
+	FailedSteps        int    `json:"failedSteps"`
+	ElapsedMs          int64  `json:"elapsedMs"`
+	ExecutionStartedAt string `json:"executionStartedAt"`
+}
+
+// ConditionOutput is the output of the condition node.
+type ConditionOutput struct {
+	StepTiming
+	ConditionMet   bool     `json:"conditionMet"`
+	SourceHandle   string   `json:"sourceHandle"`
+	Threshold      float64  `json:"threshold"`
+	UpperThreshold *float64 `json:"upperThreshold,omitempty"` // only set by the between operator
+	Operator       string   `json:"operator"`
+	ActualValue    float64  `json:"actualValue"`
+	Message        string   `json:"message"`
+	Expression     string   `json:"expression,omitempty"` // only set by conditions evaluating a comparison expression
+}
+
+// EmailDraft is the email sent by the email node.
+type EmailDraft struct {
+	To        string `json:"to"`
+	From      string `json:"from"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	Timestamp string `json:"timestamp"`
+}
+
+// EmailOutput is the output of the email node.
+type EmailOutput struct {
+	StepTiming
+	EmailDraft     *EmailDraft `json:"emailDraft,omitempty"`
+	Attempts       int         `json:"attempts,omitempty"`
+	DeliveryStatus string      `json:"deliveryStatus,omitempty"`
+	MessageID      string      `json:"messageId,omitempty"`
+	EmailSent      bool        `json:"emailSent,omitempty"`
+	DeadLettered   bool        `json:"deadLettered,omitempty"`
+}
+
+// SMSMessage is the message sent by the sms node.
+type SMSMessage struct {
+	To        string `json:"to"`
+	Message   string `json:"message"`
+	Timestamp string `json:"timestamp"`
+}
+
+// SMSOutput is the output of the sms node.
+type SMSOutput struct {
+	StepTiming
+	SMS            *SMSMessage `json:"sms,omitempty"`
+	DeliveryStatus string      `json:"deliveryStatus,omitempty"`
+	MessageID      string      `json:"messageId,omitempty"`
+	SMSSent        bool        `json:"smsSent,omitempty"`
+}
+
+// SlackMessage is the message posted by the slack node.
+type SlackMessage struct {
+	Message   string `json:"message"`
+	Timestamp string `json:"timestamp"`
+}
+
+// SlackOutput is the output of the slack node.
+type SlackOutput struct {
+	StepTiming
+	Slack         *SlackMessage `json:"slack,omitempty"`
+	WebhookStatus int           `json:"webhookStatus,omitempty"`
+	SlackSent     bool          `json:"slackSent,omitempty"`
+}
+
+// ArchiveOutput is the output of the archive node.
+type ArchiveOutput struct {
+	StepTiming
+	Destination  string `json:"destination"`
+	Content      string `json:"content"`
+	BytesWritten int    `json:"bytesWritten,omitempty"`
+}
+
+// HistoryOutput is the output of the history node.
+type HistoryOutput struct {
+	StepTiming
+	Found       bool           `json:"found"`
+	ExecutionID string         `json:"executionId,omitempty"`
+	ExecutedAt  string         `json:"executedAt,omitempty"`
+	Values      map[string]any `json:"values,omitempty"`  // the loaded values by context key
+	Missing     []string       `json:"missing,omitempty"` // the mapped keys the previous execution has no value for
+}
+
+// HTTPRequestOutput is the output of the http-request node.
+type HTTPRequestOutput struct {
+	StepTiming
+	Method    string         `json:"method,omitempty"`
+	URL       string         `json:"url,omitempty"`
+	Status    int            `json:"status,omitempty"`
+	Body      any            `json:"body,omitempty"`
+	Extracted map[string]any `json:"extracted,omitempty"`
+}
+
+// stepOutput converts the typed output built by a processor into the StepResult.Output map and passes the
+// processor error through. The zero StepTiming fields are overwritten by processNodes.
+func stepOutput(output any, err error) (map[string]interface{}, error) {
+	data, marshalErr := json.Marshal(output)
+	if marshalErr == nil {
+		var m map[string]interface{}
+		if marshalErr = json.Unmarshal(data, &m); marshalErr == nil {
+			return m, err
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("%w: %v", ErrMarshalFailed, marshalErr)
+	}
+	return nil, err
+}
+
+// DecodeOutput decodes the step output into v, e.g one of the typed node outputs.
+func (s StepResult) DecodeOutput(v any) error {
+	data, err := json.Marshal(s.Output)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMarshalFailed, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("step %s output: %w", s.NodeID, err)
+	}
+	return nil
+}
+
+// FormOutput returns the output of a form node step.
+func (s StepResult) FormOutput() (FormOutput, error) {
+	var output FormOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// WeatherOutput returns the output of a weather node step.
+func (s StepResult) WeatherOutput() (WeatherOutput, error) {
+	var output WeatherOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// ConditionOutput returns the output of a condition node step.
+func (s StepResult) ConditionOutput() (ConditionOutput, error) {
+	var output ConditionOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// EmailOutput returns the output of an email node step.
+func (s StepResult) EmailOutput() (EmailOutput, error) {
+	var output EmailOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// LoopOutput returns the output of a loop node step.
+func (s StepResult) LoopOutput() (LoopOutput, error) {
+	var output LoopOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// TransformOutput returns the output of a transform node step.
+func (s StepResult) TransformOutput() (TransformOutput, error) {
+	var output TransformOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// MetricsOutput returns the output of a metrics node step.
+func (s StepResult) MetricsOutput() (MetricsOutput, error) {
+	var output MetricsOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// SMSOutput returns the output of an sms node step.
+func (s StepResult) SMSOutput() (SMSOutput, error) {
+	var output SMSOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// SlackOutput returns the output of a slack node step.
+func (s StepResult) SlackOutput() (SlackOutput, error) {
+	var output SlackOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// ArchiveOutput returns the output of an archive node step.
+func (s StepResult) ArchiveOutput() (ArchiveOutput, error) {
+	var output ArchiveOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// HistoryOutput returns the output of a history node step.
+func (s StepResult) HistoryOutput() (HistoryOutput, error) {
+	var output HistoryOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
+
+// HTTPRequestOutput returns the output of an http-request node step.
+func (s StepResult) HTTPRequestOutput() (HTTPRequestOutput, error) {
+	var output HTTPRequestOutput
+	err := s.DecodeOutput(&output)
+	return output, err
+}
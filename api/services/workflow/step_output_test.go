@@ -0,0 +1,180 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepResultTypedOutputs(t *testing.T) {
+	readingTime := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		contextData.SetFloat(weatherContextKey(WeatherMetricTemperature), 25)
+		contextData.Set(weatherContextKey(weatherReadingTimeKey), readingTime)
+		return nil
+	}
+	processEmailNodeFn = func(ctx context.Context, sender EmailSender, msg EmailMessage) error { return nil }
+	defer func() {
+		processWeatherNodeFn = processWeatherNode
+		processEmailNodeFn = processEmailNode
+	}()
+
+	wf := &WorkflowDefinition{
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: FormNodeID},
+			{ID: WeatherAPINodeID, Data: NodeData{Metadata: NodeMetadata{MaxAgeMinutes: 30}}},
+			{ID: ConditionNodeID},
+			{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{Subject: "Alert", Body: "{{city}} is {{temperature}}°C"}}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: FormNodeID},
+			{Source: FormNodeID, Target: WeatherAPINodeID},
+			{Source: WeatherAPINodeID, Target: ConditionNodeID},
+			{Source: ConditionNodeID, Target: EmailNodeID, SourceHandle: ConditionMetHandle},
+			{Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+			{Source: EmailNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{
+		FormData:  FormData{Name: "Jane", Email: "jane@example.com", City: "Sydney", Country: "AU"},
+		Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+	}
+
+	got, err := processNodes(context.Background(), wf, payload, ExecutionOptions{DefaultEmailFrom: "alerts@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Len(t, got.Steps, 6)
+
+	form, err := got.Steps[1].FormOutput()
+	require.NoError(t, err)
+	require.Equal(t, "Sydney", form.City)
+	require.Equal(t, "AU", form.Country)
+
+	weather, err := got.Steps[2].WeatherOutput()
+	require.NoError(t, err)
+	require.Equal(t, "Sydney", weather.Location)
+	require.Equal(t, 25.0, *weather.Temperature)
+	require.Nil(t, weather.Humidity)
+	require.Equal(t, readingTime, weather.ReadingTime)
+	require.Contains(t, weather.Warning, ErrStaleWeatherData.Error())
+
+	condition, err := got.Steps[3].ConditionOutput()
+	require.NoError(t, err)
+	require.True(t, condition.ConditionMet)
+	require.Equal(t, ConditionMetHandle, condition.SourceHandle)
+	require.Equal(t, 25.0, condition.ActualValue)
+
+	email, err := got.Steps[4].EmailOutput()
+	require.NoError(t, err)
+	require.True(t, email.EmailSent)
+	require.Equal(t, "Sydney is 25.0°C", email.EmailDraft.Body)
+
+	// the typed outputs marshal into the same JSON as the step output
+	typed := []any{form, weather, condition, email}
+	for i, output := range typed {
+		want, err := json.Marshal(got.Steps[i+1].Output)
+		require.NoError(t, err)
+		gotJSON, err := json.Marshal(output)
+		require.NoError(t, err)
+		require.JSONEq(t, string(want), string(gotJSON))
+	}
+}
+
+func TestStepResultTypedOutputsOtherNodes(t *testing.T) {
+	processWeatherNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context, weatherAPI WeatherAPIConfig) error {
+		contextData.SetFloat(weatherContextKey(WeatherMetricTemperature), 21.5)
+		return nil
+	}
+	processHTTPRequestNodeFn = func(ctx context.Context, node Node, payload *ExecutePayload, contextData *Context) error {
+		contextData.Set(nodeContextKey(node, "status"), 200)
+		contextData.Set(nodeContextKey(node, "body"), map[string]any{"level": "high"})
+		contextData.Set("level", "high")
+		return nil
+	}
+	processSMSNodeFn = func(node Node, to string, message string) error { return nil }
+	defer func() {
+		processWeatherNodeFn = processWeatherNode
+		processHTTPRequestNodeFn = processHTTPRequestNode
+		processSMSNodeFn = processSMSNode
+	}()
+
+	wf := &WorkflowDefinition{
+		ID: "wf-1",
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: LoopNodeID, Data: NodeData{Metadata: NodeMetadata{Options: []CityCoordinates{{City: "Sydney"}, {City: "Perth"}}}}},
+			{ID: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "doubled = 2 * 21.5"}}},
+			{ID: MetricsNodeID},
+			{ID: HTTPRequestNodeID, Data: NodeData{Metadata: NodeMetadata{HTTPRequest: &HTTPRequest{URL: "https://example.com/levels", Extract: map[string]string{"level": "$.level"}}}}},
+			{ID: HistoryNodeID, Data: NodeData{Metadata: NodeMetadata{History: &HistoryConfig{Values: map[string]string{"previousDoubled": "doubled", "previousLevel": "level"}}}}},
+			{ID: SMSNodeID},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: LoopNodeID},
+			{Source: LoopNodeID, Target: TransformNodeID},
+			{Source: TransformNodeID, Target: MetricsNodeID},
+			{Source: MetricsNodeID, Target: HTTPRequestNodeID},
+			{Source: HTTPRequestNodeID, Target: HistoryNodeID},
+			{Source: HistoryNodeID, Target: SMSNodeID},
+			{Source: SMSNodeID, Target: EndNodeID},
+		},
+	}
+	payload := &ExecutePayload{FormData: FormData{City: "Sydney", Phone: "+61400000000"}}
+	opts := ExecutionOptions{
+		LastExecution: func(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+			return &ExecutionRecord{ID: "exec-1", Result: ExecutionResult{ExecutedAt: "2025-01-01T00:00:00Z"}, Context: map[string]any{"doubled": 40.0}}, nil
+		},
+	}
+
+	got, err := processNodes(context.Background(), wf, payload, opts)
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Len(t, got.Steps, 8)
+
+	loop, err := got.Steps[1].LoopOutput()
+	require.NoError(t, err)
+	require.Len(t, loop.Iterations, 2)
+	require.Equal(t, "Perth", loop.Iterations[1].Location)
+	require.Equal(t, 21.5, *loop.Iterations[1].Temperature)
+
+	transform, err := got.Steps[2].TransformOutput()
+	require.NoError(t, err)
+	require.Equal(t, TransformOutput{StepTiming: transform.StepTiming, Key: "doubled", Value: 43}, transform)
+
+	metrics, err := got.Steps[3].MetricsOutput()
+	require.NoError(t, err)
+	require.Equal(t, 3, metrics.StepCount)
+
+	httpRequest, err := got.Steps[4].HTTPRequestOutput()
+	require.NoError(t, err)
+	require.Equal(t, 200, httpRequest.Status)
+	require.Equal(t, map[string]any{"level": "high"}, httpRequest.Extracted)
+
+	history, err := got.Steps[5].HistoryOutput()
+	require.NoError(t, err)
+	require.True(t, history.Found)
+	require.Equal(t, "exec-1", history.ExecutionID)
+	require.Equal(t, map[string]any{"previousDoubled": 40.0}, history.Values)
+	require.Equal(t, []string{"previousLevel"}, history.Missing)
+
+	sms, err := got.Steps[6].SMSOutput()
+	require.NoError(t, err)
+	require.True(t, sms.SMSSent)
+	require.Equal(t, "+61400000000", sms.SMS.To)
+
+	// the typed outputs marshal into the same JSON as the step output
+	typed := []any{loop, transform, metrics, httpRequest, history, sms}
+	for i, output := range typed {
+		want, err := json.Marshal(got.Steps[i+1].Output)
+		require.NoError(t, err)
+		gotJSON, err := json.Marshal(output)
+		require.NoError(t, err)
+		require.JSONEq(t, string(want), string(gotJSON))
+	}
+}
@@ -0,0 +1,147 @@
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// this file template.go contains the placeholder rendering used by the email (subject and body) and http-request nodes
+// and the condition node message.
+
+const (
+	// MissingPlaceholderKeep leaves placeholders without a value intact (default).
+	MissingPlaceholderKeep = "keep"
+	// MissingPlaceholderEmpty replaces placeholders without a value with an empty string.
+	MissingPlaceholderEmpty = "empty"
+)
+
+// placeholderPattern matches a {{key}} placeholder, e.g {{city}} or {{weather.windspeed}}.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// renderPlaceholders substitutes every {{key}} placeholder in the template with its value from the form data
+// or contextData. Keys are resolved in this order:
+//   - form data fields (name, email, city, operator, threshold)
+//   - the exact contextData key (e.g weather.windspeed)
+//   - the weather metric of the same name (e.g temperature resolves to weather.temperature)
+//
+// Placeholders without a value are left intact or replaced with an empty string depending on the mode.
+func renderPlaceholders(tmpl string, payload *ExecutePayload, contextData *Context, mode string) string {
+	return placeholderPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		key := placeholderPattern.FindStringSubmatch(match)[1]
+
+		value, ok := placeholderValue(key, payload, contextData)
+		if !ok {
+			if mode == MissingPlaceholderEmpty {
+				return ""
+			}
+			return match
+		}
+		return value
+	})
+}
+
+// placeholderValue looks up and formats the value for a placeholder key.
+func placeholderValue(key string, payload *ExecutePayload, contextData *Context) (string, bool) {
+	formValues := map[string]string{
+		"name":  payload.FormData.Name,
+		"email": payload.FormData.Email,
+		"city":  payload.FormData.City,
+	}
+	if v, ok := formValues[key]; ok && v != "" {
+		return v, true
+	}
+
+	condition := payload.resolvedCondition()
+	switch key {
+	case "operator":
+		if condition.Operator != "" {
+			return condition.Operator, true
+		}
+	case "threshold":
+		return formatPlaceholderValue(condition.Threshold), true
+	}
+
+	if v, ok := contextData.Get(key); ok && v != nil {
+		return formatPlaceholderValue(v), true
+	}
+	if v, ok := contextData.Get(weatherContextKey(key)); ok && v != nil {
+		return formatPlaceholderValue(v), true
+	}
+
+	return "", false
+}
+
+// formatPlaceholderValue formats a value for a template. Floats keep one decimal place (e.g 21.0).
+func formatPlaceholderValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', 1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', 1, 32)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// formatConditionNumber formats a number of the condition message with the fewest decimals representing it
+// exactly, without trailing zeros (e.g 21, 21.25), so a threshold isn't rounded.
+func formatConditionNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+const (
+	// defaultConditionMessageTemplate renders e.g "Temperature 21.5°C is greater than 20°C - condition met".
+	defaultConditionMessageTemplate = "{{label}} {{value}}{{unit}} is {{operator}} {{threshold}}{{unit}} - {{result}}"
+	// defaultBetweenMessageTemplate renders e.g "Temperature 21.5°C is between 18°C and 24°C - condition met".
+	defaultBetweenMessageTemplate = "{{label}} {{value}}{{unit}} is between {{threshold}}{{unit}} and {{upperThreshold}}{{unit}} - {{result}}"
+)
+
+// conditionMessageValues are the values available to a condition message template.
+type conditionMessageValues struct {
+	Label     string // e.g "Temperature"
+	Value     float64
+	Unit      string // e.g "°C"
+	Condition Condition
+	Result    string // ConditionMetString or ConditionNotMetString
+}
+
+// conditionMessage renders the human readable condition node message. The template can use the
+// {{label}}, {{value}}, {{unit}}, {{operator}}, {{threshold}}, {{upperThreshold}} and {{result}} placeholders,
+// unknown placeholders are left intact. An empty template uses the default wording for the operator.
+func conditionMessage(tmpl string, v conditionMessageValues) string {
+	if tmpl == "" {
+		tmpl = defaultConditionMessageTemplate
+		if v.Condition.Operator == OperatorBetween {
+			tmpl = defaultBetweenMessageTemplate
+		}
+	}
+
+	values := map[string]string{
+		"label":          v.Label,
+		"value":          formatConditionNumber(v.Value),
+		"unit":           v.Unit,
+		"operator":       strings.ReplaceAll(v.Condition.Operator, "_", " "),
+		"threshold":      formatConditionNumber(v.Condition.Threshold),
+		"upperThreshold": formatConditionNumber(v.Condition.UpperThreshold),
+		"result":         v.Result,
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		key := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// renderEmailTemplate renders the placeholders of both the subject and the body of the email node template.
+func renderEmailTemplate(node Node, payload *ExecutePayload, contextData *Context) (subject, body string) {
+	tmpl := node.Data.Metadata.EmailTemplate
+	mode := node.Data.Metadata.MissingPlaceholders
+	return renderPlaceholders(tmpl.Subject, payload, contextData, mode), renderPlaceholders(tmpl.Body, payload, contextData, mode)
+}
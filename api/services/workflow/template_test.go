@@ -0,0 +1,117 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPlaceholders(t *testing.T) {
+	payload := &ExecutePayload{FormData: FormData{Name: "Jane", Email: "jane@example.com", City: "Sydney"}}
+	contextData := map[string]any{
+		"weather.temperature": 21.0,
+		"weather.windspeed":   12.5,
+		"webhook.status":      200,
+	}
+
+	tests := []struct {
+		label string
+		tmpl  string
+		mode  string
+		want  string
+	}{
+		{
+			label: "form data and weather metrics",
+			tmpl:  "Hi {{name}}, {{city}} is {{temperature}}°C with {{ windspeed }} km/h winds",
+			want:  "Hi Jane, Sydney is 21.0°C with 12.5 km/h winds",
+		},
+		{
+			label: "full context keys",
+			tmpl:  "status {{webhook.status}}, wind {{weather.windspeed}}",
+			want:  "status 200, wind 12.5",
+		},
+		{
+			label: "missing key kept by default",
+			tmpl:  "humidity {{humidity}}%",
+			want:  "humidity {{humidity}}%",
+		},
+		{
+			label: "missing key replaced with empty string",
+			tmpl:  "humidity {{humidity}}%",
+			mode:  MissingPlaceholderEmpty,
+			want:  "humidity %",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			require.Equal(t, tt.want, renderPlaceholders(tt.tmpl, payload, newContextFrom(contextData), tt.mode))
+		})
+	}
+}
+
+func TestRenderEmailTemplate(t *testing.T) {
+	node := Node{ID: EmailNodeID, Data: NodeData{Metadata: NodeMetadata{EmailTemplate: &EmailTemplate{
+		Subject: "Weather alert for {{city}}: {{temperature}}°C",
+		Body:    "Hi {{name}}, it's {{temperature}}°C in {{city}}",
+	}}}}
+	payload := &ExecutePayload{FormData: FormData{Name: "Jane", City: "Sydney"}}
+	contextData := newContextFrom(map[string]any{"weather.temperature": 31.0})
+
+	subject, body := renderEmailTemplate(node, payload, contextData)
+	// the email keeps the one decimal formatting, only the condition message drops trailing zeros
+	require.Equal(t, "Weather alert for Sydney: 31.0°C", subject)
+	require.Equal(t, "Hi Jane, it's 31.0°C in Sydney", body)
+}
+
+func TestConditionMessage(t *testing.T) {
+	tests := []struct {
+		label  string
+		tmpl   string
+		values conditionMessageValues
+		want   string
+	}{
+		{
+			label: "default template",
+			values: conditionMessageValues{
+				Label: "Temperature", Value: 21, Unit: "°C",
+				Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+				Result:    ConditionMetString,
+			},
+			want: "Temperature 21°C is greater than 20°C - condition met",
+		},
+		{
+			label: "default between template",
+			values: conditionMessageValues{
+				Label: "Temperature", Value: 15, Unit: "°C",
+				Condition: Condition{Operator: OperatorBetween, Threshold: 18, UpperThreshold: 24},
+				Result:    ConditionNotMetString,
+			},
+			want: "Temperature 15°C is between 18°C and 24°C - condition not met",
+		},
+		{
+			label: "custom template and unit",
+			tmpl:  "Il fait {{value}}{{unit}} (seuil {{threshold}}{{unit}}) {{unknown}}",
+			values: conditionMessageValues{
+				Label: "Temperature", Value: 70.2, Unit: "°F",
+				Condition: Condition{Operator: OperatorLessThan, Threshold: 68},
+			},
+			want: "Il fait 70.2°F (seuil 68°F) {{unknown}}",
+		},
+		{
+			label: "decimals keep their precision",
+			values: conditionMessageValues{
+				Label: "Temperature", Value: 21.3, Unit: "°C",
+				Condition: Condition{Operator: OperatorLessThan, Threshold: 21.25},
+				Result:    ConditionNotMetString,
+			},
+			want: "Temperature 21.3°C is less than 21.25°C - condition not met",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			require.Equal(t, tt.want, conditionMessage(tt.tmpl, tt.values))
+		})
+	}
+}
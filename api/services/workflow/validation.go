@@ -0,0 +1,475 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// this file validation.go contains the structural checks run against a workflow definition.
+
+// ValidateWorkflow runs every structural check against the workflow graph and returns the first error found:
+//   - the start and end nodes exist, as well as the entry node when one is set
+//   - every edge connects two existing nodes
+//   - no edge enters the start node or leaves the end node
+//   - every condition node has exactly one edge per outcome handle, or a default edge for the others
+//   - the graph has no cycles
+//   - every context key read by a condition node is produced by a node upstream of it
+//   - every JSONPath extracted by an http-request node is in the supported subset (see jsonpath.go)
+//
+// It doesn't execute any node, so it can be used before persisting a definition.
+func ValidateWorkflow(wf *WorkflowDefinition) error {
+	nodeMap := make(map[string]Node)
+	for _, node := range wf.Nodes {
+		nodeMap[node.ID] = node
+	}
+
+	if _, ok := nodeMap[StartNodeID]; !ok {
+		return ErrMissingStartNode
+	}
+	if _, ok := nodeMap[EndNodeID]; !ok {
+		return ErrMissingEndNode
+	}
+	if _, ok := nodeMap[wf.EntryNodeID]; wf.EntryNodeID != "" && !ok {
+		return fmt.Errorf("%w: %s", ErrEntryNodeNotFound, wf.EntryNodeID)
+	}
+
+	for _, edge := range wf.Edges {
+		if _, ok := nodeMap[edge.Source]; !ok {
+			return fmt.Errorf("%w: edge %s has unknown source %s", ErrDanglingEdge, edge.ID, edge.Source)
+		}
+		if _, ok := nodeMap[edge.Target]; !ok {
+			return fmt.Errorf("%w: edge %s has unknown target %s", ErrDanglingEdge, edge.ID, edge.Target)
+		}
+		// start must be the entry point and end the exit point of the graph
+		if edge.Target == StartNodeID {
+			return fmt.Errorf("%w: node %s (edge %s)", ErrStartNodeIncomingEdge, edge.Target, edge.ID)
+		}
+		if edge.Source == EndNodeID {
+			return fmt.Errorf("%w: node %s (edge %s)", ErrEndNodeOutgoingEdge, edge.Source, edge.ID)
+		}
+	}
+
+	if err := validateConditionRoutes(wf); err != nil {
+		return err
+	}
+
+	if err := validateAcyclic(wf); err != nil {
+		return err
+	}
+
+	if err := validateExtractPaths(wf); err != nil {
+		return err
+	}
+
+	return validateContextDependencies(wf)
+}
+
+// validateExtractPaths checks the JSONPaths of the http-request nodes parse, so an unsupported expression (e.g a
+// filter) is rejected with the workflow rather than failing the node as a path not found in the response.
+func validateExtractPaths(wf *WorkflowDefinition) error {
+	for _, node := range wf.Nodes {
+		cfg := node.Data.Metadata.HTTPRequest
+		nodeType, _ := resolveNodeType(node, builtinProcessors(ExecutionOptions{}))
+		if cfg == nil || nodeType != HTTPRequestNodeID || hasCustomProcessor(nodeType) {
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(cfg.Extract)) {
+			if _, err := parseJSONPath(cfg.Extract[key]); err != nil {
+				return fmt.Errorf("node %s extract %s: %w", node.ID, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateContextDependencies checks the context keys read by every condition node are produced by one of its
+// upstream nodes, e.g a condition on "weather.humidity" needs a weather node recording the humidity metric.
+// Conditions reading their value from the payload are skipped, and so are conditions with an upstream node
+// whose produced keys are unknown (e.g a custom node type), as it may produce any key.
+func validateContextDependencies(wf *WorkflowDefinition) error {
+	nodeMap := make(map[string]Node)
+	for _, node := range wf.Nodes {
+		nodeMap[node.ID] = node
+	}
+	// reverse adjacency map (targetID > list of sourceIDs) to walk the graph upstream
+	parents := make(map[string][]string)
+	for _, edge := range wf.Edges {
+		parents[edge.Target] = append(parents[edge.Target], edge.Source)
+	}
+
+	for _, node := range wf.Nodes {
+		if node.ID != ConditionNodeID && node.Type != ConditionNodeID {
+			continue
+		}
+		if node.Data.Metadata.ValueSource == ConditionValueSourcePayload {
+			continue
+		}
+
+		produced, known := upstreamContextKeys(node.ID, nodeMap, parents)
+		if !known {
+			continue
+		}
+
+		for _, keys := range conditionInputKeys(node) {
+			if !slices.ContainsFunc(keys, func(key string) bool { return produced[key] }) {
+				return fmt.Errorf("%w: node %s reads %q", ErrMissingContextDependency, node.ID, keys[0])
+			}
+		}
+	}
+
+	return nil
+}
+
+// upstreamContextKeys returns the context keys produced by the nodes upstream of the node.
+// known is false when an upstream node may produce keys that can't be determined from its definition.
+func upstreamContextKeys(id string, nodeMap map[string]Node, parents map[string][]string) (produced map[string]bool, known bool) {
+	produced = make(map[string]bool)
+	visited := map[string]bool{id: true}
+	queue := append([]string(nil), parents[id]...)
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		if visited[parentID] {
+			continue
+		}
+		visited[parentID] = true
+
+		keys, ok := producedContextKeys(nodeMap[parentID])
+		if !ok {
+			return nil, false
+		}
+		for _, key := range keys {
+			produced[key] = true
+		}
+		queue = append(queue, parents[parentID]...)
+	}
+	return produced, true
+}
+
+// producedContextKeys returns the contextData keys a node sets when it runs, including the keys it declares
+// in its output variables. ok is false for nodes whose keys are unknown (custom processors, unknown types).
+func producedContextKeys(node Node) (keys []string, ok bool) {
+	keys = append(keys, node.Data.Metadata.OutputVariables...)
+
+	nodeType, builtin := resolveNodeType(node, builtinProcessors(ExecutionOptions{}))
+	if !builtin || hasCustomProcessor(nodeType) {
+		return keys, false
+	}
+
+	switch nodeType {
+	case WeatherAPINodeID:
+		for _, metric := range weatherMetrics(node) {
+			keys = append(keys, weatherContextKey(metric))
+		}
+	case LoopNodeID:
+		keys = append(keys, nodeContextKey(node, "results"))
+		for _, metric := range weatherMetrics(node) {
+			keys = append(keys, nodeContextKey(node, metric))
+		}
+	case TransformNodeID:
+		if key, _ := transformOutputKey(node); key != "" {
+			keys = append(keys, key)
+		}
+	case MetricsNodeID:
+		for _, name := range []string{"stepCount", "failedSteps", "elapsedMs", "startedAt"} {
+			keys = append(keys, nodeContextKey(node, name))
+		}
+	case HistoryNodeID:
+		if cfg := node.Data.Metadata.History; cfg != nil {
+			for key := range cfg.Values {
+				keys = append(keys, key)
+			}
+		}
+	case HTTPRequestNodeID:
+		keys = append(keys, nodeContextKey(node, "status"), nodeContextKey(node, "body"))
+		if cfg := node.Data.Metadata.HTTPRequest; cfg != nil {
+			for key := range cfg.Extract {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, true
+}
+
+// validateConditionRoutes checks every condition node has exactly one outgoing edge per outcome handle, otherwise
+// the route taken would depend on the edge order. The outcomes are the clause handles when the node defines
+// condition clauses, or the met and not met handles. A node with a (single) default edge may leave outcomes
+// without an edge, they are routed to the default edge.
+func validateConditionRoutes(wf *WorkflowDefinition) error {
+	for _, node := range wf.Nodes {
+		if node.ID != ConditionNodeID && node.Type != ConditionNodeID {
+			continue
+		}
+
+		handles := []string{ConditionMetHandle, ConditionNotMetHandle}
+		if clauses := node.Data.Metadata.Conditions; len(clauses) > 0 {
+			handles = handles[:0]
+			for _, clause := range clauses {
+				handles = append(handles, clause.SourceHandle)
+			}
+		}
+
+		// a default edge catches the outcomes without an edge of their own
+		var defaultEdgeIDs []string
+		for _, edge := range wf.Edges {
+			if edge.Source == node.ID && edge.SourceHandle == ConditionDefaultHandle {
+				defaultEdgeIDs = append(defaultEdgeIDs, edge.ID)
+			}
+		}
+		if len(defaultEdgeIDs) > 1 {
+			return fmt.Errorf("%w: node %s has edges %s for handle %q", ErrConflictingConditionRoutes, node.ID, strings.Join(defaultEdgeIDs, ", "), ConditionDefaultHandle)
+		}
+
+		for _, handle := range handles {
+			var edgeIDs []string
+			for _, edge := range wf.Edges {
+				if edge.Source == node.ID && conditionEdgeMatches(edge, handle) {
+					edgeIDs = append(edgeIDs, edge.ID)
+				}
+			}
+
+			switch {
+			case len(edgeIDs) == 0 && len(defaultEdgeIDs) == 0:
+				return fmt.Errorf("%w: node %s has no edge for handle %q", ErrMissingConditionRoute, node.ID, handle)
+			case len(edgeIDs) > 1:
+				return fmt.Errorf("%w: node %s has edges %s for handle %q", ErrConflictingConditionRoutes, node.ID, strings.Join(edgeIDs, ", "), handle)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateAcyclic returns ErrCycleDetected when the graph contains a cycle, naming a node on the cycle.
+func validateAcyclic(wf *WorkflowDefinition) error {
+	adj := make(map[string][]string)
+	for _, edge := range wf.Edges {
+		adj[edge.Source] = append(adj[edge.Source], edge.Target)
+	}
+
+	// nodes are unvisited, in progress (on the current DFS path) or done
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+
+	// the DFS is iterative so very long workflows can't overflow the goroutine stack.
+	// each frame is a node on the current path and the index of its next edge to explore.
+	type frame struct {
+		id   string
+		edge int
+	}
+	visit := func(id string) error {
+		state[id] = inProgress
+		path := []frame{{id: id}}
+		for len(path) > 0 {
+			top := &path[len(path)-1]
+			if top.edge == len(adj[top.id]) {
+				state[top.id] = done
+				path = path[:len(path)-1]
+				continue
+			}
+			next := adj[top.id][top.edge]
+			top.edge++
+
+			switch state[next] {
+			case inProgress:
+				return fmt.Errorf("%w: edge %s -> %s closes a cycle", ErrCycleDetected, top.id, next)
+			case unvisited:
+				state[next] = inProgress
+				path = append(path, frame{id: next})
+			}
+		}
+		return nil
+	}
+
+	for _, node := range wf.Nodes {
+		if state[node.ID] == unvisited {
+			if err := visit(node.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidationResult is the outcome of validating a workflow definition without saving it.
+type ValidationResult struct {
+	Valid  bool              `json:"valid"`
+	Errors []ValidationIssue `json:"errors,omitempty"`
+}
+
+// ValidationIssue is a single problem found in a workflow definition.
+type ValidationIssue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// validateDefinition runs every check against the raw definition and collects the problems found:
+// the schema checks (ValidateWorkflowSchema), the structural checks (ValidateWorkflow) and unreachable nodes.
+func validateDefinition(definition []byte) (ValidationResult, error) {
+	var wf WorkflowDefinition
+	if err := json.Unmarshal(definition, &wf); err != nil {
+		return ValidationResult{}, ErrInvalidJSON
+	}
+
+	var issues []ValidationIssue
+	addIssue := func(err error) {
+		issues = append(issues, ValidationIssue{Code: errorCode(err), Message: err.Error()})
+	}
+
+	if err := ValidateWorkflowSchema(definition); err != nil {
+		var validationErrs ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, fe := range validationErrs {
+				issues = append(issues, ValidationIssue{Code: fe.Code, Message: fe.Message, Field: fe.Field})
+			}
+		} else {
+			addIssue(err)
+		}
+	}
+	if err := ValidateWorkflow(&wf); err != nil {
+		addIssue(err)
+	}
+	if unreachable := unreachableNodes(&wf); len(unreachable) > 0 {
+		addIssue(fmt.Errorf("%w: %s", ErrUnreachableNodes, strings.Join(unreachable, ", ")))
+	}
+
+	return ValidationResult{Valid: len(issues) == 0, Errors: issues}, nil
+}
+
+// unreachableNodes returns the ids of the nodes no path from the start node leads to, in definition order.
+// Nodes on a condition branch that wasn't taken are still reachable.
+func unreachableNodes(wf *WorkflowDefinition) []string {
+	adj := make(map[string][]string)
+	for _, edge := range wf.Edges {
+		adj[edge.Source] = append(adj[edge.Source], edge.Target)
+	}
+
+	reachable := map[string]bool{StartNodeID: true}
+	queue := []string{StartNodeID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[id] {
+			if !reachable[next] {
+				reachable[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	var unreachable []string
+	for _, node := range wf.Nodes {
+		if !reachable[node.ID] {
+			unreachable = append(unreachable, node.ID)
+		}
+	}
+	return unreachable
+}
+
+// topologicalOrder returns the position of every node in a topological order of the graph (Kahn's algorithm):
+// a node always comes after the nodes with an edge into it. Ties are broken by the definition order so the
+// order is stable. The graph must be acyclic, nodes on a cycle are left out.
+func topologicalOrder(wf *WorkflowDefinition) map[string]int {
+	adj := make(map[string][]string)
+	inDegree := make(map[string]int)
+	for _, edge := range wf.Edges {
+		adj[edge.Source] = append(adj[edge.Source], edge.Target)
+		inDegree[edge.Target]++
+	}
+
+	var queue []string
+	for _, node := range wf.Nodes {
+		if inDegree[node.ID] == 0 {
+			queue = append(queue, node.ID)
+		}
+	}
+
+	order := make(map[string]int, len(wf.Nodes))
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order[id] = len(order)
+		for _, next := range adj[id] {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	return order
+}
+
+// ValidateWorkflowSchema checks the raw definition JSON has every required field before it is persisted:
+//   - the definition is an object with an id and nodes/edges arrays
+//   - every node has a unique id and a type
+//   - every edge has a source and a target
+//
+// Definitions that unmarshal into partially populated structs are rejected with ValidationErrors
+// naming each invalid field (e.g "nodes[2].type").
+func ValidateWorkflowSchema(definition []byte) error {
+	var raw struct {
+		ID    any               `json:"id"`
+		Nodes *[]map[string]any `json:"nodes"`
+		Edges *[]map[string]any `json:"edges"`
+	}
+	if err := json.Unmarshal(definition, &raw); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWorkflowFormat, err)
+	}
+
+	var errs ValidationErrors
+
+	if !isNonEmptyString(raw.ID) {
+		errs = append(errs, newFieldError("id", ErrMissingRequiredField))
+	}
+
+	if raw.Nodes == nil {
+		errs = append(errs, newFieldError("nodes", ErrMissingRequiredField))
+	} else {
+		seen := make(map[string]bool)
+		for i, node := range *raw.Nodes {
+			if !isNonEmptyString(node["id"]) {
+				errs = append(errs, newFieldError(fmt.Sprintf("nodes[%d].id", i), ErrMissingRequiredField))
+			} else if id := node["id"].(string); seen[id] {
+				errs = append(errs, newFieldError(fmt.Sprintf("nodes[%d].id", i), fmt.Errorf("%w: %s", ErrDuplicateNodeID, id)))
+			} else {
+				seen[id] = true
+			}
+			if !isNonEmptyString(node["type"]) {
+				errs = append(errs, newFieldError(fmt.Sprintf("nodes[%d].type", i), ErrMissingRequiredField))
+			}
+		}
+	}
+
+	if raw.Edges == nil {
+		errs = append(errs, newFieldError("edges", ErrMissingRequiredField))
+	} else {
+		for i, edge := range *raw.Edges {
+			for _, key := range []string{"source", "target"} {
+				if !isNonEmptyString(edge[key]) {
+					errs = append(errs, newFieldError(fmt.Sprintf("edges[%d].%s", i, key), ErrMissingRequiredField))
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// isNonEmptyString reports whether the decoded JSON value is a non-empty string.
+func isNonEmptyString(v any) bool {
+	str, ok := v.(string)
+	return ok && str != ""
+}
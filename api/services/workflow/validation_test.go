@@ -0,0 +1,375 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWorkflow(t *testing.T) {
+	tests := []struct {
+		label       string
+		workflow    *WorkflowDefinition
+		expectErr   bool
+		errExpected error
+	}{
+		{
+			label: "success: start -> end",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{{ID: "e1", Source: StartNodeID, Target: EndNodeID}},
+			},
+		},
+		{
+			label: "error: missing start node",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: EndNodeID}},
+			},
+			expectErr:   true,
+			errExpected: ErrMissingStartNode,
+		},
+		{
+			label: "error: missing end node",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}},
+			},
+			expectErr:   true,
+			errExpected: ErrMissingEndNode,
+		},
+		{
+			label: "success: entry node",
+			workflow: &WorkflowDefinition{
+				Nodes:       []Node{{ID: StartNodeID}, {ID: MetricsNodeID}, {ID: EndNodeID}},
+				Edges:       []Edge{{ID: "e1", Source: StartNodeID, Target: MetricsNodeID}, {ID: "e2", Source: MetricsNodeID, Target: EndNodeID}},
+				EntryNodeID: MetricsNodeID,
+			},
+		},
+		{
+			label: "error: unknown entry node",
+			workflow: &WorkflowDefinition{
+				Nodes:       []Node{{ID: StartNodeID}, {ID: EndNodeID}},
+				Edges:       []Edge{{ID: "e1", Source: StartNodeID, Target: EndNodeID}},
+				EntryNodeID: "missing",
+			},
+			expectErr:   true,
+			errExpected: ErrEntryNodeNotFound,
+		},
+		{
+			label: "error: edge with unknown target",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{{ID: "e1", Source: StartNodeID, Target: "missing"}},
+			},
+			expectErr:   true,
+			errExpected: ErrDanglingEdge,
+		},
+		{
+			label: "error: edge into start node",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: FormNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: FormNodeID},
+					{ID: "e2", Source: FormNodeID, Target: StartNodeID},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrStartNodeIncomingEdge,
+		},
+		{
+			label: "error: edge out of end node",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: FormNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: EndNodeID},
+					{ID: "e2", Source: EndNodeID, Target: FormNodeID},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrEndNodeOutgoingEdge,
+		},
+		{
+			label: "success: condition node with one edge per handle",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: WeatherAPINodeID}, {ID: ConditionNodeID}, {ID: EmailNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{
+					{ID: "e0", Source: StartNodeID, Target: WeatherAPINodeID},
+					{ID: "e1", Source: WeatherAPINodeID, Target: ConditionNodeID},
+					{ID: "e2", Source: ConditionNodeID, Target: EmailNodeID, SourceHandle: ConditionMetHandle},
+					{ID: "e3", Source: ConditionNodeID, Target: EndNodeID, Label: "✗ No Alert Needed"},
+					{ID: "e4", Source: EmailNodeID, Target: EndNodeID},
+				},
+			},
+		},
+		{
+			label: "error: condition node with two edges for the same handle",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: ConditionNodeID}, {ID: EmailNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: ConditionNodeID},
+					{ID: "e2", Source: ConditionNodeID, Target: EmailNodeID, Label: "✓ Condition Met"},
+					{ID: "e3", Source: ConditionNodeID, Target: EndNodeID, Label: "✓ Condition Met"},
+					{ID: "e4", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+					{ID: "e5", Source: EmailNodeID, Target: EndNodeID},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrConflictingConditionRoutes,
+		},
+		{
+			label: "error: condition clause without an edge",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: "check", Type: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{Conditions: []ConditionClause{
+						{Operator: OperatorLessThan, Threshold: 10, SourceHandle: "cold"},
+						{Operator: OperatorGreaterThanOrEqual, Threshold: 10, SourceHandle: "warm"},
+					}}}},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: "check"},
+					{ID: "e2", Source: "check", Target: EndNodeID, SourceHandle: "cold"},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrMissingConditionRoute,
+		},
+		{
+			label: "success: condition clauses with a default edge",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: WeatherAPINodeID},
+					{ID: "check", Type: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{Conditions: []ConditionClause{
+						{Operator: OperatorLessThan, Threshold: 10, SourceHandle: "cold"},
+						{Operator: OperatorGreaterThanOrEqual, Threshold: 30, SourceHandle: "hot"},
+					}}}},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: WeatherAPINodeID},
+					{ID: "e2", Source: WeatherAPINodeID, Target: "check"},
+					{ID: "e3", Source: "check", Target: EndNodeID, SourceHandle: "cold"},
+					{ID: "e4", Source: "check", Target: EndNodeID, SourceHandle: ConditionDefaultHandle},
+				},
+			},
+		},
+		{
+			label: "error: condition node with two default edges",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: WeatherAPINodeID}, {ID: ConditionNodeID}, {ID: EmailNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: WeatherAPINodeID},
+					{ID: "e2", Source: WeatherAPINodeID, Target: ConditionNodeID},
+					{ID: "e3", Source: ConditionNodeID, Target: EmailNodeID, SourceHandle: ConditionDefaultHandle},
+					{ID: "e4", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionDefaultHandle},
+					{ID: "e5", Source: EmailNodeID, Target: EndNodeID},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrConflictingConditionRoutes,
+		},
+		{
+			label: "error: cycle",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: FormNodeID}, {ID: WeatherAPINodeID}, {ID: EndNodeID}},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: FormNodeID},
+					{ID: "e2", Source: FormNodeID, Target: WeatherAPINodeID},
+					{ID: "e3", Source: WeatherAPINodeID, Target: FormNodeID},
+					{ID: "e4", Source: WeatherAPINodeID, Target: EndNodeID},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrCycleDetected,
+		},
+		{
+			label: "error: condition reads a metric no upstream node records",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: WeatherAPINodeID},
+					{ID: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{Field: "weather.humidity"}}},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: WeatherAPINodeID},
+					{ID: "e2", Source: WeatherAPINodeID, Target: ConditionNodeID},
+					{ID: "e3", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionMetHandle},
+					{ID: "e4", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrMissingContextDependency,
+		},
+		{
+			label: "error: condition reads a metric only recorded downstream",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: ConditionNodeID},
+					{ID: WeatherAPINodeID},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: ConditionNodeID},
+					{ID: "e2", Source: ConditionNodeID, Target: WeatherAPINodeID, SourceHandle: ConditionMetHandle},
+					{ID: "e3", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+					{ID: "e4", Source: WeatherAPINodeID, Target: EndNodeID},
+				},
+			},
+			expectErr:   true,
+			errExpected: ErrMissingContextDependency,
+		},
+		{
+			label: "success: condition clauses reading a transform output",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: WeatherAPINodeID, Data: NodeData{Metadata: NodeMetadata{Metrics: []string{"temperature", "windspeed"}}}},
+					{ID: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "feels_like = temperature - windspeed * 0.5"}}},
+					{ID: "check", Type: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{Conditions: []ConditionClause{
+						{Field: "feels_like", Operator: OperatorLessThan, Threshold: 10, SourceHandle: "cold"},
+						{Field: "weather.windspeed", Operator: OperatorGreaterThanOrEqual, Threshold: 10, SourceHandle: "windy"},
+					}}}},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: WeatherAPINodeID},
+					{ID: "e2", Source: WeatherAPINodeID, Target: TransformNodeID},
+					{ID: "e3", Source: TransformNodeID, Target: "check"},
+					{ID: "e4", Source: "check", Target: EndNodeID, SourceHandle: "cold"},
+					{ID: "e5", Source: "check", Target: EndNodeID, SourceHandle: "windy"},
+				},
+			},
+		},
+		{
+			label: "success: condition after a node of an unknown type",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{{ID: StartNodeID}, {ID: "sensor", Type: "custom-sensor"}, {ID: ConditionNodeID}, {ID: EndNodeID}},
+				Edges: []Edge{
+					{ID: "e1", Source: StartNodeID, Target: "sensor"},
+					{ID: "e2", Source: "sensor", Target: ConditionNodeID},
+					{ID: "e3", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionMetHandle},
+					{ID: "e4", Source: ConditionNodeID, Target: EndNodeID, SourceHandle: ConditionNotMetHandle},
+				},
+			},
+		},
+		{
+			label: "success: http-request extract paths",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: HTTPRequestNodeID, Data: NodeData{Metadata: NodeMetadata{HTTPRequest: &HTTPRequest{URL: "https://example.com", Extract: map[string]string{"price": "$.data['price'].amount", "first": "$.items[0]"}}}}},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{{ID: "e1", Source: StartNodeID, Target: HTTPRequestNodeID}, {ID: "e2", Source: HTTPRequestNodeID, Target: EndNodeID}},
+			},
+		},
+		{
+			label: "error: unsupported http-request extract path",
+			workflow: &WorkflowDefinition{
+				Nodes: []Node{
+					{ID: StartNodeID},
+					{ID: HTTPRequestNodeID, Data: NodeData{Metadata: NodeMetadata{HTTPRequest: &HTTPRequest{URL: "https://example.com", Extract: map[string]string{"cheap": "$.items[?(@.price < 10)]"}}}}},
+					{ID: EndNodeID},
+				},
+				Edges: []Edge{{ID: "e1", Source: StartNodeID, Target: HTTPRequestNodeID}, {ID: "e2", Source: HTTPRequestNodeID, Target: EndNodeID}},
+			},
+			expectErr:   true,
+			errExpected: ErrInvalidJSONPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			err := ValidateWorkflow(tt.workflow)
+			if tt.expectErr {
+				require.ErrorIs(t, err, tt.errExpected)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateWorkflowSchema(t *testing.T) {
+	tests := []struct {
+		label       string
+		definition  string
+		expectErr   bool
+		errExpected error
+		wantFields  []string
+	}{
+		{
+			label:      "success: complete definition",
+			definition: `{"id":"wf-1","nodes":[{"id":"start","type":"start"},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"end"}]}`,
+		},
+		{
+			label:       "error: not a json object",
+			definition:  `[]`,
+			expectErr:   true,
+			errExpected: ErrInvalidWorkflowFormat,
+		},
+		{
+			label:       "error: missing id, nodes and edges",
+			definition:  `{}`,
+			expectErr:   true,
+			errExpected: ErrMissingRequiredField,
+			wantFields:  []string{"id", "nodes", "edges"},
+		},
+		{
+			label:       "error: nodes and edges missing required fields",
+			definition:  `{"id":"wf-1","nodes":[{"id":"start"},{"id":7,"type":"end"}],"edges":[{"id":"e1","source":"start"}]}`,
+			expectErr:   true,
+			errExpected: ErrMissingRequiredField,
+			wantFields:  []string{"nodes[0].type", "nodes[1].id", "edges[0].target"},
+		},
+		{
+			label:       "error: duplicate node id",
+			definition:  `{"id":"wf-1","nodes":[{"id":"start","type":"start"},{"id":"start","type":"end"}],"edges":[]}`,
+			expectErr:   true,
+			errExpected: ErrDuplicateNodeID,
+			wantFields:  []string{"nodes[1].id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			err := ValidateWorkflowSchema([]byte(tt.definition))
+			if !tt.expectErr {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.errExpected)
+			if len(tt.wantFields) > 0 {
+				var validationErrs ValidationErrors
+				require.ErrorAs(t, err, &validationErrs)
+
+				var gotFields []string
+				for _, fe := range validationErrs {
+					gotFields = append(gotFields, fe.Field)
+				}
+				require.Equal(t, tt.wantFields, gotFields)
+			}
+		})
+	}
+}
+
+func TestUnreachableNodes(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: ConditionNodeID}, {ID: EmailNodeID}, {ID: "orphan"}, {ID: "island-a"}, {ID: "island-b"}, {ID: EndNodeID}},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: ConditionNodeID},
+			{Source: ConditionNodeID, Target: EmailNodeID},
+			{Source: ConditionNodeID, Target: EndNodeID},
+			{Source: "island-a", Target: "island-b"},
+		},
+	}
+	require.Equal(t, []string{"orphan", "island-a", "island-b"}, unreachableNodes(wf))
+
+	wf.Nodes = []Node{{ID: StartNodeID}, {ID: EndNodeID}}
+	wf.Edges = []Edge{{Source: StartNodeID, Target: EndNodeID}}
+	require.Empty(t, unreachableNodes(wf))
+}
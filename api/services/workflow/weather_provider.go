@@ -0,0 +1,572 @@
+package workflow
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// this file weather_provider.go contains the weather providers the weather node can fetch the current weather from.
+
+const (
+	// supported weather providers, selected with the weather node "provider" metadata
+	WeatherProviderOpenMeteo      = "open-meteo"
+	WeatherProviderOpenWeatherMap = "openweathermap"
+
+	// defaultOpenWeatherMapBaseURL is the OpenWeatherMap API used when no base URL is configured.
+	defaultOpenWeatherMapBaseURL = "https://api.openweathermap.org"
+)
+
+// weatherProvider resolves a city to coordinates and fetches the current weather metrics for them.
+type weatherProvider interface {
+	// geocode returns the latitude and longitude of the city, the place matching the hint when the name is ambiguous.
+	geocode(ctx context.Context, city string, hint geocodingHint) (lat, lon float64, err error)
+	// currentWeather returns the value of each requested metric at the coordinates.
+	currentWeather(ctx context.Context, node Node, lat, lon float64, metrics []string) (map[string]float64, error)
+	// requestedURLs returns the (redacted) URL called for each API, keyed by geocodingAPIName or weatherAPIName.
+	requestedURLs() map[string]string
+	// retryWait returns the total time waited before retrying rate limited (429) requests.
+	retryWait() time.Duration
+	// readingTime returns the time the current weather was observed at, zero when the response didn't say.
+	readingTime() time.Time
+}
+
+// names of the APIs called by the weather providers
+const (
+	geocodingAPIName = "geocoding API"
+	weatherAPIName   = "weather API"
+)
+
+// weatherClient performs the provider requests and keeps track of the URLs called.
+type weatherClient struct {
+	requested map[string]string
+	// limiter, when set, rate limits the calls
+	limiter *tokenBucket
+	// retry is the node retry policy applied to rate limited requests, nil for a single retry
+	retry *RetryPolicy
+	// waited is the total time spent waiting before retrying rate limited requests
+	waited time.Duration
+	// observedAt is the time of the current weather reading
+	observedAt time.Time
+}
+
+func (c *weatherClient) requestedURLs() map[string]string {
+	return c.requested
+}
+
+func (c *weatherClient) retryWait() time.Duration {
+	return c.waited
+}
+
+func (c *weatherClient) readingTime() time.Time {
+	return c.observedAt
+}
+
+// defaultRateLimitedAttempts is the number of attempts of a rate limited request when the node has no retry policy.
+const defaultRateLimitedAttempts = 2
+
+// maxAttempts returns the number of attempts of a rate limited request.
+func (c *weatherClient) maxAttempts() int {
+	if c.retry == nil {
+		return defaultRateLimitedAttempts
+	}
+	return max(c.retry.MaxAttempts, 1)
+}
+
+// defaultRateLimitedBackoff is the wait before retrying a rate limited request without a Retry-After header when the
+// node retry policy has no backoff, multiplied by the number of attempts so far.
+const defaultRateLimitedBackoff = time.Second
+
+// rateLimitedBackoff returns the wait before retrying a rate limited request without a Retry-After header: the node
+// retry backoff, or defaultRateLimitedBackoff with up to 20% of jitter so concurrent executions don't retry in
+// lockstep. The wait is capped at the ctx deadline in waitBeforeRetry.
+func (c *weatherClient) rateLimitedBackoff(attempt int) time.Duration {
+	if c.retry != nil && c.retry.BackoffMs > 0 {
+		return time.Duration(c.retry.BackoffMs*attempt) * time.Millisecond
+	}
+	backoff := defaultRateLimitedBackoff * time.Duration(attempt)
+	return backoff + rand.N(backoff/5)
+}
+
+// waitBeforeRetry waits before the next attempt of a rate limited request, at most until the ctx deadline. It fails
+// with ErrWeatherRateLimited when no time is left before the deadline, and when ctx is done while waiting.
+func (c *weatherClient) waitBeforeRetry(ctx context.Context, wait time.Duration) error {
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: no time left to retry before the deadline", ErrWeatherRateLimited)
+		}
+		wait = min(wait, remaining)
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		// a wait capped at the deadline may end with it
+		if hasDeadline && !time.Now().Before(deadline) {
+			return fmt.Errorf("%w: no time left to retry before the deadline", ErrWeatherRateLimited)
+		}
+		c.waited += wait
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrWeatherRateLimited, ctx.Err())
+	}
+}
+
+// parseRetryAfter parses a Retry-After header value, either a number of seconds or an HTTP date.
+// ok is false when the header is missing or invalid. A date in the past is a zero wait.
+func parseRetryAfter(value string, now time.Time) (wait time.Duration, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(date.Sub(now), 0), true
+}
+
+// newWeatherProvider returns the provider selected by the node, defaulting to Open-Meteo.
+func newWeatherProvider(node Node, weatherAPI WeatherAPIConfig) (weatherProvider, error) {
+	switch node.Data.Metadata.Provider {
+	case "", WeatherProviderOpenMeteo:
+		return &openMeteoProvider{weatherClient: weatherClient{limiter: weatherAPI.limiter, retry: node.Data.Metadata.Retry}, config: weatherAPI}, nil
+	case WeatherProviderOpenWeatherMap:
+		if weatherAPI.OpenWeatherMapAPIKey == "" {
+			return nil, fmt.Errorf("%w: %s requires an API key", ErrUnsupportedWeatherProvider, WeatherProviderOpenWeatherMap)
+		}
+		return &openWeatherMapProvider{weatherClient: weatherClient{limiter: weatherAPI.limiter, retry: node.Data.Metadata.Retry}, config: weatherAPI}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedWeatherProvider, node.Data.Metadata.Provider)
+	}
+}
+
+// getJSON performs a GET request to the named API with the headers and decodes the JSON response into v.
+// errStatus is wrapped when the response status isn't 200 OK.
+// A rate limited (429) request is retried after the wait asked by its Retry-After header (or rateLimitedBackoff
+// without one), as many times as the node retry policy allows.
+func (c *weatherClient) getJSON(ctx context.Context, name, rawURL string, headers map[string]string, errStatus error, v any) error {
+	if c.requested == nil {
+		c.requested = make(map[string]string)
+	}
+	c.requested[name] = redactURL(rawURL)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.get(ctx, name, rawURL, headers)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < c.maxAttempts() {
+			wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			resp.Body.Close()
+			if !ok {
+				wait = c.rateLimitedBackoff(attempt)
+			}
+			if err := c.waitBeforeRetry(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%w: %d", errStatus, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return ErrResponseDecodeFailed
+		}
+		return nil
+	}
+}
+
+// get sends a single GET request to the named API, waiting for the rate limiter first when there's one.
+func (c *weatherClient) get(ctx context.Context, name, rawURL string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s request: %w", name, err)
+	}
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClientFromContext(ctx).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", name, err)
+	}
+	return resp, nil
+}
+
+// openMeteoProvider fetches the weather from Open-Meteo using the node apiEndpoint.
+type openMeteoProvider struct {
+	weatherClient
+	config WeatherAPIConfig
+}
+
+func (p *openMeteoProvider) geocode(ctx context.Context, city string, hint geocodingHint) (float64, float64, error) {
+	var geoData GeoCodingResponse
+	if err := p.getJSON(ctx, geocodingAPIName, geocodingURL(p.config, city, hint.count), nil, ErrGeocodingRequestFailed, &geoData); err != nil {
+		return 0, 0, err
+	}
+
+	candidates := make([]geocodingCandidate, 0, len(geoData.Results))
+	for _, result := range geoData.Results {
+		candidates = append(candidates, geocodingCandidate{
+			name:        result.Name,
+			country:     result.Country,
+			countryCode: result.CountryCode,
+			region:      result.Admin1,
+			lat:         result.Latitude,
+			lon:         result.Longitude,
+		})
+	}
+	place, err := hint.pick(city, candidates)
+	if err != nil {
+		return 0, 0, err
+	}
+	return place.lat, place.lon, nil
+}
+
+func (p *openMeteoProvider) currentWeather(ctx context.Context, node Node, lat, lon float64, metrics []string) (map[string]float64, error) {
+	// replace placeholders in definition API URL
+	apiEndpoint := node.Data.Metadata.APIEndpoint
+	apiEndpoint = strings.ReplaceAll(apiEndpoint, "{lat}", fmt.Sprintf("%f", lat))
+	apiEndpoint = strings.ReplaceAll(apiEndpoint, "{lon}", fmt.Sprintf("%f", lon))
+	apiEndpoint, err := weatherURL(p.config, apiEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// humidity isn't part of current_weather so it has to be requested separately
+	for _, metric := range metrics {
+		if metric != WeatherMetricHumidity {
+			continue
+		}
+		u, err := url.Parse(apiEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weather API endpoint: %w", err)
+		}
+		q := u.Query()
+		q.Set("current", "relative_humidity_2m")
+		u.RawQuery = q.Encode()
+		apiEndpoint = u.String()
+	}
+
+	var weather WeatherResponse
+	if err := p.getJSON(ctx, weatherAPIName, apiEndpoint, node.Data.Metadata.APIHeaders, ErrWeatherRequestFailed, &weather); err != nil {
+		return nil, err
+	}
+	// a 200 response with an unexpected shape would otherwise report a 0°C reading
+	if weather.CurrentWeather == nil {
+		return nil, fmt.Errorf("%w: current_weather", ErrWeatherResponseIncomplete)
+	}
+	if slices.Contains(metrics, WeatherMetricHumidity) && weather.Current == nil {
+		return nil, fmt.Errorf("%w: current", ErrWeatherResponseIncomplete)
+	}
+
+	p.observedAt = parseOpenMeteoTime(weather.CurrentWeather.Time, weather.UTCOffsetSeconds)
+
+	values := make(map[string]float64, len(metrics))
+	for _, metric := range metrics {
+		values[metric] = weatherMetricValues[metric](&weather)
+	}
+	return values, nil
+}
+
+// openMeteoTimeLayout is the layout of the Open-Meteo times, in the timezone of the response utc_offset_seconds.
+const openMeteoTimeLayout = "2006-01-02T15:04"
+
+// parseOpenMeteoTime parses the time of the current weather, either an ISO 8601 local time (the default) or
+// a unix timestamp (with timeformat=unixtime). It returns the zero time when the time is missing or malformed.
+func parseOpenMeteoTime(raw json.RawMessage, utcOffsetSeconds int) time.Time {
+	var local string
+	if err := json.Unmarshal(raw, &local); err == nil {
+		t, err := time.ParseInLocation(openMeteoTimeLayout, local, time.FixedZone("", utcOffsetSeconds))
+		if err != nil {
+			return time.Time{}
+		}
+		return t
+	}
+	var unix int64
+	if err := json.Unmarshal(raw, &unix); err == nil && unix > 0 {
+		return time.Unix(unix, 0)
+	}
+	return time.Time{}
+}
+
+// openWeatherMapProvider fetches the weather from the OpenWeatherMap geocoding and current weather APIs.
+type openWeatherMapProvider struct {
+	weatherClient
+	config WeatherAPIConfig
+}
+
+// OpenWeatherMapResponse is the subset of the OpenWeatherMap current weather response used by the weather node.
+type OpenWeatherMapResponse struct {
+	Main struct {
+		Temperature float64 `json:"temp"`
+		Humidity    float64 `json:"humidity"`
+	} `json:"main"`
+	Wind struct {
+		Speed     float64 `json:"speed"` // m/s with metric units
+		Direction float64 `json:"deg"`
+	} `json:"wind"`
+	Weather []struct {
+		ID float64 `json:"id"`
+	} `json:"weather"`
+	Time int64 `json:"dt"` // unix time of the reading
+}
+
+// openWeatherMapMetricValues maps each supported metric to its value in the OpenWeatherMap response,
+// converted to the Open-Meteo units (e.g wind speed in km/h). The weather code is the OpenWeatherMap condition id.
+var openWeatherMapMetricValues = map[string]func(w *OpenWeatherMapResponse) float64{
+	WeatherMetricTemperature:   func(w *OpenWeatherMapResponse) float64 { return w.Main.Temperature },
+	WeatherMetricWindSpeed:     func(w *OpenWeatherMapResponse) float64 { return w.Wind.Speed * 3.6 },
+	WeatherMetricWindDirection: func(w *OpenWeatherMapResponse) float64 { return w.Wind.Direction },
+	WeatherMetricHumidity:      func(w *OpenWeatherMapResponse) float64 { return w.Main.Humidity },
+	WeatherMetricWeatherCode: func(w *OpenWeatherMapResponse) float64 {
+		if len(w.Weather) == 0 {
+			return 0
+		}
+		return w.Weather[0].ID
+	},
+}
+
+func (p *openWeatherMapProvider) url(path string, query url.Values) string {
+	base := p.config.OpenWeatherMapBaseURL
+	if base == "" {
+		base = defaultOpenWeatherMapBaseURL
+	}
+	query.Set("appid", p.config.OpenWeatherMapAPIKey)
+
+	return strings.TrimSuffix(base, "/") + path + "?" + query.Encode()
+}
+
+func (p *openWeatherMapProvider) geocode(ctx context.Context, city string, hint geocodingHint) (float64, float64, error) {
+	var results []struct {
+		Name    string  `json:"name"`
+		Lat     float64 `json:"lat"`
+		Lon     float64 `json:"lon"`
+		Country string  `json:"country"` // ISO code
+		State   string  `json:"state"`
+	}
+	geoURL := p.url("/geo/1.0/direct", url.Values{"q": {city}, "limit": {strconv.Itoa(hint.count)}})
+	if err := p.getJSON(ctx, geocodingAPIName, geoURL, nil, ErrGeocodingRequestFailed, &results); err != nil {
+		return 0, 0, err
+	}
+
+	candidates := make([]geocodingCandidate, 0, len(results))
+	for _, result := range results {
+		candidates = append(candidates, geocodingCandidate{
+			name:        result.Name,
+			countryCode: result.Country,
+			region:      result.State,
+			lat:         result.Lat,
+			lon:         result.Lon,
+		})
+	}
+	place, err := hint.pick(city, candidates)
+	if err != nil {
+		return 0, 0, err
+	}
+	return place.lat, place.lon, nil
+}
+
+func (p *openWeatherMapProvider) currentWeather(ctx context.Context, node Node, lat, lon float64, metrics []string) (map[string]float64, error) {
+	endpoint := p.url("/data/2.5/weather", url.Values{
+		"lat":   {fmt.Sprintf("%f", lat)},
+		"lon":   {fmt.Sprintf("%f", lon)},
+		"units": {"metric"},
+	})
+
+	var weather OpenWeatherMapResponse
+	if err := p.getJSON(ctx, weatherAPIName, endpoint, nil, ErrWeatherRequestFailed, &weather); err != nil {
+		return nil, err
+	}
+
+	if weather.Time > 0 {
+		p.observedAt = time.Unix(weather.Time, 0)
+	}
+
+	values := make(map[string]float64, len(metrics))
+	for _, metric := range metrics {
+		values[metric] = openWeatherMapMetricValues[metric](&weather)
+	}
+	return values, nil
+}
+
+// sensitiveNames are the query param and header name fragments whose values are redacted from recorded
+// URLs and headers.
+var sensitiveNames = []string{"key", "token", "secret", "appid", "password", "signature", "auth", "cookie"}
+
+// isSensitiveName reports whether a query param or header value must be redacted.
+func isSensitiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(lower, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeaders returns a copy of the headers with the credential values (e.g Authorization) hidden,
+// so they can be included in the step output.
+func redactHeaders(headers map[string]string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if isSensitiveName(name) {
+			value = "REDACTED"
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// redactURL hides credentials in the URL (user info password and key-looking query params) so it can be
+// included in the step output.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := u.Query()
+	for name := range query {
+		if isSensitiveName(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.Redacted()
+}
+
+const (
+	// defaultGeocodingCount is the number of geocoding results chosen from when the city has a country or region hint,
+	// or in strict mode.
+	defaultGeocodingCount = 10
+	// maxGeocodingCount is the largest number of results the geocoding APIs return.
+	maxGeocodingCount = 100
+)
+
+// geocodingHint chooses the place of a city name matching several places (e.g Springfield).
+type geocodingHint struct {
+	country string // country name or ISO code
+	region  string // first-level administrative area
+	count   int    // number of geocoding results to choose from
+	strict  bool   // fail with ErrAmbiguousCity instead of taking the best ranked place, see GeocodingConfig.Strict
+}
+
+// newGeocodingHint returns the geocoding hint of the weather node: the country and region of the form data, else
+// the ones of the node geocoding metadata. Without a hint, strict mode or a configured count only the first result
+// is fetched.
+func newGeocodingHint(node Node, formData FormData) geocodingHint {
+	hint := geocodingHint{country: formData.Country, region: formData.Region}
+	cfg := node.Data.Metadata.Geocoding
+	if cfg != nil {
+		hint.strict = cfg.Strict
+		if hint.country == "" {
+			hint.country = cfg.Country
+		}
+		if hint.region == "" {
+			hint.region = cfg.Region
+		}
+	}
+
+	switch {
+	case cfg != nil && cfg.Count > 0:
+		hint.count = min(cfg.Count, maxGeocodingCount)
+	case hint.country != "" || hint.region != "" || hint.strict:
+		// strict mode needs several results to tell an ambiguous city
+		hint.count = defaultGeocodingCount
+	default:
+		hint.count = 1
+	}
+	return hint
+}
+
+// geocodingCandidate is a place returned by the geocoding API for a city name.
+type geocodingCandidate struct {
+	name        string
+	country     string // country name, empty for providers only returning the code
+	countryCode string
+	region      string
+	lat, lon    float64
+}
+
+// String describes the place, e.g "Springfield, Illinois, United States".
+func (c geocodingCandidate) String() string {
+	parts := []string{c.name}
+	for _, part := range []string{c.region, cmp.Or(c.country, c.countryCode)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matches reports whether the place is in the hint country and region, the hint parts left empty match any place.
+func (h geocodingHint) matches(c geocodingCandidate) bool {
+	if h.country != "" && !strings.EqualFold(h.country, c.country) && !strings.EqualFold(h.country, c.countryCode) {
+		return false
+	}
+	return h.region == "" || strings.EqualFold(h.region, c.region)
+}
+
+// pick returns the place of the city among the geocoding candidates, ranked by relevance by the API: the first one
+// matching the hint. A hint matching none of the candidates fails with an ErrNoGeocodingMatch error, rather than
+// silently taking a place elsewhere. In strict mode, several matching candidates fail with an ErrAmbiguousCity error.
+// Both errors list the candidates.
+func (h geocodingHint) pick(city string, candidates []geocodingCandidate) (geocodingCandidate, error) {
+	if len(candidates) == 0 {
+		return geocodingCandidate{}, fmt.Errorf("no results found for city: %s", city)
+	}
+
+	var matching []geocodingCandidate
+	for _, candidate := range candidates {
+		if h.matches(candidate) {
+			matching = append(matching, candidate)
+		}
+	}
+
+	switch {
+	case len(matching) == 0:
+		return geocodingCandidate{}, fmt.Errorf("%w: %s matches %s", ErrNoGeocodingMatch, city, describePlaces(candidates))
+	case len(matching) > 1 && h.strict:
+		return geocodingCandidate{}, fmt.Errorf("%w: %s matches %s", ErrAmbiguousCity, city, describePlaces(matching))
+	}
+	return matching[0], nil
+}
+
+// describePlaces lists the places for an error message.
+func describePlaces(candidates []geocodingCandidate) string {
+	places := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		places = append(places, candidate.String())
+	}
+	return strings.Join(places, "; ")
+}
@@ -1,10 +1,19 @@
 package workflow
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
-	"log/slog"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 
@@ -14,119 +23,1005 @@ import (
 func (s *Service) HandleGetWorkflow(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	ctx := r.Context()
+	logger := loggerFromContext(ctx)
 
-	slog.Debug("Returning workflow definition for id", "id", id)
+	logger.Debug("Returning workflow definition for id", "id", id)
 
-	definitionBytes, err := s.GetWorkflowDefinitionByID(ctx, id)
+	record, err := s.GetWorkflowByID(ctx, id)
 	if err != nil {
 		var status int
-		var msg string
+		var respErr error
 
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
 			status = http.StatusNotFound
-			msg = errorToJSON(ErrWorkflowNotFound)
+			respErr = ErrWorkflowNotFound
 		default:
 			status = http.StatusInternalServerError
-			msg = errorToJSON(ErrInternalServerError)
+			respErr = ErrInternalServerError
 		}
 
-		http.Error(w, msg, status)
+		writeJSONError(w, respErr, status)
 		return
 	}
 
 	var wf WorkflowDefinition
-	if err := json.Unmarshal(definitionBytes, &wf); err != nil {
-		slog.Error("Invalid workflow format", "id", id, "error", err)
-		http.Error(w, errorToJSON(ErrInvalidWorkflowFormat), http.StatusInternalServerError)
+	if err := json.Unmarshal(record.Definition, &wf); err != nil {
+		logger.Error("Invalid workflow format", "id", id, "error", err)
+		writeJSONError(w, ErrInvalidWorkflowFormat, http.StatusInternalServerError)
+		return
+	}
+
+	setWorkflowTimestampHeaders(w, record)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(record.Definition)
+}
+
+const (
+	// defaultListLimit is the page size of the workflow list when the request doesn't specify a limit.
+	defaultListLimit = 20
+	// maxListLimit is the largest page size a request can ask for.
+	maxListLimit = 100
+
+	limitQueryParam  = "limit"
+	offsetQueryParam = "offset"
+)
+
+// ListWorkflowsResponse is a page of the workflow list with the pagination metadata.
+type ListWorkflowsResponse struct {
+	Items   []WorkflowSummary `json:"items"`
+	Total   int               `json:"total"`
+	Limit   int               `json:"limit"`
+	Offset  int               `json:"offset"`
+	HasMore bool              `json:"hasMore"`
+}
+
+// newListWorkflowsResponse wraps a page of workflows in the pagination envelope.
+func newListWorkflowsResponse(items []WorkflowSummary, total, limit, offset int) ListWorkflowsResponse {
+	return ListWorkflowsResponse{
+		Items:   items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: offset+len(items) < total,
+	}
+}
+
+// listPagination reads the "limit" (1 to maxListLimit, default defaultListLimit) and "offset" (default 0)
+// query params.
+func listPagination(r *http.Request) (limit, offset int, err error) {
+	limit, offset = defaultListLimit, 0
+	params := []struct {
+		name     string
+		dst      *int
+		min, max int
+	}{
+		{name: limitQueryParam, dst: &limit, min: 1, max: maxListLimit},
+		{name: offsetQueryParam, dst: &offset, min: 0, max: math.MaxInt32},
+	}
+	for _, param := range params {
+		raw := r.URL.Query().Get(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < param.min || value > param.max {
+			return 0, 0, fmt.Errorf("%w: %s", ErrInvalidQueryParam, param.name)
+		}
+		*param.dst = value
+	}
+	return limit, offset, nil
+}
+
+// HandleListWorkflows returns a page of the stored workflows (without their definitions) in a pagination envelope.
+func (s *Service) HandleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := loggerFromContext(ctx)
+
+	limit, offset, err := listPagination(r)
+	if err != nil {
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+	logger.Debug("Listing workflows", "limit", limit, "offset", offset)
+
+	items, total, err := s.ListWorkflows(ctx, limit, offset)
+	if err != nil {
+		logger.Error("Error listing workflows", "error", err)
+		writeJSONError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	jsonBytes, err := json.Marshal(newListWorkflowsResponse(items, total, limit, offset))
+	if err != nil {
+		logger.Error("Failed to marshal list response", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonBytes)
+}
+
+// HandleWorkflowExists answers HEAD requests with 200 or 404 and no body, without loading the definition.
+func (s *Service) HandleWorkflowExists(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	id := mux.Vars(r)["id"]
+	logger.Debug("Checking workflow exists for id", "id", id)
+
+	exists, err := s.ExistsWorkflow(r.Context(), id)
+	if err != nil {
+		logger.Error("Error checking workflow exists", "id", id, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+const (
+	createdAtHeader = "X-Created-At"
+	updatedAtHeader = "X-Updated-At"
+)
+
+// setWorkflowTimestampHeaders exposes the workflow row timestamps as response headers.
+// updated_at is also sent as Last-Modified so standard HTTP caching can use it.
+func setWorkflowTimestampHeaders(w http.ResponseWriter, record *WorkflowRecord) {
+	if !record.CreatedAt.IsZero() {
+		w.Header().Set(createdAtHeader, record.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	if !record.UpdatedAt.IsZero() {
+		w.Header().Set(updatedAtHeader, record.UpdatedAt.UTC().Format(time.RFC3339))
+		w.Header().Set("Last-Modified", record.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// defaultWorkflowName is used when a created workflow does not supply a name.
+const defaultWorkflowName = "Untitled Workflow"
+
+type CreateWorkflowResponse struct {
+	ID string `json:"id"`
+}
+
+func (s *Service) HandleCreateWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := loggerFromContext(ctx)
+	logger.Debug("Handling workflow creation")
+
+	var wf WorkflowDefinition
+	if err := json.NewDecoder(r.Body).Decode(&wf); err != nil {
+		logger.Error("Invalid JSON payload", "error", err)
+		writeBodyError(w, err)
+		return
+	}
+
+	if err := ValidateWorkflow(&wf); err != nil {
+		logger.Debug("Invalid workflow definition", "error", err)
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// generate an id when the payload doesn't supply one
+	if wf.ID == "" {
+		id, err := newWorkflowID()
+		if err != nil {
+			logger.Error("Failed to generate workflow id", "error", err)
+			writeJSONError(w, ErrInternalServerError, http.StatusInternalServerError)
+			return
+		}
+		wf.ID = id
+	}
+
+	name := wf.Name
+	if name == "" {
+		name = defaultWorkflowName
+	}
+
+	definitionBytes, err := json.Marshal(wf)
+	if err != nil {
+		logger.Error("Failed to marshal workflow definition", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.CreateWorkflow(ctx, wf.ID, name, definitionBytes); err != nil {
+		var status int
+		var respErr error
+		var validationErrs ValidationErrors
+
+		switch {
+		case errors.As(err, &validationErrs):
+			status = http.StatusBadRequest
+			respErr = validationErrs
+		case errors.Is(err, ErrWorkflowAlreadyExists):
+			status = http.StatusConflict
+			respErr = ErrWorkflowAlreadyExists
+		default:
+			logger.Error("Error creating workflow", "id", wf.ID, "error", err)
+			status = http.StatusInternalServerError
+			respErr = ErrInternalServerError
+		}
+
+		writeJSONError(w, respErr, status)
+		return
+	}
+
+	jsonBytes, err := json.Marshal(CreateWorkflowResponse{ID: wf.ID})
+	if err != nil {
+		logger.Error("Failed to marshal create response", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonBytes)
+}
+
+// HandlePatchWorkflow merges a partial definition into the stored workflow and returns the updated definition.
+func (s *Service) HandlePatchWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	ctx := r.Context()
+	logger := loggerFromContext(ctx)
+	logger.Debug("Patching workflow for id", "id", id)
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("Failed to read request body", "error", err)
+		writeBodyError(w, err)
+		return
+	}
+
+	record, err := s.PatchWorkflowDefinitionByID(ctx, id, patch)
+	if err != nil {
+		var status int
+		var respErr error
+		var validationErrs ValidationErrors
+
+		switch {
+		case errors.As(err, &validationErrs):
+			status = http.StatusBadRequest
+			respErr = validationErrs
+		case errors.Is(err, ErrInvalidJSON):
+			status = http.StatusBadRequest
+			respErr = ErrInvalidJSON
+		case errors.Is(err, pgx.ErrNoRows):
+			status = http.StatusNotFound
+			respErr = ErrWorkflowNotFound
+		default:
+			logger.Error("Error patching workflow", "id", id, "error", err)
+			status = http.StatusInternalServerError
+			respErr = ErrInternalServerError
+		}
+
+		writeJSONError(w, respErr, status)
+		return
+	}
+
+	setWorkflowTimestampHeaders(w, record)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(record.Definition)
+}
+
+// HandleValidateWorkflow validates a workflow definition without saving it. Both valid and invalid definitions
+// return 200 with the validation result, only a body that isn't JSON is a request error.
+func (s *Service) HandleValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	logger.Debug("Handling workflow validation")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logger.Error("Failed to read request body", "error", err)
+		writeBodyError(w, err)
+		return
+	}
+
+	result, err := validateDefinition(body)
+	if err != nil {
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		logger.Error("Failed to marshal validation result", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(definitionBytes)
+	w.Write(jsonBytes)
+}
+
+// newWorkflowID generates a random (version 4) UUID for a new workflow.
+func newWorkflowID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
 }
 
 // form data structs
 type Condition struct {
-	Operator  string  `json:"operator"`
-	Threshold float64 `json:"threshold"`
+	Operator       string   `json:"operator"`
+	Threshold      float64  `json:"threshold"`
+	UpperThreshold float64  `json:"upperThreshold,omitempty"` // Required by the between operator, Threshold is the lower bound
+	Value          *float64 `json:"value,omitempty"`          // Compared by condition nodes reading their value from the payload
+	Tolerance      *float64 `json:"tolerance,omitempty"`      // Max difference still matching the equals operator, defaults to 1e-6 (0 for exact)
 }
 
 type FormData struct {
-	Name      string  `json:"name"`
-	Email     string  `json:"email"`
-	City      string  `json:"city"`
-	Operator  string  `json:"operator"`  // Optional if already in Condition
-	Threshold float64 `json:"threshold"` // Optional if already in Condition
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	City      string   `json:"city"`
+	Lat       *float64 `json:"lat,omitempty"` // With Lon, looks up the weather at these coordinates instead of geocoding the city
+	Lon       *float64 `json:"lon,omitempty"`
+	Phone     string   `json:"phone,omitempty"`   // Required by workflows with an sms node
+	Country   string   `json:"country,omitempty"` // Narrows the geocoding of an ambiguous city, see GeocodingConfig
+	Region    string   `json:"region,omitempty"`
+	Operator  string   `json:"operator"`  // Used when Condition has no operator, see ExecutePayload.resolvedCondition
+	Threshold float64  `json:"threshold"` // Used when Condition has no operator, see ExecutePayload.resolvedCondition
+	// Fields holds the inputs of forms asking for more than the fields above, see NodeMetadata.InputFields
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// values returns every field of the form data by name, the named fields taking precedence over Fields.
+// Empty fields are left out.
+func (f FormData) values() map[string]any {
+	values := make(map[string]any, len(f.Fields)+6)
+	for name, value := range f.Fields {
+		if value != nil && value != "" {
+			values[name] = value
+		}
+	}
+	for name, value := range map[string]string{"name": f.Name, "email": f.Email, "city": f.City, "phone": f.Phone, "country": f.Country, "region": f.Region} {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	if f.Lat != nil {
+		values["lat"] = *f.Lat
+	}
+	if f.Lon != nil {
+		values["lon"] = *f.Lon
+	}
+	return values
 }
 
 type ExecutePayload struct {
 	FormData  FormData  `json:"formData"`
 	Condition Condition `json:"condition"`
+	// EntryNodeID starts the execution from the given node instead of the workflow entry node,
+	// e.g to re-run a workflow from the middle.
+	EntryNodeID string `json:"entryNodeId,omitempty"`
+}
+
+// resolvedCondition returns the condition the condition node evaluates. The operator and threshold are read
+// from Condition when its operator is set, otherwise both fall back to FormData.Operator and FormData.Threshold
+// (the payload shape sent by the form). A threshold is never mixed from the two, so a 0 Condition threshold is
+// kept as is. The other Condition fields (upperThreshold, value) are always read from Condition.
+func (p *ExecutePayload) resolvedCondition() Condition {
+	condition := p.Condition
+	if condition.Operator == "" {
+		condition.Operator = p.FormData.Operator
+		condition.Threshold = p.FormData.Threshold
+	}
+	return condition
+}
+
+// withDefaultCity returns the payload with its city set to the default city when the form data has none.
+// The payload is returned as is when it has a city or there's no default city.
+func (p *ExecutePayload) withDefaultCity(city string) *ExecutePayload {
+	if p.FormData.City != "" || city == "" {
+		return p
+	}
+	withCity := *p
+	withCity.FormData.City = city
+	return &withCity
+}
+
+// Validate checks the payload of the default weather workflow before execution (name, email, city and a payload
+// condition are required) and returns every invalid field at once. See ValidateFor for other workflows.
+func (p *ExecutePayload) Validate() error {
+	return p.ValidateFor(nil)
+}
+
+// ValidateFor checks the payload before executing the workflow and returns every invalid field at once. The form
+// data is validated the way the workflow form nodes validate it (their InputFields, or the name, email and city),
+// and the condition operator is only required when a condition node compares the payload condition. A nil
+// workflow is validated like the default weather workflow.
+func (p *ExecutePayload) ValidateFor(wf *WorkflowDefinition) error {
+	errs := validateFormData(p.FormData)
+	requireCondition := true
+	if wf != nil {
+		errs = validateWorkflowFormData(wf, p.FormData)
+		requireCondition = usesPayloadCondition(wf)
+	}
+
+	condition := p.resolvedCondition()
+	if (requireCondition || condition.Operator != "") && !supportedOperators[condition.Operator] {
+		errs = append(errs, newFieldError("condition.operator", fmt.Errorf("%w: %q", ErrUnsupportedOperator, condition.Operator)))
+	}
+	if condition.Operator == OperatorBetween && condition.UpperThreshold < condition.Threshold {
+		errs = append(errs, newFieldError("condition.upperThreshold", ErrInvalidThresholdRange))
+	}
+	if condition.Tolerance != nil && (*condition.Tolerance < 0 || math.IsNaN(*condition.Tolerance)) {
+		errs = append(errs, newFieldError("condition.tolerance", ErrInvalidTolerance))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateWorkflowFormData validates the form data with the rules of every form node of the workflow. Without a
+// form node, nothing is required and only the given fields are checked (e.g the email is well-formed).
+func validateWorkflowFormData(wf *WorkflowDefinition, formData FormData) ValidationErrors {
+	var errs ValidationErrors
+	seen := make(map[string]bool)
+	hasForm := false
+	for _, node := range wf.Nodes {
+		if nodeType, _ := resolveNodeType(node, builtinProcessors(ExecutionOptions{})); nodeType != FormNodeID || hasCustomProcessor(nodeType) {
+			continue
+		}
+		hasForm = true
+		// a field required by several form nodes is reported once
+		for _, fieldErr := range formNodeErrors(node, formData) {
+			if !seen[fieldErr.Field] {
+				seen[fieldErr.Field] = true
+				errs = append(errs, fieldErr)
+			}
+		}
+	}
+
+	if !hasForm {
+		return validateInputFields(formData, nil)
+	}
+	return errs
+}
+
+// usesPayloadCondition reports whether a condition node of the workflow compares the payload condition, i.e it has
+// neither condition clauses nor a complete comparison expression.
+func usesPayloadCondition(wf *WorkflowDefinition) bool {
+	for _, node := range wf.Nodes {
+		if nodeType, _ := resolveNodeType(node, builtinProcessors(ExecutionOptions{})); nodeType != ConditionNodeID || hasCustomProcessor(nodeType) {
+			continue
+		}
+		if _, ok := conditionComparison(node); ok || len(node.Data.Metadata.Conditions) > 0 {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// decodeExecutePayload reads the execute payload from the JSON body. Requests without a body (e.g GET)
+// fall back to the form fields and condition passed as query parameters.
+func decodeExecutePayload(r *http.Request) (ExecutePayload, error) {
+	var payload ExecutePayload
+	if r.Method != http.MethodGet && r.Body != nil {
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err == nil {
+			return payload, nil
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return payload, err
+		}
+		if !errors.Is(err, io.EOF) {
+			return payload, invalidJSONError(err)
+		}
+	}
+
+	return executePayloadFromQuery(r.URL.Query())
+}
+
+// executePayloadFromQuery builds the execute payload from query parameters
+// (e.g ?name=Jane&email=jane@example.com&city=Sydney&operator=greater_than&threshold=20).
+func executePayloadFromQuery(query url.Values) (ExecutePayload, error) {
+	payload := ExecutePayload{
+		FormData: FormData{
+			Name:    query.Get("name"),
+			Email:   query.Get("email"),
+			City:    query.Get("city"),
+			Phone:   query.Get("phone"),
+			Country: query.Get("country"),
+			Region:  query.Get("region"),
+		},
+		Condition: Condition{
+			Operator: query.Get("operator"),
+		},
+		EntryNodeID: query.Get("entryNodeId"),
+	}
+
+	thresholds := map[string]*float64{
+		"threshold":      &payload.Condition.Threshold,
+		"upperThreshold": &payload.Condition.UpperThreshold,
+	}
+	for param, dst := range thresholds {
+		raw := query.Get(param)
+		if raw == "" {
+			continue
+		}
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return payload, fmt.Errorf("%w: %s", ErrInvalidQueryParam, param)
+		}
+		*dst = threshold
+	}
+	if raw := query.Get("value"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return payload, fmt.Errorf("%w: value", ErrInvalidQueryParam)
+		}
+		payload.Condition.Value = &value
+	}
+	coordinates := map[string]**float64{
+		"lat": &payload.FormData.Lat,
+		"lon": &payload.FormData.Lon,
+	}
+	for param, dst := range coordinates {
+		raw := query.Get(param)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return payload, fmt.Errorf("%w: %s", ErrInvalidQueryParam, param)
+		}
+		*dst = &value
+	}
+
+	return payload, nil
+}
+
+const (
+	// defaultExecutionTimeout bounds a workflow execution when the request doesn't specify a timeout.
+	defaultExecutionTimeout = 30 * time.Second
+	// maxExecutionTimeout is the upper bound a request can ask for.
+	maxExecutionTimeout = 5 * time.Minute
+
+	executionTimeoutQueryParam = "timeout"
+	executionTimeoutHeader     = "X-Execution-Timeout"
+	dryRunQueryParam           = "dryRun"
+	strictQueryParam           = "strict"
+	includeContextQueryParam   = "includeContext"
+	sortStepsQueryParam        = "sortSteps"
+	prettyQueryParam           = "pretty"
+	failurePolicyQueryParam    = "failurePolicy"
+	failurePolicyHeader        = "X-Failure-Policy"
+
+	// ndjsonContentType is the Accept value that switches the execute endpoint to streaming mode.
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// executionTimeout reads the execution timeout from the "timeout" query param or the X-Execution-Timeout header.
+// The value is either a Go duration (e.g "10s") or a whole number of seconds.
+func executionTimeout(r *http.Request) (time.Duration, error) {
+	raw := r.URL.Query().Get(executionTimeoutQueryParam)
+	if raw == "" {
+		raw = r.Header.Get(executionTimeoutHeader)
+	}
+	if raw == "" {
+		return defaultExecutionTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(raw)
+		if convErr != nil {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidTimeout, raw)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	if timeout <= 0 || timeout > maxExecutionTimeout {
+		return 0, fmt.Errorf("%w: must be between 0 and %s", ErrInvalidTimeout, maxExecutionTimeout)
+	}
+
+	return timeout, nil
+}
+
+// executionFailurePolicy reads the failure policy from the "failurePolicy" query param or the X-Failure-Policy header.
+func executionFailurePolicy(r *http.Request) (string, error) {
+	policy := r.URL.Query().Get(failurePolicyQueryParam)
+	if policy == "" {
+		policy = r.Header.Get(failurePolicyHeader)
+	}
+	if !supportedFailurePolicies[policy] {
+		return "", fmt.Errorf("%w: %q", ErrInvalidFailurePolicy, policy)
+	}
+	return policy, nil
 }
 
 func (s *Service) HandleExecuteWorkflow(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	ctx := r.Context()
-	slog.Debug("Handling workflow execution for id", "id", id)
+	logger := loggerFromContext(ctx)
+	logger.Debug("Handling workflow execution for id", "id", id)
+
+	opts, timeout, err := s.executionRequestOptions(r)
+	if err != nil {
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
 
 	// decode form data
-	var payload ExecutePayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		slog.Error("Invalid JSON payload", "error", err)
-		http.Error(w, errorToJSON(ErrInvalidJSON), http.StatusBadRequest)
+	payload, err := decodeExecutePayload(r)
+	if err != nil {
+		logger.Error("Invalid execute payload", "error", err)
+		writeBodyError(w, err)
 		return
 	}
 
+	// a retried request with the same Idempotency-Key gets the prior result instead of re-running the side effects
+	var idempotencyKey string
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" && !opts.DryRun {
+		// a streamed execution has no single response to replay
+		if wantsNDJSON(r) {
+			writeJSONError(w, ErrIdempotencyKeyStreamed, http.StatusBadRequest)
+			return
+		}
+
+		idempotencyKey = idempotencyCacheKey(id, key)
+		body, reserved, err := s.reserveIdempotencyKey(ctx, idempotencyKey, payload)
+		switch {
+		case errors.Is(err, ErrIdempotencyKeyReused):
+			writeJSONError(w, err, http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, ErrIdempotencyKeyInFlight):
+			writeJSONError(w, err, http.StatusConflict)
+			return
+		case err != nil:
+			logger.Error("Failed to reserve idempotency key", "id", id, "error", err)
+			writeJSONError(w, ErrInternalServerError, http.StatusInternalServerError)
+			return
+		case !reserved:
+			logger.Debug("Replaying execution result", "id", id, "idempotency key", key)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(idempotencyReplayedHeader, "true")
+			w.WriteHeader(http.StatusOK)
+			writeExecutionBody(w, r, body)
+			return
+		}
+		// frees the key for a retry when the execution doesn't complete, a no-op once completed
+		defer s.idempotency.release(idempotencyKey)
+	}
+
 	definitionBytes, err := s.GetWorkflowDefinitionByID(ctx, id)
 	if err != nil {
 		var status int
-		var msg string
+		var respErr error
 
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
 			status = http.StatusNotFound
-			msg = errorToJSON(ErrWorkflowNotFound)
+			respErr = ErrWorkflowNotFound
 		default:
 			status = http.StatusInternalServerError
-			msg = errorToJSON(ErrInternalServerError)
+			respErr = ErrInternalServerError
 		}
 
-		http.Error(w, msg, status)
+		writeJSONError(w, respErr, status)
 		return
 	}
 
 	var wf WorkflowDefinition
 	if err := json.Unmarshal(definitionBytes, &wf); err != nil {
-		slog.Error("Invalid workflow format", "id", id, "error", err)
-		http.Error(w, errorToJSON(ErrInvalidWorkflowFormat), http.StatusInternalServerError)
+		logger.Error("Invalid workflow format", "id", id, "error", err)
+		writeJSONError(w, ErrInvalidWorkflowFormat, http.StatusInternalServerError)
+		return
+	}
+
+	// the payload is validated against the workflow form and condition nodes,
+	// the default city has to be applied before the validation requiring a city
+	payload = *payload.withDefaultCity(opts.DefaultCity)
+	if err := payload.ValidateFor(&wf); err != nil {
+		logger.Debug("Invalid execute payload", "id", id, "error", err)
+		writeJSONError(w, err, http.StatusBadRequest)
 		return
 	}
 
-	// update workflow definition
-	err = s.UpdateWorkflowDefinitionByID(ctx, wf.ID, definitionBytes)
+	s.withStoredWorkflowHooks(id, &wf, &opts)
+	s.runExecution(w, r, id, &wf, &payload, opts, timeout, idempotencyKey)
+}
+
+// withStoredWorkflowHooks sets the execution hooks of a stored workflow: the undelivered emails are saved as dead
+// letters and, when the workflow has a history node, the executions are stored for the history node of the next
+// ones (apart from the dry runs). Executions of workflows without a history node aren't stored, as nothing reads them.
+func (s *Service) withStoredWorkflowHooks(id string, wf *WorkflowDefinition, opts *ExecutionOptions) {
+	opts.OnDeadLetter = func(ctx context.Context, letter EmailDeadLetter) error {
+		letter.WorkflowID = id
+		// still saved when the execution timed out, so the undelivered email isn't lost
+		return s.SaveEmailDeadLetter(context.WithoutCancel(ctx), letter)
+	}
+
+	opts.LastExecution = func(ctx context.Context, workflowID string) (*ExecutionRecord, error) {
+		record, err := s.GetLastExecution(ctx, workflowID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return record, err
+	}
+	if !opts.DryRun && hasHistoryNode(wf) {
+		opts.OnExecutionComplete = func(ctx context.Context, result *ExecutionResult, contextData map[string]any) {
+			// still saved when the execution timed out, so the next execution can compare against it
+			if err := s.SaveExecution(context.WithoutCancel(ctx), id, result, contextData); err != nil {
+				loggerFromContext(ctx).Error("Failed to save execution", "id", id, "error", err)
+			}
+		}
+	}
+}
+
+// ExecuteInlineRequest is the body of the inline execute route: an unsaved definition and the execute payload.
+type ExecuteInlineRequest struct {
+	Definition *WorkflowDefinition `json:"definition"`
+	Payload    ExecutePayload      `json:"payload"`
+}
+
+// HandleExecuteInlineWorkflow executes the definition sent in the request body (e.g the editor's unsaved draft)
+// without reading from or writing to the database. It accepts the same query params and headers as the
+// stored workflow execute route, apart from Idempotency-Key. The execution is tracked under a generated workflow ID,
+// returned in the X-Workflow-ID header, rather than the ID of the definition.
+func (s *Service) HandleExecuteInlineWorkflow(w http.ResponseWriter, r *http.Request) {
+	logger := loggerFromContext(r.Context())
+	logger.Debug("Handling inline workflow execution")
+
+	opts, timeout, err := s.executionRequestOptions(r)
+	if err != nil {
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req ExecuteInlineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Debug("Invalid JSON payload", "error", err)
+		writeBodyError(w, err)
+		return
+	}
+	if req.Definition == nil {
+		writeJSONError(w, ValidationErrors{newFieldError("definition", ErrMissingRequiredField)}, http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateWorkflow(req.Definition); err != nil {
+		logger.Debug("Invalid workflow definition", "error", err)
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	payload := req.Payload.withDefaultCity(opts.DefaultCity)
+	if err := payload.ValidateFor(req.Definition); err != nil {
+		logger.Debug("Invalid execute payload", "error", err)
+		writeJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	id, err := newWorkflowID()
 	if err != nil {
-		slog.Error("Error updating workflow", "id", id, "error", err)
-		http.Error(w, errorToJSON(ErrInternalServerError), http.StatusInternalServerError)
+		logger.Error("Failed to generate inline workflow id", "error", err)
+		writeJSONError(w, ErrInternalServerError, http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set(workflowIDHeader, id)
+
+	s.runExecution(w, r, id, req.Definition, payload, opts, timeout, "")
+}
 
-	executionResults, err := processNodes(&wf, &payload)
+// executionRequestOptions reads the execution options and timeout from the execute request query params
+// and headers. The returned error is a bad request.
+func (s *Service) executionRequestOptions(r *http.Request) (ExecutionOptions, time.Duration, error) {
+	timeout, err := executionTimeout(r)
 	if err != nil {
-		slog.Error("Error executing workflow", "id", id, "error", err)
-		http.Error(w, errorToJSON(ErrInternalServerError), http.StatusInternalServerError)
+		return ExecutionOptions{}, 0, err
+	}
+
+	opts := s.executionOptions()
+	if opts.FailurePolicy, err = executionFailurePolicy(r); err != nil {
+		return ExecutionOptions{}, 0, err
+	}
+	boolParams := map[string]*bool{
+		dryRunQueryParam:         &opts.DryRun,
+		strictQueryParam:         &opts.Strict,
+		includeContextQueryParam: &opts.IncludeContext,
+		sortStepsQueryParam:      &opts.SortSteps,
+	}
+	for param, dst := range boolParams {
+		raw := r.URL.Query().Get(param)
+		if raw == "" {
+			continue
+		}
+		if *dst, err = strconv.ParseBool(raw); err != nil {
+			return ExecutionOptions{}, 0, fmt.Errorf("%w: %s", ErrInvalidQueryParam, param)
+		}
+	}
+
+	return opts, timeout, nil
+}
+
+// runExecution executes the workflow within the timeout and writes the result, or streams the steps when the
+// client asked for NDJSON. It's shared by the stored and inline execute routes, the result is cached for the
+// idempotency key when one is given.
+func (s *Service) runExecution(w http.ResponseWriter, r *http.Request, id string, wf *WorkflowDefinition, payload *ExecutePayload, opts ExecutionOptions, timeout time.Duration, idempotencyKey string) {
+	logger := loggerFromContext(r.Context())
+
+	// the execution is tracked under its id until it finishes so it can be canceled
+	executionID := r.Header.Get(executionIDHeader)
+	if !isValidRequestID(executionID) {
+		var err error
+		if executionID, err = newWorkflowID(); err != nil {
+			logger.Error("Failed to generate execution id", "error", err)
+			writeJSONError(w, ErrInternalServerError, http.StatusInternalServerError)
+			return
+		}
+	}
+	cancelCtx, cancelExecution := context.WithCancelCause(r.Context())
+	defer cancelExecution(nil)
+	execution, ok := s.executions.register(id, executionID, cancelExecution)
+	if !ok {
+		writeJSONError(w, ErrExecutionAlreadyRunning, http.StatusConflict)
+		return
+	}
+	defer s.executions.remove(executionID, execution)
+	w.Header().Set(executionIDHeader, executionID)
+
+	// bound the execution so slow external nodes can't run forever
+	execCtx, cancel := context.WithTimeout(cancelCtx, timeout)
+	defer cancel()
+
+	if wantsNDJSON(r) {
+		streamExecution(execCtx, w, id, wf, payload, opts)
+		return
+	}
+
+	executionResults, err := ExecuteWorkflowWithOptions(execCtx, wf, payload, opts)
+	if err != nil {
+		logger.Error("Error executing workflow", "id", id, "error", err)
+		switch {
+		case errors.Is(err, ErrExecutionTimeout):
+			writeJSONError(w, err, http.StatusGatewayTimeout)
+			return
+		case errors.Is(err, ErrExecutionCanceled) && errors.Is(context.Cause(cancelCtx), ErrExecutionCanceled):
+			writeJSONError(w, err, http.StatusConflict)
+			return
+		case errors.Is(err, ErrTooManySteps), errors.Is(err, ErrUnreachableNodes), errors.Is(err, ErrEntryNodeNotFound):
+			writeJSONError(w, err, http.StatusUnprocessableEntity)
+			return
+		}
+		writeJSONError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	// invalid form data is a client error, reported like the payload validation
+	if validationErrs, ok := formValidationFailure(wf, executionResults); ok {
+		logger.Debug("Invalid form data", "id", id, "error", validationErrs)
+		writeJSONError(w, validationErrs, http.StatusUnprocessableEntity)
 		return
 	}
 
 	jsonBytes, err := json.Marshal(executionResults)
 	if err != nil {
-		slog.Error("Failed to marshal execution results", "error", err)
-		http.Error(w, errorToJSON(ErrMarshalFailed), http.StatusInternalServerError)
+		logger.Error("Failed to marshal execution results", "error", err)
+		writeJSONError(w, ErrMarshalFailed, http.StatusInternalServerError)
 		return
 	}
 
+	s.idempotency.complete(idempotencyKey, jsonBytes)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(jsonBytes)
+	writeExecutionBody(w, r, jsonBytes)
+}
+
+// formValidationFailure returns the field errors of an execution that failed at a form node, i.e the first failed
+// step of the execution is a form node rejecting the form data.
+func formValidationFailure(wf *WorkflowDefinition, result *ExecutionResult) (ValidationErrors, bool) {
+	if result.Error == nil {
+		return nil, false
+	}
+	var validationErrs ValidationErrors
+	if !errors.As(result.Error, &validationErrs) {
+		return nil, false
+	}
+
+	for _, node := range wf.Nodes {
+		if node.ID != result.Error.NodeID {
+			continue
+		}
+		nodeType, _ := resolveNodeType(node, builtinProcessors(ExecutionOptions{}))
+		return validationErrs, nodeType == FormNodeID
+	}
+	return nil, false
+}
+
+// writeExecutionBody writes the JSON execution result, indented when the client asked for ?pretty=true.
+// The result is stored compact in the idempotency cache, so it's indented when written instead of marshalled.
+func writeExecutionBody(w http.ResponseWriter, r *http.Request, body []byte) {
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get(prettyQueryParam)); pretty {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err == nil {
+			indented.WriteByte('\n')
+			body = indented.Bytes()
+		}
+	}
+	w.Write(body)
+}
+
+// wantsNDJSON reports whether the client asked for the execution steps as a newline-delimited JSON stream.
+func wantsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+}
+
+// ExecutionSummary is the last line of a streamed execution, sent after every step.
+type ExecutionSummary struct {
+	ExecutedAt string         `json:"executedAt"`
+	Status     string         `json:"status"`
+	Warnings   []string       `json:"warnings,omitempty"`
+	DurationMs int64          `json:"durationMs"`
+	Error      string         `json:"error,omitempty"`
+	Code       string         `json:"code,omitempty"`
+	Context    map[string]any `json:"context,omitempty"` // final contextData, only with includeContext=true
+}
+
+// streamExecution runs the workflow and writes each StepResult as a JSON line as soon as the node
+// completes, followed by an ExecutionSummary line. The status code is sent before execution starts,
+// so execution errors are reported in the summary instead.
+func streamExecution(ctx context.Context, w http.ResponseWriter, id string, wf *WorkflowDefinition, payload *ExecutePayload, opts ExecutionOptions) {
+	logger := loggerFromContext(ctx)
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	writeLine := func(v any) {
+		if err := enc.Encode(v); err != nil {
+			logger.Error("Failed to write execution stream", "id", id, "error", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	opts.OnStep = func(step StepResult) {
+		writeLine(step)
+	}
+
+	summary := ExecutionSummary{Status: StatusFailed}
+	result, err := ExecuteWorkflowWithOptions(ctx, wf, payload, opts)
+	if result != nil {
+		summary.ExecutedAt = result.ExecutedAt
+		summary.Status = result.Status
+		summary.Warnings = result.Warnings
+		summary.DurationMs = result.DurationMs
+		summary.Context = result.Context
+	} else {
+		summary.ExecutedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	if err != nil {
+		logger.Error("Error executing workflow", "id", id, "error", err)
+		if !errors.Is(err, ErrExecutionTimeout) && !errors.Is(err, ErrExecutionCanceled) && !errors.Is(err, ErrTooManySteps) && !errors.Is(err, ErrUnreachableNodes) && !errors.Is(err, ErrEntryNodeNotFound) {
+			err = ErrInternalServerError
+		}
+		summary.Error = err.Error()
+		summary.Code = errorCode(err)
+	} else if result.Error != nil {
+		summary.Error = result.Error.Error()
+		summary.Code = result.Error.Code
+	}
+
+	writeLine(summary)
 }
@@ -0,0 +1,700 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutePayloadValidate(t *testing.T) {
+	tests := []struct {
+		label      string
+		payload    *ExecutePayload
+		wantFields []string
+	}{
+		{
+			label: "success: valid payload",
+			payload: &ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney"},
+				Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+			},
+		},
+		{
+			label: "success: operator and threshold in the form data",
+			payload: &ExecutePayload{
+				FormData: FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney", Operator: OperatorLessThan, Threshold: 5},
+			},
+		},
+		{
+			label: "success: coordinates instead of the city",
+			payload: &ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", Lat: ptr(-33.87), Lon: ptr(151.21)},
+				Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+			},
+		},
+		{
+			label: "error: coordinates out of range",
+			payload: &ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", Lat: ptr(-91.0), Lon: ptr(181.0)},
+				Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+			},
+			wantFields: []string{"formData.lat", "formData.lon"},
+		},
+		{
+			label: "error: latitude without longitude",
+			payload: &ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", Lat: ptr(-33.87)},
+				Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+			},
+			wantFields: []string{"formData.lon"},
+		},
+		{
+			label:      "error: every field invalid",
+			payload:    &ExecutePayload{},
+			wantFields: []string{"formData.name", "formData.email", "formData.city", "condition.operator"},
+		},
+		{
+			label: "error: unsupported operator",
+			payload: &ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney"},
+				Condition: Condition{Operator: "roughly"},
+			},
+			wantFields: []string{"condition.operator"},
+		},
+		{
+			label: "error: between with an inverted range",
+			payload: &ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney"},
+				Condition: Condition{Operator: OperatorBetween, Threshold: 25, UpperThreshold: 18},
+			},
+			wantFields: []string{"condition.upperThreshold"},
+		},
+		{
+			label: "error: negative equals tolerance",
+			payload: &ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney"},
+				Condition: Condition{Operator: OperatorEquals, Threshold: 21, Tolerance: ptr(-0.1)},
+			},
+			wantFields: []string{"condition.tolerance"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			err := tt.payload.Validate()
+			if len(tt.wantFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErrs ValidationErrors
+			require.ErrorAs(t, err, &validationErrs)
+
+			var gotFields []string
+			for _, fe := range validationErrs {
+				gotFields = append(gotFields, fe.Field)
+			}
+			require.Equal(t, tt.wantFields, gotFields)
+		})
+	}
+}
+
+func TestExecutePayloadValidateFor(t *testing.T) {
+	customForm := Node{ID: FormNodeID, Data: NodeData{Metadata: NodeMetadata{InputFields: []string{"company", "email"}}}}
+	payloadCondition := Node{ID: ConditionNodeID}
+	expressionCondition := Node{ID: ConditionNodeID, Data: NodeData{Metadata: NodeMetadata{ConditionExpr: "temperature > 30"}}}
+	workflow := func(nodes ...Node) *WorkflowDefinition {
+		return &WorkflowDefinition{Nodes: append([]Node{{ID: StartNodeID}, {ID: EndNodeID}}, nodes...)}
+	}
+
+	tests := []struct {
+		label      string
+		wf         *WorkflowDefinition
+		payload    ExecutePayload
+		wantFields []string
+	}{
+		{
+			label:   "success: custom form fields without a condition",
+			wf:      workflow(customForm),
+			payload: ExecutePayload{FormData: FormData{Email: "jane@example.com", Fields: map[string]any{"company": "Acme"}}},
+		},
+		{
+			label:   "success: condition expression doesn't need the payload condition",
+			wf:      workflow(Node{ID: FormNodeID}, expressionCondition),
+			payload: ExecutePayload{FormData: FormData{Name: "Jane", Email: "jane@example.com", City: "Sydney"}},
+		},
+		{
+			label:   "success: no form node",
+			wf:      workflow(),
+			payload: ExecutePayload{},
+		},
+		{
+			label:      "error: missing custom form fields",
+			wf:         workflow(customForm),
+			payload:    ExecutePayload{FormData: FormData{Name: "Jane", City: "Sydney"}},
+			wantFields: []string{"formData.fields.company", "formData.email"},
+		},
+		{
+			label:      "error: default form and payload condition",
+			wf:         workflow(Node{ID: FormNodeID}, payloadCondition),
+			payload:    ExecutePayload{FormData: FormData{Name: "Jane", Email: "jane@example.com"}},
+			wantFields: []string{"formData.city", "condition.operator"},
+		},
+		{
+			label:      "error: field required by several forms reported once",
+			wf:         workflow(customForm, Node{ID: "signup", Type: FormNodeID, Data: NodeData{Metadata: NodeMetadata{InputFields: []string{"company"}}}}),
+			payload:    ExecutePayload{FormData: FormData{Email: "jane@example.com"}},
+			wantFields: []string{"formData.fields.company"},
+		},
+		{
+			label:      "error: invalid email without a form node",
+			wf:         workflow(),
+			payload:    ExecutePayload{FormData: FormData{Email: "jane"}},
+			wantFields: []string{"formData.email"},
+		},
+		{
+			label:      "error: unsupported operator without a condition node",
+			wf:         workflow(),
+			payload:    ExecutePayload{Condition: Condition{Operator: "roughly"}},
+			wantFields: []string{"condition.operator"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			err := tt.payload.ValidateFor(tt.wf)
+			if len(tt.wantFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErrs ValidationErrors
+			require.ErrorAs(t, err, &validationErrs)
+
+			var gotFields []string
+			for _, fe := range validationErrs {
+				gotFields = append(gotFields, fe.Field)
+			}
+			require.Equal(t, tt.wantFields, gotFields)
+		})
+	}
+}
+
+func TestExecuteWorkflowCustomFormFields(t *testing.T) {
+	wf := &WorkflowDefinition{
+		ID: "signup",
+		Nodes: []Node{
+			{ID: StartNodeID},
+			{ID: FormNodeID, Data: NodeData{Metadata: NodeMetadata{InputFields: []string{"company"}}}},
+			{ID: TransformNodeID, Data: NodeData{Metadata: NodeMetadata{Expression: "seats = 2 * 5"}}},
+			{ID: EndNodeID},
+		},
+		Edges: []Edge{
+			{Source: StartNodeID, Target: FormNodeID},
+			{Source: FormNodeID, Target: TransformNodeID},
+			{Source: TransformNodeID, Target: EndNodeID},
+		},
+	}
+
+	got, err := ExecuteWorkflow(context.Background(), wf, &ExecutePayload{FormData: FormData{Fields: map[string]any{"company": "Acme"}}})
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, got.Status)
+	require.Len(t, got.Steps, 4)
+
+	_, err = ExecuteWorkflow(context.Background(), wf, &ExecutePayload{})
+	var validationErrs ValidationErrors
+	require.ErrorAs(t, err, &validationErrs)
+	require.Equal(t, "formData.fields.company", validationErrs[0].Field)
+}
+
+func TestExecutePayloadResolvedCondition(t *testing.T) {
+	tests := []struct {
+		label   string
+		payload ExecutePayload
+		want    Condition
+	}{
+		{
+			label: "condition takes precedence",
+			payload: ExecutePayload{
+				FormData:  FormData{Operator: OperatorLessThan, Threshold: 5},
+				Condition: Condition{Operator: OperatorGreaterThan, Threshold: 0},
+			},
+			want: Condition{Operator: OperatorGreaterThan, Threshold: 0},
+		},
+		{
+			label: "falls back to the form data",
+			payload: ExecutePayload{
+				FormData:  FormData{Operator: OperatorBetween, Threshold: 18},
+				Condition: Condition{UpperThreshold: 24},
+			},
+			want: Condition{Operator: OperatorBetween, Threshold: 18, UpperThreshold: 24},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.payload.resolvedCondition())
+		})
+	}
+}
+
+func TestExecutePayloadWithDefaultCity(t *testing.T) {
+	blank := &ExecutePayload{FormData: FormData{Name: "Alice"}}
+
+	got := blank.withDefaultCity("Sydney")
+	require.Equal(t, "Sydney", got.FormData.City)
+	require.Equal(t, "Alice", got.FormData.Name)
+	require.Empty(t, blank.FormData.City, "the original payload is left untouched")
+
+	require.Same(t, blank, blank.withDefaultCity(""), "no default city")
+
+	withCity := &ExecutePayload{FormData: FormData{City: "Perth"}}
+	require.Equal(t, "Perth", withCity.withDefaultCity("Sydney").FormData.City)
+}
+
+func TestExecutionTimeout(t *testing.T) {
+	tests := []struct {
+		label     string
+		target    string
+		header    string
+		want      time.Duration
+		expectErr bool
+	}{
+		{label: "default", target: "/", want: defaultExecutionTimeout},
+		{label: "query param duration", target: "/?timeout=5s", want: 5 * time.Second},
+		{label: "header seconds", target: "/", header: "12", want: 12 * time.Second},
+		{label: "error: invalid value", target: "/?timeout=soon", expectErr: true},
+		{label: "error: above maximum", target: "/?timeout=1h", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set(executionTimeoutHeader, tt.header)
+			}
+
+			got, err := executionTimeout(r)
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrInvalidTimeout)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecutionFailurePolicy(t *testing.T) {
+	tests := []struct {
+		label     string
+		target    string
+		header    string
+		want      string
+		expectErr bool
+	}{
+		{label: "default", target: "/", want: ""},
+		{label: "query param", target: "/?failurePolicy=stopOnError", want: FailurePolicyStopOnError},
+		{label: "header", target: "/", header: "continue", want: FailurePolicyContinue},
+		{label: "error: unknown policy", target: "/?failurePolicy=ignore", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.target, nil)
+			if tt.header != "" {
+				r.Header.Set(failurePolicyHeader, tt.header)
+			}
+
+			got, err := executionFailurePolicy(r)
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrInvalidFailurePolicy)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	tests := []struct {
+		label       string
+		target      string
+		wantLimit   int
+		wantOffset  int
+		expectErr   bool
+		errExpected string
+	}{
+		{label: "defaults", target: "/", wantLimit: defaultListLimit},
+		{label: "limit and offset", target: "/?limit=5&offset=10", wantLimit: 5, wantOffset: 10},
+		{label: "error: limit above maximum", target: "/?limit=1000", expectErr: true, errExpected: "invalid query parameter: limit"},
+		{label: "error: negative offset", target: "/?offset=-1", expectErr: true, errExpected: "invalid query parameter: offset"},
+		{label: "error: non numeric limit", target: "/?limit=all", expectErr: true, errExpected: "invalid query parameter: limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			limit, offset, err := listPagination(httptest.NewRequest("GET", tt.target, nil))
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrInvalidQueryParam)
+				require.EqualError(t, err, tt.errExpected)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantLimit, limit)
+			require.Equal(t, tt.wantOffset, offset)
+		})
+	}
+}
+
+func TestNewListWorkflowsResponse(t *testing.T) {
+	items := []WorkflowSummary{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}}
+
+	got := newListWorkflowsResponse(items, 5, 2, 2)
+	require.True(t, got.HasMore)
+	require.Equal(t, 5, got.Total)
+
+	got = newListWorkflowsResponse(items, 4, 2, 2)
+	require.False(t, got.HasMore)
+
+	jsonBytes, err := json.Marshal(newListWorkflowsResponse([]WorkflowSummary{}, 0, 20, 0))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"items":[],"total":0,"limit":20,"offset":0,"hasMore":false}`, string(jsonBytes))
+}
+
+func TestSetWorkflowTimestampHeaders(t *testing.T) {
+	record := &WorkflowRecord{
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt: time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC),
+	}
+
+	w := httptest.NewRecorder()
+	setWorkflowTimestampHeaders(w, record)
+
+	require.Equal(t, "2025-01-02T03:04:05Z", w.Header().Get(createdAtHeader))
+	require.Equal(t, "2025-02-03T04:05:06Z", w.Header().Get(updatedAtHeader))
+	require.Equal(t, "Mon, 03 Feb 2025 04:05:06 GMT", w.Header().Get("Last-Modified"))
+}
+
+func TestFormValidationFailure(t *testing.T) {
+	wf := &WorkflowDefinition{Nodes: []Node{
+		{ID: StartNodeID},
+		{ID: "signup", Type: FormNodeID},
+		{ID: EmailNodeID},
+	}}
+	fieldErrs := ValidationErrors{newFieldError("formData.name", ErrMissingFormFieldName)}
+
+	tests := []struct {
+		label  string
+		result *ExecutionResult
+		wantOK bool
+	}{
+		{label: "completed", result: &ExecutionResult{Status: StatusCompleted}},
+		{label: "form node failure", result: &ExecutionResult{Error: &ExecutionError{NodeID: "signup", err: fieldErrs}}, wantOK: true},
+		{label: "other node failure", result: &ExecutionResult{Error: &ExecutionError{NodeID: EmailNodeID, err: fieldErrs}}},
+		{label: "form node failing otherwise", result: &ExecutionResult{Error: &ExecutionError{NodeID: "signup", err: ErrNodeTimeout}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, ok := formValidationFailure(wf, tt.result)
+			require.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				require.Equal(t, fieldErrs, got)
+			}
+		})
+	}
+}
+
+func TestWriteExecutionBody(t *testing.T) {
+	body := []byte(`{"status":"completed","steps":[]}`)
+
+	tests := []struct {
+		label string
+		query string
+		want  string
+	}{
+		{label: "compact by default", query: "", want: `{"status":"completed","steps":[]}`},
+		{label: "pretty", query: "?pretty=true", want: "{\n  \"status\": \"completed\",\n  \"steps\": []\n}\n"},
+		{label: "pretty disabled", query: "?pretty=false", want: `{"status":"completed","steps":[]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/workflows/wf-1/execute"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			writeExecutionBody(rec, r, body)
+
+			require.Equal(t, tt.want, rec.Body.String())
+		})
+	}
+}
+
+func TestStreamExecution(t *testing.T) {
+	wf := &WorkflowDefinition{
+		Nodes: []Node{{ID: StartNodeID}, {ID: EndNodeID}},
+		Edges: []Edge{{Source: StartNodeID, Target: EndNodeID}},
+	}
+
+	payload := &ExecutePayload{
+		FormData:  FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney"},
+		Condition: Condition{Operator: OperatorGreaterThan, Threshold: 20},
+	}
+
+	rec := httptest.NewRecorder()
+	streamExecution(context.Background(), rec, "wf-1", wf, payload, ExecutionOptions{})
+
+	require.Equal(t, ndjsonContentType, rec.Header().Get("Content-Type"))
+	require.True(t, rec.Flushed)
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var step StepResult
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &step))
+	require.Equal(t, StartNodeID, step.NodeID)
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &step))
+	require.Equal(t, EndNodeID, step.NodeID)
+
+	var summary ExecutionSummary
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &summary))
+	require.Equal(t, StatusCompleted, summary.Status)
+	require.Empty(t, summary.Error)
+}
+
+func TestWantsNDJSON(t *testing.T) {
+	r := httptest.NewRequest("POST", "/workflows/1/execute", nil)
+	require.False(t, wantsNDJSON(r))
+
+	r.Header.Set("Accept", "application/x-ndjson")
+	require.True(t, wantsNDJSON(r))
+}
+
+func TestDecodeExecutePayload(t *testing.T) {
+	tests := []struct {
+		label           string
+		method          string
+		target          string
+		body            string
+		expectErr       bool
+		errExpected     error
+		payloadExpected ExecutePayload
+	}{
+		{
+			label:  "success: json body",
+			method: "POST",
+			target: "/workflows/1/execute?name=Ignored",
+			body:   `{"formData":{"name":"Alice","email":"alice@example.com","city":"Sydney"},"condition":{"operator":"greater_than","threshold":25}}`,
+			payloadExpected: ExecutePayload{
+				FormData:  FormData{Name: "Alice", Email: "alice@example.com", City: "Sydney"},
+				Condition: Condition{Operator: "greater_than", Threshold: 25},
+			},
+		},
+		{
+			label:  "success: get with query params",
+			method: "GET",
+			target: "/workflows/1/execute?name=Jane&email=jane@example.com&city=Sydney&operator=less_than&threshold=20.5",
+			payloadExpected: ExecutePayload{
+				FormData:  FormData{Name: "Jane", Email: "jane@example.com", City: "Sydney"},
+				Condition: Condition{Operator: "less_than", Threshold: 20.5},
+			},
+		},
+		{
+			label:  "success: coordinates in query params",
+			method: "GET",
+			target: "/workflows/1/execute?name=Jane&lat=-33.87&lon=151.21",
+			payloadExpected: ExecutePayload{
+				FormData: FormData{Name: "Jane", Lat: ptr(-33.87), Lon: ptr(151.21)},
+			},
+		},
+		{
+			label:  "success: post without a body falls back to query params",
+			method: "POST",
+			target: "/workflows/1/execute?name=Jane&city=Sydney",
+			payloadExpected: ExecutePayload{
+				FormData: FormData{Name: "Jane", City: "Sydney"},
+			},
+		},
+		{
+			label:       "error: invalid json",
+			method:      "POST",
+			target:      "/workflows/1/execute",
+			body:        `{"formData":`,
+			expectErr:   true,
+			errExpected: ErrInvalidJSON,
+		},
+		{
+			label:       "error: invalid threshold",
+			method:      "GET",
+			target:      "/workflows/1/execute?threshold=warm",
+			expectErr:   true,
+			errExpected: ErrInvalidQueryParam,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			got, err := decodeExecutePayload(r)
+			if tt.expectErr {
+				require.ErrorIs(t, err, tt.errExpected)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.payloadExpected, got)
+			}
+		})
+	}
+}
+
+func TestHandleValidateWorkflow(t *testing.T) {
+	tests := []struct {
+		label      string
+		body       string
+		wantStatus int
+		wantValid  bool
+		wantCodes  []string
+	}{
+		{
+			label:      "success: valid definition",
+			body:       `{"id":"wf-1","nodes":[{"id":"start","type":"start"},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"end"}]}`,
+			wantStatus: http.StatusOK,
+			wantValid:  true,
+		},
+		{
+			label:      "success: invalid definition lists every problem",
+			body:       `{"id":"wf-1","nodes":[{"id":"start","type":"start"},{"id":"orphan","type":"form"},{"id":"end"}],"edges":[{"id":"e1","source":"start","target":"end"}]}`,
+			wantStatus: http.StatusOK,
+			wantCodes:  []string{ErrMissingRequiredField.Code, ErrUnreachableNodes.Code},
+		},
+		{
+			label:      "success: structural error",
+			body:       `{"id":"wf-1","nodes":[{"id":"start","type":"start"},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"missing"}]}`,
+			wantStatus: http.StatusOK,
+			wantCodes:  []string{ErrDanglingEdge.Code, ErrUnreachableNodes.Code},
+		},
+		{
+			label:      "error: invalid json",
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			s := &Service{config: DefaultConfig()}
+			rec := httptest.NewRecorder()
+			s.HandleValidateWorkflow(rec, httptest.NewRequest("POST", "/workflows/validate", strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var result ValidationResult
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+			require.Equal(t, tt.wantValid, result.Valid)
+
+			var gotCodes []string
+			for _, issue := range result.Errors {
+				gotCodes = append(gotCodes, issue.Code)
+			}
+			require.Equal(t, tt.wantCodes, gotCodes)
+		})
+	}
+}
+
+// ptr returns a pointer to the value, e.g for the optional payload fields.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestHandleExecuteInlineWorkflow(t *testing.T) {
+	definition := `{"id":"draft","nodes":[{"id":"start","type":"start"},{"id":"form","type":"form"},{"id":"end","type":"end"}],"edges":[{"id":"e1","source":"start","target":"form"},{"id":"e2","source":"form","target":"end"}]}`
+	payload := `{"formData":{"name":"Jane","email":"jane@example.com","city":"Sydney"},"condition":{"operator":"greater_than","threshold":20}}`
+
+	tests := []struct {
+		label      string
+		target     string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			label:      "success: executes the draft",
+			target:     "/workflows/execute",
+			body:       `{"definition":` + definition + `,"payload":` + payload + `}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			label:      "error: missing definition",
+			target:     "/workflows/execute",
+			body:       `{"payload":` + payload + `}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrInvalidPayload.Code,
+		},
+		{
+			label:      "error: invalid definition",
+			target:     "/workflows/execute",
+			body:       `{"definition":{"id":"draft","nodes":[{"id":"start","type":"start"}],"edges":[]},"payload":` + payload + `}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrMissingEndNode.Code,
+		},
+		{
+			label:      "error: invalid payload",
+			target:     "/workflows/execute",
+			body:       `{"definition":` + definition + `,"payload":{}}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrInvalidPayload.Code,
+		},
+		{
+			label:      "success: custom form fields",
+			target:     "/workflows/execute",
+			body:       `{"definition":` + strings.Replace(definition, `"type":"form"`, `"type":"form","data":{"metadata":{"inputFields":["company"]}}`, 1) + `,"payload":{"formData":{"fields":{"company":"Acme"}}}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			label:      "error: missing custom form field",
+			target:     "/workflows/execute",
+			body:       `{"definition":` + strings.Replace(definition, `"type":"form"`, `"type":"form","data":{"metadata":{"inputFields":["company"]}}`, 1) + `,"payload":` + payload + `}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrInvalidPayload.Code,
+		},
+		{
+			label:      "error: invalid query param",
+			target:     "/workflows/execute?dryRun=maybe",
+			body:       `{"definition":` + definition + `,"payload":` + payload + `}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   ErrInvalidQueryParam.Code,
+		},
+	}
+
+	router := mux.NewRouter()
+	s := &Service{config: DefaultConfig()}
+	s.LoadRoutes(router, false)
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			if tt.wantCode != "" {
+				require.Equal(t, tt.wantCode, body["code"])
+				return
+			}
+			require.Equal(t, StatusCompleted, body["status"])
+			require.Len(t, body["steps"], 3)
+			require.NotEmpty(t, rec.Header().Get(workflowIDHeader))
+		})
+	}
+}
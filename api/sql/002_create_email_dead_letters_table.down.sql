@@ -0,0 +1,2 @@
+-- down migration reverses the up migration
+DROP TABLE IF EXISTS email_dead_letters;
\ No newline at end of file
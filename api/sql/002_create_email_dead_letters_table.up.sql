@@ -0,0 +1,17 @@
+-- up migration creates the email dead letter table
+BEGIN;
+
+-- emails that still failed after every retry, kept so no alert is silently lost
+CREATE TABLE IF NOT EXISTS email_dead_letters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow_id TEXT NOT NULL,
+    node_id TEXT NOT NULL,
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,2 @@
+-- down migration reverses the up migration
+DROP TABLE IF EXISTS workflow_executions;
\ No newline at end of file
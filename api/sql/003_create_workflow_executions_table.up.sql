@@ -0,0 +1,16 @@
+-- up migration creates the workflow executions table
+BEGIN;
+
+-- results of the stored workflow executions, read back by the history nodes of later executions
+CREATE TABLE IF NOT EXISTS workflow_executions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workflow_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    result JSONB NOT NULL,
+    context JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS workflow_executions_workflow_id_created_at_idx ON workflow_executions (workflow_id, created_at DESC);
+
+COMMIT;